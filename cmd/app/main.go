@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -9,7 +10,9 @@ import (
 	"runtime"
 	"syscall"
 	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
 	"tz_effective/internal/adaper/storage/postgres"
+	"tz_effective/internal/adaper/storage/sqlite"
 	"tz_effective/internal/ports/http/public"
 	"tz_effective/internal/service"
 )
@@ -23,12 +26,13 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	pgStorage, err := postgres.New(ctx, cfg)
+	storage, err := newStorage(ctx, cfg)
 	if err != nil {
-		log.Fatalln("Failed to initialize PostgresSQL storage", "error", err)
+		log.Fatalln("Failed to initialize storage", "driver", cfg.Storage.Driver, "error", err)
 	}
 
-	serviceRate := service.NewService(pgStorage, cfg)
+	serviceRate := service.NewService(storage, cfg)
+	go serviceRate.RunTrashPurge(ctx)
 
 	done := make(chan os.Signal, 1)
 
@@ -39,7 +43,7 @@ func main() {
 		"go_version", runtime.Version(),
 	).Info("starting server")
 
-	serverDone := public.StartServer(ctx, serviceRate, cfg)
+	serverDone := public.StartServer(ctx, serviceRate, storage, cfg)
 
 	logger.Info("server started")
 
@@ -51,3 +55,38 @@ func main() {
 	logger.Info("server stopped")
 
 }
+
+// appStorage хранилище подписок и ключей идемпотентности
+type appStorage interface {
+	service.Storage
+	service.IdempotencyStore
+}
+
+// newStorage создает хранилище, выбранное в STORAGE_DRIVER
+func newStorage(ctx context.Context, cfg *config.Config) (appStorage, error) {
+	switch cfg.Storage.Driver {
+	case config.DriverMemory:
+		return memory.New(), nil
+	case config.DriverSQLite:
+		return sqlite.New(ctx, cfg)
+	case config.DriverPostgres:
+		pgStorage, err := postgres.New(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Storage.AutoMigrate {
+			migrator, err := pgStorage.Migrator()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := migrator.Up(ctx, 0); err != nil {
+				return nil, err
+			}
+		}
+
+		return pgStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
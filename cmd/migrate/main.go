@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/postgres"
+)
+
+// Утилита для ручного управления миграциями схемы PostgreSQL.
+//
+//	migrate up [-steps N]     применить N (по умолчанию все) неприменённых миграций
+//	migrate down [-steps N]   откатить N (по умолчанию одну) последних миграций
+//	migrate version           вывести текущую версию схемы
+//	migrate baseline -to V    пометить миграции до версии V примененными без выполнения
+//	migrate seed -file F      загрузить тестовые данные из SQL-файла F (только для dev-окружения)
+func main() {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 0, "number of migrations to apply or revert")
+	to := fs.Int64("to", 0, "version for baseline command")
+	file := fs.String("file", "", "SQL file for seed command")
+
+	if len(os.Args) < 2 {
+		log.Fatalln("usage: migrate up|down|version|baseline|seed [flags]")
+	}
+	command := os.Args[1]
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		log.Fatalln("Failed to parse flags", "error", err)
+	}
+
+	cfg := config.NewConfig()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+
+	ctx := context.Background()
+
+	pgStorage, err := postgres.New(ctx, cfg)
+	if err != nil {
+		log.Fatalln("Failed to initialize PostgresSQL storage", "error", err)
+	}
+
+	migrator, err := pgStorage.Migrator()
+	if err != nil {
+		log.Fatalln("Failed to load migrations", "error", err)
+	}
+
+	switch command {
+	case "up":
+		_, err = migrator.Up(ctx, *steps)
+	case "down":
+		_, err = migrator.Down(ctx, *steps)
+	case "version":
+		var version int64
+		version, err = migrator.Version(ctx)
+		if err == nil {
+			fmt.Println(version)
+		}
+	case "baseline":
+		if *to <= 0 {
+			log.Fatalln("baseline requires -to version")
+		}
+		err = migrator.Baseline(ctx, *to)
+	case "seed":
+		if *file == "" {
+			log.Fatalln("seed requires -file path")
+		}
+		var sql []byte
+		if sql, err = os.ReadFile(*file); err == nil {
+			err = migrator.Seed(ctx, string(sql))
+		}
+	default:
+		log.Fatalln("unknown command", command)
+	}
+
+	if err != nil {
+		log.Fatalln("Migration failed", "error", err)
+	}
+}
@@ -12,21 +12,37 @@ type Config struct {
 	HTTPServer HTTPServer
 }
 
+// Поддерживаемые драйверы хранилища
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+)
+
 type Storage struct {
-	Timeout  time.Duration `env:"BD_TIMEOUT" env-default:"10s"`
-	Host     string        `env:"BD_HOST" env-required:"true"`
-	Port     int           `env:"BD_PORT" env-required:"true"`
-	User     string        `env:"BD_USER" env-required:"true"`
-	Password string        `env:"BD_PASSWORD" env-required:"true"`
-	DBName   string        `env:"BD_DBNAME" env-required:"true"`
-	SSLMode  string        `env:"BD_SSL_MODE" env-default:"disable"`
-	Schema   string        `env:"BD_SCHEMA" env-default:"dev"`
+	Driver         string        `env:"STORAGE_DRIVER" env-default:"postgres"`
+	Timeout        time.Duration `env:"BD_TIMEOUT" env-default:"10s"`
+	Host           string        `env:"BD_HOST"`
+	Port           int           `env:"BD_PORT"`
+	User           string        `env:"BD_USER"`
+	Password       string        `env:"BD_PASSWORD"`
+	DBName         string        `env:"BD_DBNAME"`
+	SSLMode        string        `env:"BD_SSL_MODE" env-default:"disable"`
+	Schema         string        `env:"BD_SCHEMA" env-default:"dev"`
+	AutoMigrate    bool          `env:"BD_AUTO_MIGRATE" env-default:"false"`
+	SQLitePath     string        `env:"SQLITE_PATH" env-default:"subscriptions.db"`
+	TrashRetention time.Duration `env:"STORAGE_TRASH_RETENTION" env-default:"720h"`
 }
 
 type HTTPServer struct {
-	Port        string        `env:"HTTP_PORT" env-default:"8082"`
-	Timeout     time.Duration `env:"HTTP_TIMEOUT" env-default:"2m"`
-	IdleTimeout time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	Port           string        `env:"HTTP_PORT" env-default:"8082"`
+	Timeout        time.Duration `env:"HTTP_TIMEOUT" env-default:"2m"`
+	IdleTimeout    time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	RequireIfMatch bool          `env:"HTTP_REQUIRE_IF_MATCH" env-default:"false"`
+	IdempotencyTTL time.Duration `env:"HTTP_IDEMPOTENCY_TTL" env-default:"24h"`
+	BatchLimit     int           `env:"HTTP_BATCH_LIMIT" env-default:"1000"`
+	ImportLimit    int           `env:"HTTP_IMPORT_LIMIT" env-default:"100000"`
+	AdminToken     string        `env:"HTTP_ADMIN_TOKEN"`
 }
 
 func NewConfig() *Config {
@@ -39,5 +55,16 @@ func NewConfig() *Config {
 		log.Fatal("Error reading env")
 	}
 
+	switch cfg.Storage.Driver {
+	case DriverPostgres:
+		if cfg.Storage.Host == "" || cfg.Storage.Port == 0 || cfg.Storage.User == "" ||
+			cfg.Storage.Password == "" || cfg.Storage.DBName == "" {
+			log.Fatal("BD_HOST, BD_PORT, BD_USER, BD_PASSWORD and BD_DBNAME are required for postgres storage")
+		}
+	case DriverMemory, DriverSQLite:
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", cfg.Storage.Driver)
+	}
+
 	return cfg
 }
@@ -0,0 +1 @@
+-- Откатывать нечего: миграция не меняет данные.
@@ -1,12 +0,0 @@
-INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date) VALUES
-('Yandex Plus', 299, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '01-2025', '07-2025'),
-('Netflix Премиум', 999, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '02-2025', '08-2025'),
-('Кинопоиск HD', 399, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '01-2025', NULL),
-('Spotify Premium', 199, 'f47ac10b-58cc-4372-a567-0e02b2c3d479', '03-2025', '09-2025'),
-('YouTube Premium', 349, 'f47ac10b-58cc-4372-a567-0e02b2c3d479', '02-2025', NULL),
-('Амедиатека', 599, 'a242c56a-a11a-4d14-be20-51011e2a6c90', '01-2025', '12-2025'),
-('EA Play', 299, 'a242c56a-a11a-4d14-be20-51011e2a6c90', '04-2025', NULL),
-('PlayStation Plus', 699, 'b18de2c4-ad2b-4908-a6a0-8d88c3a7c6cd', '05-2025', '11-2025'),
-('Xbox Game Pass', 799, 'b18de2c4-ad2b-4908-a6a0-8d88c3a7c6cd', '03-2025', NULL),
-('Apple Music', 199, 'c5f7e6d1-2f09-4b21-a920-3997a35b3bc5', '06-2025', NULL);
-
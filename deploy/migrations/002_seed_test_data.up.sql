@@ -0,0 +1,2 @@
+-- Тестовые данные загружаются только в dev-окружении из deploy/seed/test_data.sql (migrate seed).
+-- Версия 2 сохранена, чтобы не сдвигать нумерацию уже примененных миграций.
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_start_date_month_check,
+    DROP CONSTRAINT IF EXISTS subscriptions_end_date_month_check;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE VARCHAR(7) USING to_char(start_date, 'MM-YYYY'),
+    ALTER COLUMN end_date TYPE VARCHAR(7) USING to_char(end_date, 'MM-YYYY');
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE DATE USING to_date(start_date, 'MM-YYYY'),
+    ALTER COLUMN end_date TYPE DATE USING to_date(end_date, 'MM-YYYY');
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_start_date_month_check CHECK (start_date = date_trunc('month', start_date)),
+    ADD CONSTRAINT subscriptions_end_date_month_check CHECK (end_date = date_trunc('month', end_date));
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS subscriptions_start_date_id_idx;
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
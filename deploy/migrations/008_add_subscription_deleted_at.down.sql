@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS subscriptions_deleted_at_idx;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS subscription_events (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    before_data JSONB,
+    after_data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS subscription_events_subscription_id_idx ON subscription_events (subscription_id, id);
+CREATE INDEX IF NOT EXISTS subscription_events_created_at_idx ON subscription_events (created_at);
+
+CREATE OR REPLACE FUNCTION subscription_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'subscription_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER subscription_events_append_only
+    BEFORE UPDATE OR DELETE ON subscription_events
+    FOR EACH ROW EXECUTE FUNCTION subscription_events_append_only();
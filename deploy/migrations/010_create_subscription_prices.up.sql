@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    effective_from DATE NOT NULL,
+    price BIGINT NOT NULL,
+    PRIMARY KEY (subscription_id, effective_from)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS billing_anchor,
+    DROP COLUMN IF EXISTS billing_period;
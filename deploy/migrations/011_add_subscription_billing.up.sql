@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions
+    ADD COLUMN billing_period VARCHAR(16) NOT NULL DEFAULT 'month'
+        CONSTRAINT subscriptions_billing_period_check CHECK (billing_period IN ('month', 'quarter', 'year', 'week')),
+    ADD COLUMN billing_anchor DATE;
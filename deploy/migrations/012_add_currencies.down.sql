@@ -0,0 +1,18 @@
+-- в старой схеме цены хранятся в рублях без копеек: откат не должен терять валюты и дробные суммы
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM subscriptions WHERE currency <> 'RUB' OR amount_minor % 100 <> 0)
+        OR EXISTS (SELECT 1 FROM subscription_prices WHERE amount_minor % 100 <> 0) THEN
+        RAISE EXCEPTION 'cannot revert currencies: subscriptions with non-RUB currency or fractional amounts exist';
+    END IF;
+END
+$$;
+
+DROP TABLE IF EXISTS exchange_rates;
+
+UPDATE subscription_prices SET amount_minor = amount_minor / 100;
+UPDATE subscriptions SET amount_minor = amount_minor / 100;
+ALTER TABLE subscription_prices RENAME COLUMN amount_minor TO price;
+ALTER TABLE subscriptions RENAME COLUMN amount_minor TO price;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,18 @@
+ALTER TABLE subscriptions
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB'
+        CONSTRAINT subscriptions_currency_check CHECK (currency IN ('RUB', 'USD', 'EUR', 'KZT'));
+
+-- цены хранятся в минимальных единицах валюты: рубли переводятся в копейки
+ALTER TABLE subscriptions RENAME COLUMN price TO amount_minor;
+ALTER TABLE subscription_prices RENAME COLUMN price TO amount_minor;
+UPDATE subscriptions SET amount_minor = amount_minor * 100;
+UPDATE subscription_prices SET amount_minor = amount_minor * 100;
+
+-- журнал не меняется: снимки без amount_minor приводятся к минимальным единицам при чтении
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) NOT NULL,
+    month DATE NOT NULL,
+    rate NUMERIC(20, 8) NOT NULL CONSTRAINT exchange_rates_rate_check CHECK (rate > 0),
+    PRIMARY KEY (currency, month)
+);
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS содержит SQL-миграции PostgreSQL вида NNN_description.up.sql и NNN_description.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
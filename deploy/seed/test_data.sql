@@ -0,0 +1,20 @@
+-- Тестовые данные для dev-окружения, не входят в версионированные миграции.
+-- Загружаются командой migrate seed -file deploy/seed/test_data.sql после migrate up; повторная загрузка не дублирует строки.
+INSERT INTO subscriptions (service_name, amount_minor, user_id, start_date, end_date)
+SELECT v.service_name, v.amount_minor, v.user_id::uuid, to_date(v.start_date, 'MM-YYYY'), to_date(v.end_date, 'MM-YYYY')
+FROM (VALUES
+    ('Yandex Plus', 29900, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '01-2025', '07-2025'),
+    ('Netflix Премиум', 99900, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '02-2025', '08-2025'),
+    ('Кинопоиск HD', 39900, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '01-2025', NULL),
+    ('Spotify Premium', 19900, 'f47ac10b-58cc-4372-a567-0e02b2c3d479', '03-2025', '09-2025'),
+    ('YouTube Premium', 34900, 'f47ac10b-58cc-4372-a567-0e02b2c3d479', '02-2025', NULL),
+    ('Амедиатека', 59900, 'a242c56a-a11a-4d14-be20-51011e2a6c90', '01-2025', '12-2025'),
+    ('EA Play', 29900, 'a242c56a-a11a-4d14-be20-51011e2a6c90', '04-2025', NULL),
+    ('PlayStation Plus', 69900, 'b18de2c4-ad2b-4908-a6a0-8d88c3a7c6cd', '05-2025', '11-2025'),
+    ('Xbox Game Pass', 79900, 'b18de2c4-ad2b-4908-a6a0-8d88c3a7c6cd', '03-2025', NULL),
+    ('Apple Music', 19900, 'c5f7e6d1-2f09-4b21-a920-3997a35b3bc5', '06-2025', NULL)
+) AS v (service_name, amount_minor, user_id, start_date, end_date)
+WHERE NOT EXISTS (
+    SELECT 1 FROM subscriptions s
+    WHERE s.service_name = v.service_name AND s.user_id = v.user_id::uuid
+);
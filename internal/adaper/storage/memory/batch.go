@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"tz_effective/internal/entities"
+)
+
+// ApplyBatch выполняет операции пакета под одной блокировкой, откатывая их по снимку хранилища
+func (s *Storage) ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, nextID, events := maps.Clone(s.subs), s.nextID, len(s.events)
+
+	results := make([]entities.BatchResult, len(ops))
+	for i := range ops {
+		results[i] = s.applyOperation(ctx, &ops[i])
+		if atomic && results[i].Err != nil {
+			s.subs, s.nextID, s.events = snapshot, nextID, s.events[:events]
+			return entities.AbortBatch(ops, results[i], i), nil
+		}
+	}
+	return results, nil
+}
+
+// applyOperation выполняет одну операцию пакета. Вызывается под блокировкой.
+func (s *Storage) applyOperation(ctx context.Context, op *entities.BatchOperation) entities.BatchResult {
+	switch op.Op {
+	case entities.BatchCreate:
+		rec, err := newRecord(op.Subscription)
+		if err != nil {
+			return entities.BatchResult{Err: fmt.Errorf("error creating subscription: %w", err)}
+		}
+		return entities.BatchResult{ID: s.create(ctx, rec), Version: 1}
+	case entities.BatchUpdate:
+		rec, err := newRecord(op.Subscription)
+		if err != nil {
+			return entities.BatchResult{ID: op.ID, Err: fmt.Errorf("error updating subscription with ID %d: %w", op.ID, err)}
+		}
+		version, err := s.update(ctx, op.ID, rec, op.Version)
+		return entities.BatchResult{ID: op.ID, Version: version, Err: err}
+	case entities.BatchDelete:
+		return entities.BatchResult{ID: op.ID, Err: s.delete(ctx, op.ID, op.Version)}
+	default:
+		return entities.BatchResult{ID: op.ID, Err: fmt.Errorf("unknown batch operation %q: %w", op.Op, entities.ErrValidation)}
+	}
+}
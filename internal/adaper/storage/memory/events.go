@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// recordEvent добавляет событие в журнал. Снимки копируются. Вызывается под блокировкой.
+func (s *Storage) recordEvent(ctx context.Context, action string, before, after *entities.Subscriptions) {
+	event := entities.NewEvent(ctx, action, copySnapshot(before), copySnapshot(after))
+	event.ID = int64(len(s.events)) + 1
+	event.CreatedAt = time.Now().UTC()
+	s.events = append(s.events, *event)
+}
+
+// ListEvents возвращает страницу журнала по фильтру в порядке записи
+func (s *Storage) ListEvents(_ context.Context, filter *entities.EventFilter) (*entities.EventPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := filter.PageSize()
+	var events []entities.SubscriptionEvent
+	for _, event := range s.events {
+		if len(events) > limit {
+			break
+		}
+		if event.ID <= filter.After ||
+			filter.SubscriptionID != nil && event.SubscriptionID != *filter.SubscriptionID ||
+			filter.Actor != nil && event.Actor != *filter.Actor ||
+			len(filter.Actions) > 0 && !slices.Contains(filter.Actions, event.Action) ||
+			filter.From != nil && event.CreatedAt.Before(*filter.From) ||
+			filter.To != nil && !event.CreatedAt.Before(*filter.To) {
+			continue
+		}
+		event.Before, event.After = copySnapshot(event.Before), copySnapshot(event.After)
+		events = append(events, event)
+	}
+
+	return entities.NewEventPage(events, limit), nil
+}
+
+func copySnapshot(sub *entities.Subscriptions) *entities.Subscriptions {
+	if sub == nil {
+		return nil
+	}
+	snapshot := copySubscription(*sub)
+	return &snapshot
+}
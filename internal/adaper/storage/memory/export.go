@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"tz_effective/internal/entities"
+)
+
+// ExportSubscriptions вызывает fn без блокировки хранилища, чтобы медленный получатель не задерживал изменения
+func (s *Storage) ExportSubscriptions(_ context.Context, filter *entities.ListFilter, fn func(*entities.Subscriptions) error) error {
+	match, err := listMatcher(filter)
+	if err != nil {
+		return fmt.Errorf("error exporting subscriptions: %w", err)
+	}
+
+	s.mu.RLock()
+	var matched []record
+	for _, rec := range s.subs {
+		if match(rec) {
+			matched = append(matched, rec)
+		}
+	}
+	s.mu.RUnlock()
+
+	order := filter.Order()
+	sort.Slice(matched, func(i, j int) bool { return compareRecords(matched[i], matched[j], order) < 0 })
+
+	for _, rec := range matched {
+		sub := copySubscription(rec.sub)
+		sub.Prices = nil
+		if err := fn(&sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) ReserveIdempotencyKey(_ context.Context, rec *entities.IdempotencyRecord) (*entities.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.keys[rec.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		existing.Body = append([]byte(nil), existing.Body...)
+		return &existing, nil
+	}
+
+	s.keys[rec.Key] = entities.IdempotencyRecord{
+		Key:         rec.Key,
+		Fingerprint: rec.Fingerprint,
+		ExpiresAt:   rec.ExpiresAt,
+	}
+	return nil, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(_ context.Context, rec *entities.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.keys[rec.Key]
+	if !ok || existing.Fingerprint != rec.Fingerprint {
+		return nil
+	}
+	existing.Status = rec.Status
+	existing.ContentType = rec.ContentType
+	existing.Body = append([]byte(nil), rec.Body...)
+	s.keys[rec.Key] = existing
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.keys[key]; ok && !existing.Completed() {
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, rec := range s.keys {
+		if !rec.ExpiresAt.After(before) {
+			delete(s.keys, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"tz_effective/internal/entities"
+)
+
+// ImportSubscriptions сохраняет все подписки или, если хотя бы одна некорректна, ни одной
+func (s *Storage) ImportSubscriptions(ctx context.Context, subs []entities.Subscriptions) ([]int64, error) {
+	recs := make([]record, len(subs))
+	for i := range subs {
+		rec, err := newRecord(&subs[i])
+		if err != nil {
+			return nil, fmt.Errorf("error importing subscriptions: row %d: %w", i+1, err)
+		}
+		recs[i] = rec
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int64, len(recs))
+	for i, rec := range recs {
+		ids[i] = s.create(ctx, rec)
+	}
+	return ids, nil
+}
@@ -0,0 +1,498 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service/cost"
+)
+
+// Storage потокобезопасное хранилище в памяти процесса с семантикой postgres.Storage
+type Storage struct {
+	mu     sync.RWMutex
+	nextID int64
+	subs   map[int64]record
+	events []entities.SubscriptionEvent
+	keys   map[string]entities.IdempotencyRecord
+	rates  map[rateKey]entities.ExchangeRate
+}
+
+// record подписка вместе с разобранным периодом, чтобы не разбирать даты при каждом фильтре
+type record struct {
+	sub    entities.Subscriptions
+	period cost.Period
+}
+
+func New() *Storage {
+	slog.Info("In-memory storage initialized successfully")
+	return &Storage{
+		nextID: 1,
+		subs:   make(map[int64]record),
+		keys:   make(map[string]entities.IdempotencyRecord),
+		rates:  make(map[rateKey]entities.ExchangeRate),
+	}
+}
+
+func (s *Storage) CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (int64, error) {
+	rec, err := newRecord(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error creating subscription: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(ctx, rec), nil
+}
+
+// create сохраняет новую подписку и возвращает ее ID. Вызывается под блокировкой.
+func (s *Storage) create(ctx context.Context, rec record) int64 {
+	id := s.nextID
+	s.nextID++
+	rec.sub.ID = id
+	rec.sub.Version = 1
+	rec.sub.CreatedAt = time.Now().UTC()
+	rec.sub.UpdatedAt = rec.sub.CreatedAt
+	rec.sub.Prices = nil
+	s.subs[id] = rec
+	s.recordEvent(ctx, entities.EventCreate, nil, &rec.sub)
+
+	return id
+}
+
+func (s *Storage) GetSubscription(_ context.Context, id int64) (*entities.Subscriptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.subs[id]
+	if !ok || rec.sub.DeletedAt != nil {
+		return nil, fmt.Errorf("error getting subscription with ID %d: %w", id, entities.ErrNotFound)
+	}
+
+	sub := copySubscription(rec.sub)
+	return &sub, nil
+}
+
+func (s *Storage) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	rec, err := newRecord(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(ctx, id, rec, version)
+}
+
+// update заменяет подписку, если ее версия равна version. Вызывается под блокировкой.
+func (s *Storage) update(ctx context.Context, id int64, rec record, version int64) (int64, error) {
+	old, err := s.current(id, version)
+	if err != nil {
+		return 0, err
+	}
+	rec.sub.ID = id
+	rec.sub.Version = old.sub.Version + 1
+	rec.sub.CreatedAt = old.sub.CreatedAt
+	rec.sub.UpdatedAt = time.Now().UTC()
+	rec.sub.Prices = old.sub.Prices
+	s.subs[id] = rec
+	s.recordEvent(ctx, entities.EventUpdate, &old.sub, &rec.sub)
+
+	return rec.sub.Version, nil
+}
+
+func (s *Storage) PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64,
+	check func(*entities.Subscriptions) error) (*entities.Subscriptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.current(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Empty() {
+		sub := copySubscription(old.sub)
+		return &sub, nil
+	}
+
+	patched := copySubscription(old.sub)
+	patch.Apply(&patched)
+	if err := check(&patched); err != nil {
+		return nil, err
+	}
+
+	rec, err := newRecord(&patched)
+	if err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, err)
+	}
+	rec.sub.Version++
+	rec.sub.UpdatedAt = time.Now().UTC()
+	s.subs[id] = rec
+	s.recordEvent(ctx, entities.EventUpdate, &old.sub, &rec.sub)
+
+	sub := copySubscription(rec.sub)
+	return &sub, nil
+}
+
+func (s *Storage) DeleteSubscription(ctx context.Context, id int64, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(ctx, id, version)
+}
+
+// delete переносит подписку в корзину, если ее версия равна version. Вызывается под блокировкой.
+func (s *Storage) delete(ctx context.Context, id int64, version int64) error {
+	old, err := s.current(id, version)
+	if err != nil {
+		return err
+	}
+	rec := old
+	now := time.Now().UTC()
+	rec.sub.Version++
+	rec.sub.UpdatedAt = now
+	rec.sub.DeletedAt = &now
+	s.subs[id] = rec
+	s.recordEvent(ctx, entities.EventDelete, &old.sub, &rec.sub)
+
+	return nil
+}
+
+// current возвращает запись действующей подписки, если ее версия равна version (или version равен AnyVersion).
+// Вызывается под блокировкой.
+func (s *Storage) current(id int64, version int64) (record, error) {
+	return s.lookup(id, version, false)
+}
+
+// lookup возвращает запись подписки из действующих или, при deleted, из корзины,
+// если ее версия равна version. Вызывается под блокировкой.
+func (s *Storage) lookup(id int64, version int64, deleted bool) (record, error) {
+	rec, ok := s.subs[id]
+	if !ok || (rec.sub.DeletedAt != nil) != deleted {
+		return record{}, fmt.Errorf("subscription with ID %d: %w", id, entities.ErrNotFound)
+	}
+	if version != entities.AnyVersion && rec.sub.Version != version {
+		return record{}, fmt.Errorf("subscription with ID %d has version %d: %w", id, rec.sub.Version, entities.ErrVersionMismatch)
+	}
+	return rec, nil
+}
+
+// ListSubscriptions возвращает страницу подписок по фильтру в порядке filter.Order().
+// Размер страницы ограничен entities.MaxPageSize.
+func (s *Storage) ListSubscriptions(_ context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error) {
+	match, err := listMatcher(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+
+	order := filter.Order()
+	var after *record
+	if filter.After != nil {
+		key, err := filter.After.Key(order)
+		if err != nil {
+			return nil, fmt.Errorf("error listing subscriptions: %w", err)
+		}
+		rec := record{sub: *key}
+		if key.StartDate != "" {
+			if rec.period.Start, err = entities.ParseMonth(key.StartDate); err != nil {
+				return nil, fmt.Errorf("error listing subscriptions: cursor: %w", err)
+			}
+		}
+		after = &rec
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []record
+	for _, id := range s.sortedIDs() {
+		if rec := s.subs[id]; match(rec) {
+			matched = append(matched, rec)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return compareRecords(matched[i], matched[j], order) < 0 })
+
+	limit := filter.PageSize()
+	var subs []entities.Subscriptions
+	for _, rec := range matched {
+		if after != nil && compareRecords(rec, *after, order) <= 0 {
+			continue
+		}
+		if len(subs) > limit {
+			break
+		}
+		subs = append(subs, copySubscription(rec.sub))
+	}
+
+	page := entities.NewPage(subs, limit, order)
+	if filter.WithTotal {
+		total := int64(len(matched))
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// listMatcher возвращает предикат, проверяющий подписку на соответствие фильтру списка
+func listMatcher(filter *entities.ListFilter) (func(rec record) bool, error) {
+	parse := func(name string, value *string) (*entities.Month, error) {
+		if value == nil {
+			return nil, nil
+		}
+		m, err := entities.ParseMonth(*value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return &m, nil
+	}
+
+	startDate, err := parse("start_date", filter.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := parse("end_date", filter.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	activeOn, err := parse("active_on", filter.ActiveOn)
+	if err != nil {
+		return nil, err
+	}
+
+	var search string
+	if filter.Search != nil {
+		search = strings.ToLower(*filter.Search)
+	}
+	minAmount, maxAmount := filter.AmountRange()
+
+	return func(rec record) bool {
+		if (rec.sub.DeletedAt != nil) != filter.Deleted {
+			return false
+		}
+		if len(filter.UserIDs) > 0 && !contains(filter.UserIDs, rec.sub.UserID) {
+			return false
+		}
+		if len(filter.ServiceNames) > 0 && !contains(filter.ServiceNames, rec.sub.ServiceName) {
+			return false
+		}
+		if filter.Search != nil && !strings.Contains(strings.ToLower(rec.sub.ServiceName), search) {
+			return false
+		}
+		if startDate != nil && rec.period.Start < *startDate {
+			return false
+		}
+		// как и в SQL, бессрочная подписка не проходит условие end_date <= X
+		if endDate != nil && (rec.period.End == nil || *rec.period.End > *endDate) {
+			return false
+		}
+		if activeOn != nil {
+			if _, _, ok := rec.period.Overlap(*activeOn, *activeOn); !ok {
+				return false
+			}
+		}
+		if len(filter.Currencies) > 0 && !contains(filter.Currencies, rec.sub.CurrencyCode()) {
+			return false
+		}
+		if minAmount != nil && rec.sub.AmountMinor < *minAmount {
+			return false
+		}
+		if maxAmount != nil && rec.sub.AmountMinor > *maxAmount {
+			return false
+		}
+		if filter.HasEndDate != nil && (rec.period.End != nil) != *filter.HasEndDate {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// compareRecords сравнивает подписки в порядке order: -1, если a раньше b, 0 при равенстве ключей, 1 иначе
+func compareRecords(a, b record, order []entities.SortField) int {
+	for _, f := range order {
+		var c int
+		switch f.Field {
+		case entities.SortID:
+			c = compare(a.sub.ID, b.sub.ID)
+		case entities.SortServiceName:
+			c = strings.Compare(a.sub.ServiceName, b.sub.ServiceName)
+		case entities.SortPrice:
+			c = compare(a.sub.AmountMinor, b.sub.AmountMinor)
+		case entities.SortStartDate:
+			c = compare(a.period.Start, b.period.Start)
+		case entities.SortCreatedAt:
+			c = a.sub.CreatedAt.Compare(b.sub.CreatedAt)
+		case entities.SortUpdatedAt:
+			c = a.sub.UpdatedAt.Compare(b.sub.UpdatedAt)
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compare[T int64 | entities.Month](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) CalculateTotalCost(_ context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating total cost: %w", err)
+	}
+	subs, err := s.costSubscriptions(filter, &q)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating total cost: %w", err)
+	}
+
+	return cost.Total(subs, q)
+}
+
+func (s *Storage) CalculateCostBreakdown(_ context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", err)
+	}
+	subs, err := s.costSubscriptions(filter, &q)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", err)
+	}
+
+	return cost.Breakdown(subs, q)
+}
+
+func (s *Storage) CalculateGroupedCost(_ context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating grouped cost: %w", err)
+	}
+	subs, err := s.costSubscriptions(filter, &q)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating grouped cost: %w", err)
+	}
+
+	return cost.Grouped(subs, q, groupBy)
+}
+
+// costSubscriptions выбирает подписки по фильтрам пользователя и сервиса и заполняет q.Rates
+// курсами валют, нужными для пересчета их цен. Пересечение с периодом проверяет сам расчет стоимости.
+func (s *Storage) costSubscriptions(filter *entities.CostFilter, q *cost.Query) ([]entities.Subscriptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subs []entities.Subscriptions
+	for _, id := range s.sortedIDs() {
+		rec := s.subs[id]
+
+		if rec.sub.DeletedAt != nil {
+			continue
+		}
+		if filter.UserID != nil && rec.sub.UserID != *filter.UserID {
+			continue
+		}
+		if filter.ServiceName != nil && rec.sub.ServiceName != *filter.ServiceName {
+			continue
+		}
+
+		subs = append(subs, copySubscription(rec.sub))
+	}
+
+	var err error
+	if currencies := q.Currencies(subs); len(currencies) > 0 {
+		if q.Rates, err = cost.NewRates(s.exchangeRates(currencies)); err != nil {
+			return nil, err
+		}
+	}
+	return subs, nil
+}
+
+// sortedIDs возвращает ID подписок по возрастанию. Вызывается под блокировкой.
+func (s *Storage) sortedIDs() []int64 {
+	ids := make([]int64, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func newRecord(sub *entities.Subscriptions) (record, error) {
+	period, err := cost.ParsePeriod(sub)
+	if err != nil {
+		return record{}, err
+	}
+
+	if _, err := cost.ParseBilling(sub); err != nil {
+		return record{}, err
+	}
+	if !entities.ValidCurrency(sub.CurrencyCode()) {
+		return record{}, fmt.Errorf("currency: %w: unsupported currency %q", entities.ErrValidation, sub.Currency)
+	}
+
+	stored := copySubscription(*sub)
+	stored.StartDate = period.Start.String()
+	if period.End != nil {
+		endDate := period.End.String()
+		stored.EndDate = &endDate
+	}
+	stored.Currency = sub.CurrencyCode()
+	stored.BillingPeriod = sub.Billing()
+	if sub.BillingAnchor != nil {
+		anchor, err := entities.ParseMonth(*sub.BillingAnchor)
+		if err != nil {
+			return record{}, fmt.Errorf("billing_anchor: %w", err)
+		}
+		billingAnchor := anchor.String()
+		stored.BillingAnchor = &billingAnchor
+	}
+	stored.FillAmounts()
+
+	return record{sub: stored, period: period}, nil
+}
+
+// copySubscription возвращает копию подписки, не разделяющую указатели с исходной
+func copySubscription(sub entities.Subscriptions) entities.Subscriptions {
+	if sub.EndDate != nil {
+		endDate := *sub.EndDate
+		sub.EndDate = &endDate
+	}
+	if sub.BillingAnchor != nil {
+		billingAnchor := *sub.BillingAnchor
+		sub.BillingAnchor = &billingAnchor
+	}
+	if sub.DeletedAt != nil {
+		deletedAt := *sub.DeletedAt
+		sub.DeletedAt = &deletedAt
+	}
+	if sub.Prices != nil {
+		sub.Prices = append([]entities.PriceChange{}, sub.Prices...)
+	}
+	return sub
+}
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"testing"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/service"
+	"tz_effective/internal/service/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		return memory.New()
+	})
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) SetPriceChange(ctx context.Context, id int64, change *entities.PriceChange, version int64,
+	check func(*entities.Subscriptions) error) (*entities.Subscriptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.current(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := old
+	rec.sub = copySubscription(old.sub)
+	if err := rec.sub.SetPrice(*change); err != nil {
+		return nil, fmt.Errorf("error changing price of subscription with ID %d: %w", id, err)
+	}
+	if err := check(&rec.sub); err != nil {
+		return nil, err
+	}
+
+	return s.savePrices(ctx, old, rec), nil
+}
+
+func (s *Storage) DeletePriceChange(ctx context.Context, id int64, effectiveFrom string, version int64) (*entities.Subscriptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.current(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := old
+	rec.sub = copySubscription(old.sub)
+	if err := rec.sub.RemovePrice(effectiveFrom); err != nil {
+		return nil, fmt.Errorf("subscription with ID %d: %w", id, err)
+	}
+
+	return s.savePrices(ctx, old, rec), nil
+}
+
+// savePrices сохраняет подписку с измененной историей цен как новую версию. Вызывается под блокировкой.
+func (s *Storage) savePrices(ctx context.Context, old, rec record) *entities.Subscriptions {
+	rec.sub.Version++
+	rec.sub.UpdatedAt = time.Now().UTC()
+	s.subs[rec.sub.ID] = rec
+	s.recordEvent(ctx, entities.EventUpdate, &old.sub, &rec.sub)
+
+	sub := copySubscription(rec.sub)
+	return &sub
+}
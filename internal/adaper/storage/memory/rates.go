@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"tz_effective/internal/entities"
+)
+
+// rateKey курс валюты однозначно задается валютой и месяцем
+type rateKey struct {
+	currency string
+	month    entities.Month
+}
+
+func (s *Storage) ListExchangeRates(_ context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.exchangeRates(filter.Currencies), nil
+}
+
+func (s *Storage) SetExchangeRates(_ context.Context, rates []entities.ExchangeRate) error {
+	stored := make(map[rateKey]entities.ExchangeRate, len(rates))
+	for _, r := range rates {
+		month, err := entities.ParseMonth(r.Month)
+		if err != nil {
+			return fmt.Errorf("error saving exchange rate %s: month: %w", r.Currency, err)
+		}
+		if r.Rate, err = entities.NormalizeRate(r.Rate); err != nil {
+			return fmt.Errorf("error saving exchange rate %s for %s: %w", r.Currency, r.Month, err)
+		}
+		r.Month = month.String()
+		stored[rateKey{currency: r.Currency, month: month}] = r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, r := range stored {
+		s.rates[key] = r
+	}
+	return nil
+}
+
+func (s *Storage) DeleteExchangeRate(_ context.Context, currency, month string) error {
+	m, err := entities.ParseMonth(month)
+	if err != nil {
+		return fmt.Errorf("error deleting exchange rate %s: month: %w", currency, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := rateKey{currency: currency, month: m}
+	if _, ok := s.rates[key]; !ok {
+		return fmt.Errorf("error deleting exchange rate %s for %s: %w", currency, m, entities.ErrNotFound)
+	}
+	delete(s.rates, key)
+	return nil
+}
+
+// exchangeRates возвращает курсы валют currencies (всех валют, если список пуст) по валюте и месяцу.
+// Вызывается под блокировкой.
+func (s *Storage) exchangeRates(currencies []string) []entities.ExchangeRate {
+	keys := make([]rateKey, 0, len(s.rates))
+	for key := range s.rates {
+		if len(currencies) == 0 || contains(currencies, key.currency) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].currency != keys[j].currency {
+			return keys[i].currency < keys[j].currency
+		}
+		return keys[i].month < keys[j].month
+	})
+
+	rates := make([]entities.ExchangeRate, 0, len(keys))
+	for _, key := range keys {
+		rates = append(rates, s.rates[key])
+	}
+	return rates
+}
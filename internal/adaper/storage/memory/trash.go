@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) RestoreSubscription(ctx context.Context, id int64, version int64) (*entities.Subscriptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.lookup(id, version, true)
+	if err != nil {
+		return nil, err
+	}
+	rec := old
+	rec.sub.Version++
+	rec.sub.UpdatedAt = time.Now().UTC()
+	rec.sub.DeletedAt = nil
+	s.subs[id] = rec
+	s.recordEvent(ctx, entities.EventRestore, &old.sub, &rec.sub)
+
+	sub := copySubscription(rec.sub)
+	return &sub, nil
+}
+
+func (s *Storage) PurgeDeletedSubscriptions(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, rec := range s.subs {
+		if rec.sub.DeletedAt != nil && !rec.sub.DeletedAt.After(before) {
+			delete(s.subs, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
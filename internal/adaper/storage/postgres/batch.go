@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// ApplyBatch выполняет операции пакета в одной транзакции, каждую в неатомарном режиме - в своей точке сохранения
+func (s *Storage) ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	results := make([]entities.BatchResult, len(ops))
+	for i := range ops {
+		if atomic {
+			results[i] = applyOperation(ctx, tx, &ops[i])
+			if results[i].Err != nil {
+				slog.Warn("Batch aborted", "index", i, "error", results[i].Err)
+				return entities.AbortBatch(ops, results[i], i), nil
+			}
+			continue
+		}
+
+		// Begin внутри транзакции создает точку сохранения
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+		}
+		results[i] = applyOperation(ctx, savepoint, &ops[i])
+		if results[i].Err != nil {
+			err = savepoint.Rollback(ctx)
+		} else {
+			err = savepoint.Commit(ctx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+	}
+	return results, nil
+}
+
+// applyOperation выполняет одну операцию пакета
+func applyOperation(ctx context.Context, q querier, op *entities.BatchOperation) entities.BatchResult {
+	switch op.Op {
+	case entities.BatchCreate:
+		id, err := createSubscription(ctx, q, op.Subscription)
+		if err != nil {
+			return entities.BatchResult{Err: err}
+		}
+		return entities.BatchResult{ID: id, Version: 1}
+	case entities.BatchUpdate:
+		version, err := updateSubscription(ctx, q, op.ID, op.Subscription, op.Version)
+		return entities.BatchResult{ID: op.ID, Version: version, Err: err}
+	case entities.BatchDelete:
+		return entities.BatchResult{ID: op.ID, Err: deleteSubscription(ctx, q, op.ID, op.Version)}
+	default:
+		return entities.BatchResult{ID: op.ID, Err: fmt.Errorf("unknown batch operation %q: %w", op.Op, entities.ErrValidation)}
+	}
+}
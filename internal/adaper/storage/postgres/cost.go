@@ -0,0 +1,339 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"strings"
+	"time"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service/cost"
+)
+
+// CalculateTotalCost считает стоимость запросом по правилам cost.Total
+func (s *Storage) CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error) {
+	c, err := newCostQuery(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating total cost: %w", err)
+	}
+
+	res := &entities.TotalCostResponse{Currency: c.currency}
+	err = s.inSnapshot(ctx, func(tx pgx.Tx) error {
+		rates, err := c.rates(ctx, tx, nil)
+		if err != nil {
+			return err
+		}
+		res.Rates = rates[""]
+
+		return tx.QueryRow(ctx, c.with+`
+			SELECT
+				coalesce((SELECT sum(amount) FROM converted), 0)::bigint,
+				coalesce((SELECT sum(months) FROM subs), 0)::bigint`,
+			c.params...).Scan(&res.TotalCostMinor, &res.SubscriptionMonths)
+	})
+	if err != nil {
+		slog.Error("Failed to calculate total cost", "error", err, "filter", filter)
+		return nil, fmt.Errorf("error calculating total cost: %w", mapError(err))
+	}
+
+	res.TotalCost = entities.MajorUnits(res.TotalCostMinor)
+	return res, nil
+}
+
+// CalculateCostBreakdown раскладывает стоимость по месяцам запросом по правилам cost.Breakdown
+func (s *Storage) CalculateCostBreakdown(ctx context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error) {
+	c, err := newCostQuery(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", err)
+	}
+
+	res := &entities.CostBreakdownResponse{
+		Months:   make([]entities.MonthlyCost, 0, int(c.to-c.from)+1),
+		Currency: c.currency,
+	}
+	for m := c.from; m <= c.to; m++ {
+		res.Months = append(res.Months, entities.MonthlyCost{
+			Month:         m.String(),
+			Subscriptions: []entities.SubscriptionCost{},
+		})
+	}
+
+	err = s.inSnapshot(ctx, func(tx pgx.Tx) error {
+		rates, err := c.rates(ctx, tx, nil)
+		if err != nil {
+			return err
+		}
+		res.Rates = rates[""]
+
+		rows, err := tx.Query(ctx, c.with+`
+			SELECT c.month, s.service_name, s.user_id, c.amount::bigint
+			FROM converted c
+			JOIN subs s ON s.id = c.id
+			ORDER BY c.month, c.id`,
+			c.params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var date time.Time
+			var line entities.SubscriptionCost
+			if err := rows.Scan(&date, &line.ServiceName, &line.UserID, &line.CostMinor); err != nil {
+				return err
+			}
+			line.Cost = entities.MajorUnits(line.CostMinor)
+			month := &res.Months[entities.MonthOf(date)-c.from]
+			month.TotalCostMinor += line.CostMinor
+			month.Subscriptions = append(month.Subscriptions, line)
+			res.TotalCostMinor += line.CostMinor
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		slog.Error("Failed to calculate cost breakdown", "error", err, "filter", filter)
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", mapError(err))
+	}
+
+	for i := range res.Months {
+		res.Months[i].TotalCost = entities.MajorUnits(res.Months[i].TotalCostMinor)
+	}
+	res.TotalCost = entities.MajorUnits(res.TotalCostMinor)
+	return res, nil
+}
+
+// CalculateGroupedCost считает стоимость по группам запросом по правилам cost.Grouped
+func (s *Storage) CalculateGroupedCost(ctx context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error) {
+	c, err := newCostQuery(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating grouped cost: %w", err)
+	}
+	columns, err := groupColumns(groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating grouped cost: %w", err)
+	}
+	keys := strings.Join(columns, ", ")
+
+	groups := []entities.GroupedCost{}
+	err = s.inSnapshot(ctx, func(tx pgx.Tx) error {
+		rates, err := c.rates(ctx, tx, columns)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, c.with+`
+			SELECT `+keys+`, sum(s.months)::bigint, coalesce(sum(c.amount), 0)::bigint
+			FROM subs s
+			LEFT JOIN (SELECT id, sum(amount) AS amount FROM converted GROUP BY id) c ON c.id = s.id
+			GROUP BY `+keys+`
+			ORDER BY `+strings.Join(columns, ` COLLATE "C", `)+` COLLATE "C"`,
+			c.params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			values := make([]string, len(columns))
+			dest := make([]interface{}, 0, len(columns)+2)
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+			group := entities.GroupedCost{Key: make(map[string]string, len(groupBy)), Currency: c.currency}
+			if err := rows.Scan(append(dest, &group.SubscriptionMonths, &group.TotalCostMinor)...); err != nil {
+				return err
+			}
+			for i, field := range groupBy {
+				group.Key[field] = values[i]
+			}
+			group.TotalCost = entities.MajorUnits(group.TotalCostMinor)
+			group.Rates = rates[strings.Join(values, "\x00")]
+			groups = append(groups, group)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		slog.Error("Failed to calculate grouped cost", "error", err, "filter", filter, "group_by", groupBy)
+		return nil, fmt.Errorf("error calculating grouped cost: %w", mapError(err))
+	}
+
+	return groups, nil
+}
+
+// inSnapshot выполняет fn в читающей транзакции, чтобы курсы и суммы выбирались из одного снимка данных
+func (s *Storage) inSnapshot(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+// groupColumns возвращает колонки subs для полей группировки
+func groupColumns(groupBy []string) ([]string, error) {
+	columns := make([]string, 0, len(groupBy))
+	for _, field := range groupBy {
+		switch field {
+		case entities.GroupByServiceName:
+			columns = append(columns, "s.service_name")
+		case entities.GroupByUserID:
+			columns = append(columns, "s.user_id")
+		default:
+			return nil, fmt.Errorf("%w: unsupported group_by field %q", entities.ErrValidation, field)
+		}
+	}
+	return columns, nil
+}
+
+// costQuery общая часть запросов стоимости: CTE with и ее параметры
+type costQuery struct {
+	with     string
+	params   []interface{}
+	from     entities.Month
+	to       entities.Month
+	currency string
+}
+
+// newCostQuery строит CTE стоимости: subs - подписки фильтра в периоде, lines - их стоимость по месяцам, converted - в валюте запроса
+func newCostQuery(filter *entities.CostFilter) (*costQuery, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := ""
+	params := []interface{}{q.From.Time(), q.To.Time(), q.Basis == entities.BasisAccrual, q.Currency, entities.BaseCurrency}
+	paramIndex := 6
+
+	if filter.UserID != nil {
+		conditions += fmt.Sprintf(" AND user_id = $%d", paramIndex)
+		params = append(params, *filter.UserID)
+		paramIndex++
+	}
+
+	if filter.ServiceName != nil {
+		conditions += fmt.Sprintf(" AND service_name = $%d", paramIndex)
+		params = append(params, *filter.ServiceName)
+		paramIndex++
+	}
+
+	// $3 - учет по начислению: списание делится между месяцами оплаченного периода, в том числе сделанное до начала периода
+	with := `
+		WITH subs AS (
+			SELECT id, service_name, user_id::text AS user_id, amount_minor, currency::text AS currency,
+				start_date, end_date, billing_period, coalesce(billing_anchor, start_date) AS anchor,
+				greatest(start_date, $1::date) AS first_month,
+				least(coalesce(end_date, $2::date), $2::date) AS last_month,
+				` + monthIndex("least(coalesce(end_date, $2::date), $2::date)") + ` - ` + monthIndex("greatest(start_date, $1::date)") + ` + 1 AS months
+			FROM subscriptions
+			WHERE deleted_at IS NULL AND (end_date IS NULL OR end_date >= $1::date) AND start_date <= $2::date` + conditions + `
+		),
+		charges AS (
+			SELECT s.id, s.currency, s.start_date, s.end_date, s.billing_period, d.day, coalesce((
+				SELECT p.amount_minor FROM subscription_prices p
+				WHERE p.subscription_id = s.id AND p.effective_from <= m.month
+				ORDER BY p.effective_from DESC LIMIT 1
+			), s.amount_minor) AS price
+			FROM subs s
+			CROSS JOIN LATERAL generate_series(
+				greatest(s.start_date, s.first_month - CASE WHEN $3::boolean THEN ` + coverage("s", "interval '1 month'") + ` ELSE interval '0' END),
+				s.last_month, interval '1 month') AS m(month)
+			CROSS JOIN LATERAL generate_series(
+				m.month + CASE WHEN s.billing_period = 'week' THEN make_interval(days => ((s.anchor - m.month::date) % 7 + 7) % 7) ELSE interval '0' END,
+				CASE WHEN s.billing_period = 'week' THEN m.month + interval '1 month' - interval '1 day' ELSE m.month END,
+				interval '7 days') AS d(day)
+			WHERE s.billing_period NOT IN ('quarter', 'year')
+				OR mod(` + monthIndex("m.month") + ` - ` + monthIndex("s.anchor") + `, CASE WHEN s.billing_period = 'quarter' THEN 3 ELSE 12 END) = 0
+		),
+		lines AS (
+			SELECT id, currency, month, sum(amount) AS amount
+			FROM (
+				SELECT id, currency, month, div(price * cumulative, total) - div(price * (cumulative - weight), total) AS amount
+				FROM (
+					SELECT c.id, c.currency, c.price, sh.month, sh.weight,
+						sum(sh.weight) OVER (PARTITION BY c.id, c.day ORDER BY sh.month) AS cumulative,
+						sum(sh.weight) OVER (PARTITION BY c.id, c.day) AS total
+					FROM charges c
+					CROSS JOIN LATERAL (
+						SELECT date_trunc('month', u.unit)::date AS month, count(*) AS weight
+						FROM generate_series(c.day, c.day + CASE WHEN $3::boolean THEN ` + coverage("c", "interval '6 days'") + ` ELSE interval '0' END,
+							CASE WHEN c.billing_period = 'week' THEN interval '1 day' ELSE interval '1 month' END) AS u(unit)
+						GROUP BY 1
+					) sh
+					WHERE sh.month >= c.start_date AND (c.end_date IS NULL OR sh.month <= c.end_date)
+				) shares
+			) parts
+			WHERE month BETWEEN $1::date AND $2::date
+			GROUP BY id, currency, month
+		),
+		converted AS (
+			SELECT id, month, CASE WHEN currency = $4::text THEN amount
+				ELSE div(amount * from_rate, to_rate) + CASE WHEN 2 * mod(amount * from_rate, to_rate) >= to_rate THEN 1 ELSE 0 END
+			END AS amount
+			FROM (
+				SELECT l.id, l.month, l.currency, l.amount,
+					CASE WHEN l.currency = $5::text THEN 1 ELSE rf.rate END AS from_rate,
+					CASE WHEN $4::text = $5::text THEN 1 ELSE rt.rate END AS to_rate
+				FROM lines l
+				LEFT JOIN exchange_rates rf ON rf.currency = l.currency AND rf.month = l.month
+				LEFT JOIN exchange_rates rt ON rt.currency = $4::text AND rt.month = l.month
+			) r
+		)`
+
+	return &costQuery{with: with, params: params, from: q.From, to: q.To, currency: q.Currency}, nil
+}
+
+// rates выбирает курсы, по которым converted пересчитывает стоимость, по ключу группы из значений columns
+func (c *costQuery) rates(ctx context.Context, q querier, columns []string) (map[string][]entities.ExchangeRate, error) {
+	keys := ""
+	for _, column := range columns {
+		keys += column + ", "
+	}
+
+	rows, err := q.Query(ctx, c.with+`
+		SELECT DISTINCT `+keys+`need.currency, l.month, r.rate::text
+		FROM lines l
+		JOIN subs s ON s.id = l.id
+		CROSS JOIN LATERAL (VALUES (l.currency), ($4::text)) AS need(currency)
+		LEFT JOIN exchange_rates r ON r.currency = need.currency AND r.month = l.month
+		WHERE l.currency <> $4::text AND need.currency <> $5::text
+		ORDER BY `+keys+`need.currency, l.month`,
+		c.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[string][]entities.ExchangeRate)
+	for rows.Next() {
+		values := make([]string, len(columns))
+		dest := make([]interface{}, 0, len(columns)+3)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		var r entities.ExchangeRate
+		var month time.Time
+		var rate *string
+		if err := rows.Scan(append(dest, &r.Currency, &month, &rate)...); err != nil {
+			return nil, err
+		}
+		if rate == nil {
+			return nil, cost.NoRateError(r.Currency, entities.MonthOf(month))
+		}
+		r.Month = fromDate(month)
+		if r.Rate, err = entities.NormalizeRate(*rate); err != nil {
+			return nil, err
+		}
+		key := strings.Join(values, "\x00")
+		res[key] = append(res[key], r)
+	}
+	return res, rows.Err()
+}
+
+// monthIndex номер месяца даты expr от начала эры: разность номеров - число месяцев между датами
+func monthIndex(expr string) string {
+	return "(extract(year FROM " + expr + ") * 12 + extract(month FROM " + expr + "))::int"
+}
+
+// coverage длина периода, оплаченного одним списанием подписки alias, без первого месяца (для недельной - week)
+func coverage(alias, week string) string {
+	return `CASE ` + alias + `.billing_period WHEN 'week' THEN ` + week +
+		` WHEN 'quarter' THEN interval '2 months' WHEN 'year' THEN interval '11 months' ELSE interval '0' END`
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// В БД периоды подписки хранятся в колонках DATE (первое число месяца),
+// а в API передаются в формате MM-YYYY. Функции ниже переводят одно в другое.
+
+func toDate(month string) (time.Time, error) {
+	m, err := entities.ParseMonth(month)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.Time(), nil
+}
+
+func toNullDate(month *string) (*time.Time, error) {
+	if month == nil {
+		return nil, nil
+	}
+	t, err := toDate(*month)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func fromDate(t time.Time) string {
+	return entities.MonthOf(t).String()
+}
+
+func fromNullDate(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := fromDate(*t)
+	return &s
+}
+
+// subscriptionDates переводит даты подписки в значения для колонок start_date и end_date
+func subscriptionDates(sub *entities.Subscriptions) (start time.Time, end *time.Time, err error) {
+	start, err = toDate(sub.StartDate)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("start_date: %w", err)
+	}
+	end, err = toNullDate(sub.EndDate)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("end_date: %w", err)
+	}
+	return start, end, nil
+}
+
+// subscriptionBilling переводит период оплаты подписки в значения для колонок billing_period и billing_anchor
+func subscriptionBilling(sub *entities.Subscriptions) (period string, anchor *time.Time, err error) {
+	anchor, err = toNullDate(sub.BillingAnchor)
+	if err != nil {
+		return "", nil, fmt.Errorf("billing_anchor: %w", err)
+	}
+	return sub.Billing(), anchor, nil
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"net"
+	"strings"
+	"tz_effective/internal/entities"
+)
+
+// mapError оборачивает ошибку pgx в ошибку предметной области из entities, сохраняя исходную в цепочке
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", entities.ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "23505", pgErr.Code == "23P01", pgErr.Code == "40001", pgErr.Code == "40P01":
+			// unique_violation, exclusion_violation, serialization_failure, deadlock_detected
+			return fmt.Errorf("%w: %w", entities.ErrConflict, err)
+		case strings.HasPrefix(pgErr.Code, "23"), strings.HasPrefix(pgErr.Code, "22"):
+			// integrity_constraint_violation, data_exception
+			return fmt.Errorf("%w: %w", entities.ErrValidation, err)
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"), strings.HasPrefix(pgErr.Code, "57P"):
+			// connection_exception, insufficient_resources, operator_intervention (shutdown)
+			return fmt.Errorf("%w: %w", entities.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", entities.ErrUnavailable, err)
+	}
+
+	return err
+}
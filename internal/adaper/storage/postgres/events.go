@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// eventColumns колонки журнала в порядке, ожидаемом scanEvent
+const eventColumns = `id, subscription_id, action, actor, request_id, before_data, after_data, created_at`
+
+// recordEvent записывает событие в журнал в транзакции изменения
+func recordEvent(ctx context.Context, q querier, event *entities.SubscriptionEvent) error {
+	before, after, err := eventSnapshots(event)
+	if err != nil {
+		return fmt.Errorf("error recording event for subscription with ID %d: %w", event.SubscriptionID, err)
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO subscription_events (subscription_id, action, actor, request_id, before_data, after_data)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.SubscriptionID, event.Action, event.Actor, event.RequestID, before, after)
+	if err != nil {
+		slog.Error("Failed to record subscription event", "error", err, "id", event.SubscriptionID, "action", event.Action)
+		return fmt.Errorf("error recording event for subscription with ID %d: %w", event.SubscriptionID, mapError(err))
+	}
+	return nil
+}
+
+// eventSnapshots возвращает JSON снимков события, nil для отсутствующего снимка
+func eventSnapshots(event *entities.SubscriptionEvent) (before, after []byte, err error) {
+	if event.Before != nil {
+		if before, err = json.Marshal(event.Before); err != nil {
+			return nil, nil, err
+		}
+	}
+	if event.After != nil {
+		if after, err = json.Marshal(event.After); err != nil {
+			return nil, nil, err
+		}
+	}
+	return before, after, nil
+}
+
+// ListEvents возвращает страницу журнала по фильтру в порядке записи
+func (s *Storage) ListEvents(ctx context.Context, filter *entities.EventFilter) (*entities.EventPage, error) {
+	query := `SELECT ` + eventColumns + ` FROM subscription_events WHERE id > $1`
+	params := []interface{}{filter.After}
+
+	if filter.SubscriptionID != nil {
+		params = append(params, *filter.SubscriptionID)
+		query += fmt.Sprintf(" AND subscription_id = $%d", len(params))
+	}
+	if filter.Actor != nil {
+		params = append(params, *filter.Actor)
+		query += fmt.Sprintf(" AND actor = $%d", len(params))
+	}
+	if len(filter.Actions) > 0 {
+		params = append(params, filter.Actions)
+		query += fmt.Sprintf(" AND action = ANY($%d)", len(params))
+	}
+	if filter.From != nil {
+		params = append(params, *filter.From)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(params))
+	}
+	if filter.To != nil {
+		params = append(params, *filter.To)
+		query += fmt.Sprintf(" AND created_at < $%d", len(params))
+	}
+
+	limit := filter.PageSize()
+	params = append(params, limit+1)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(params))
+
+	rows, err := s.db.Query(ctx, query, params...)
+	if err != nil {
+		slog.Error("Failed to list subscription events", "error", err)
+		return nil, fmt.Errorf("error listing subscription events: %w", mapError(err))
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entities.SubscriptionEvent, error) {
+		event, err := scanEvent(row)
+		if err != nil {
+			return entities.SubscriptionEvent{}, err
+		}
+		return *event, nil
+	})
+	if err != nil {
+		slog.Error("Failed to list subscription events", "error", err)
+		return nil, fmt.Errorf("error listing subscription events: %w", mapError(err))
+	}
+
+	return entities.NewEventPage(events, limit), nil
+}
+
+// scanEvent читает событие из строки с колонками eventColumns
+func scanEvent(row pgx.Row) (*entities.SubscriptionEvent, error) {
+	var event entities.SubscriptionEvent
+	var before, after []byte
+
+	if err := row.Scan(&event.ID, &event.SubscriptionID, &event.Action, &event.Actor, &event.RequestID,
+		&before, &after, &event.CreatedAt); err != nil {
+		return nil, err
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+
+	var err error
+	if before != nil {
+		if event.Before, err = entities.DecodeSnapshot(before); err != nil {
+			return nil, fmt.Errorf("invalid stored snapshot of event %d: %w", event.ID, err)
+		}
+	}
+	if after != nil {
+		if event.After, err = entities.DecodeSnapshot(after); err != nil {
+			return nil, fmt.Errorf("invalid stored snapshot of event %d: %w", event.ID, err)
+		}
+	}
+	return &event, nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) (*entities.IdempotencyRecord, error) {
+	var key string
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (idempotency_key, fingerprint, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (idempotency_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = 0, content_type = '', body = NULL,
+			created_at = now(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		RETURNING idempotency_key`,
+		rec.Key, rec.Fingerprint, rec.ExpiresAt).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to reserve idempotency key", "error", err)
+		return nil, fmt.Errorf("error reserving idempotency key: %w", mapError(err))
+	}
+
+	existing := &entities.IdempotencyRecord{Key: rec.Key}
+	err = s.db.QueryRow(ctx, `
+		SELECT fingerprint, status, content_type, body, expires_at
+		FROM idempotency_keys WHERE idempotency_key = $1`, rec.Key).
+		Scan(&existing.Fingerprint, &existing.Status, &existing.ContentType, &existing.Body, &existing.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ключ удалили между вставкой и чтением: запрос с ним как раз завершился ошибкой
+		return nil, fmt.Errorf("error reserving idempotency key: %w", entities.ErrConflict)
+	}
+	if err != nil {
+		slog.Error("Failed to get idempotency key", "error", err)
+		return nil, fmt.Errorf("error reserving idempotency key: %w", mapError(err))
+	}
+	return existing, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE idempotency_keys SET status = $1, content_type = $2, body = $3
+		WHERE idempotency_key = $4 AND fingerprint = $5`,
+		rec.Status, rec.ContentType, rec.Body, rec.Key, rec.Fingerprint)
+	if err != nil {
+		slog.Error("Failed to complete idempotency key", "error", err)
+		return fmt.Errorf("error completing idempotency key: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = $1 AND status = 0`, key)
+	if err != nil {
+		slog.Error("Failed to release idempotency key", "error", err)
+		return fmt.Errorf("error releasing idempotency key: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if err != nil {
+		slog.Error("Failed to purge idempotency keys", "error", err)
+		return 0, fmt.Errorf("error purging idempotency keys: %w", mapError(err))
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// importColumns колонки, которые заполняет COPY при импорте
+var importColumns = []string{"id", "service_name", "amount_minor", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_anchor"}
+
+// importEventColumns колонки журнала, которые заполняет COPY событий создания при импорте
+var importEventColumns = []string{"subscription_id", "action", "actor", "request_id", "after_data"}
+
+// ImportSubscriptions сохраняет подписки одной командой COPY в одной транзакции.
+// COPY не возвращает сгенерированные ID, поэтому они заранее выделяются из последовательности.
+func (s *Storage) ImportSubscriptions(ctx context.Context, subs []entities.Subscriptions) ([]int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `SELECT nextval(pg_get_serial_sequence('subscriptions', 'id')) FROM generate_series(1, $1)`, len(subs))
+	if err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+
+	data := make([][]interface{}, len(subs))
+	for i := range subs {
+		startDate, endDate, err := subscriptionDates(&subs[i])
+		if err != nil {
+			return nil, fmt.Errorf("error importing subscriptions: row %d: %w", i+1, err)
+		}
+		billingPeriod, billingAnchor, err := subscriptionBilling(&subs[i])
+		if err != nil {
+			return nil, fmt.Errorf("error importing subscriptions: row %d: %w", i+1, err)
+		}
+		// Бинарный COPY не приводит строку к uuid, в отличие от параметров запроса
+		var userID pgtype.UUID
+		if err := userID.Scan(subs[i].UserID); err != nil {
+			return nil, fmt.Errorf("error importing subscriptions: row %d: user_id: %w: %w", i+1, entities.ErrValidation, err)
+		}
+		data[i] = []interface{}{ids[i], subs[i].ServiceName, subs[i].AmountMinor, subs[i].CurrencyCode(), userID, startDate, endDate, billingPeriod, billingAnchor}
+	}
+
+	copied, err := tx.CopyFrom(ctx, pgx.Identifier{"subscriptions"}, importColumns, pgx.CopyFromRows(data))
+	if err != nil {
+		slog.Error("Failed to copy subscriptions", "error", err)
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+
+	if err := recordImportEvents(ctx, tx, ids); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+
+	slog.Info("Subscriptions imported", "count", copied)
+	return ids, nil
+}
+
+// recordImportEvents записывает в журнал события создания импортированных подписок.
+// Снимки читаются из таблицы, чтобы в них попали значения, заполненные базой.
+func recordImportEvents(ctx context.Context, tx pgx.Tx, ids []int64) error {
+	rows, err := tx.Query(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return fmt.Errorf("error recording import events: %w", mapError(err))
+	}
+	created, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*entities.Subscriptions, error) {
+		return scanSubscription(row)
+	})
+	if err != nil {
+		return fmt.Errorf("error recording import events: %w", mapError(err))
+	}
+
+	data := make([][]interface{}, len(created))
+	for i, sub := range created {
+		event := entities.NewEvent(ctx, entities.EventCreate, nil, sub)
+		_, after, err := eventSnapshots(event)
+		if err != nil {
+			return fmt.Errorf("error recording import events: %w", err)
+		}
+		data[i] = []interface{}{event.SubscriptionID, event.Action, event.Actor, event.RequestID, after}
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"subscription_events"}, importEventColumns, pgx.CopyFromRows(data)); err != nil {
+		slog.Error("Failed to copy import events", "error", err)
+		return fmt.Errorf("error recording import events: %w", mapError(err))
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"log/slog"
+	"tz_effective/deploy/migrations"
+	"tz_effective/internal/adaper/storage/migrate"
+)
+
+// migrationLockID ключ advisory lock, под которым выполняются миграции.
+// Гарантирует, что несколько реплик не применяют миграции одновременно.
+const migrationLockID int64 = 4_872_310_615
+
+// Migrator применяет версионированные SQL-миграции и ведет их учет в таблице schema_migrations
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []migrate.Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	list, err := migrate.Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         pool,
+		migrations: list,
+	}, nil
+}
+
+// Migrator возвращает мигратор со встроенными в бинарник миграциями
+func (s *Storage) Migrator() (*Migrator, error) {
+	return NewMigrator(s.db, migrations.FS)
+}
+
+// Up применяет не более steps еще не примененных миграций (все, если steps <= 0).
+// Возвращает количество примененных миграций.
+func (m *Migrator) Up(ctx context.Context, steps int) (int, error) {
+	const op = "storage.postgres.Migrator.Up"
+
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if steps > 0 && applied >= steps {
+				break
+			}
+			if versions[mig.Version] {
+				continue
+			}
+
+			slog.Info("Applying migration", "version", mig.Version, "name", mig.Name)
+			if err := runMigration(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Migrations applied", "count", applied)
+	return applied, nil
+}
+
+// Down откатывает steps последних примененных миграций (одну, если steps <= 0).
+// Возвращает количество откаченных миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	const op = "storage.postgres.Migrator.Down"
+
+	if steps <= 0 {
+		steps = 1
+	}
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := m.migrations[i]
+			if !versions[mig.Version] {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down step", mig.Version, mig.Name)
+			}
+
+			slog.Info("Reverting migration", "version", mig.Version, "name", mig.Name)
+			if err := runMigration(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return reverted, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Migrations reverted", "count", reverted)
+	return reverted, nil
+}
+
+// Version возвращает максимальную примененную версию схемы (0, если миграции не применялись)
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.Migrator.Version"
+
+	var version int64
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// Baseline помечает миграции до version включительно как примененные, не выполняя их.
+// Нужен для БД, схема которых была создана до появления schema_migrations.
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	const op = "storage.postgres.Migrator.Baseline"
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return m.baseline(ctx, conn, version)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Migrations baselined", "version", version)
+	return nil
+}
+
+// Seed выполняет SQL с тестовыми данными в одной транзакции. Только для dev-окружения,
+// схема должна быть приведена к последней версии через Up.
+func (m *Migrator) Seed(ctx context.Context, sql string) error {
+	const op = "storage.postgres.Migrator.Seed"
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.Info("Seed data loaded")
+	return nil
+}
+
+// baseline отмечает миграции до version включительно примененными на соединении conn
+func (m *Migrator) baseline(ctx context.Context, conn *pgxpool.Conn, version int64) error {
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, err := conn.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`,
+			mig.Version, mig.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock выполняет fn под advisory lock, создав schema_migrations и отметив версию старой схемы (см. legacyVersion)
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			slog.Error("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	legacy, err := legacyVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	if legacy > 0 {
+		slog.Info("Baselining existing schema", "version", legacy)
+		if err := m.baseline(ctx, conn, legacy); err != nil {
+			return fmt.Errorf("baseline existing schema: %w", err)
+		}
+	}
+
+	return fn(conn)
+}
+
+// legacyVersion возвращает версию схемы, созданной docker-entrypoint-initdb.d до schema_migrations (2 или 3), иначе 0
+func legacyVersion(ctx context.Context, conn *pgxpool.Conn) (int64, error) {
+	var hasMigrations, hasSubscriptions bool
+	if err := conn.QueryRow(ctx,
+		`SELECT to_regclass('schema_migrations') IS NOT NULL, to_regclass('subscriptions') IS NOT NULL`,
+	).Scan(&hasMigrations, &hasSubscriptions); err != nil {
+		return 0, fmt.Errorf("detect existing schema: %w", err)
+	}
+	if hasMigrations || !hasSubscriptions {
+		return 0, nil
+	}
+
+	var dataType string
+	if err := conn.QueryRow(ctx, `
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'subscriptions' AND column_name = 'start_date'`,
+	).Scan(&dataType); err != nil {
+		return 0, fmt.Errorf("detect existing schema: %w", err)
+	}
+	if dataType == "date" {
+		return 3, nil
+	}
+	return 2, nil
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]bool, error) {
+	rows, err := conn.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions[version] = true
+	}
+
+	return versions, rows.Err()
+}
+
+// runMigration выполняет SQL миграции и запись в schema_migrations в одной транзакции
+func runMigration(ctx context.Context, conn *pgxpool.Conn, sql string, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
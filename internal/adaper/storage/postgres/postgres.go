@@ -2,14 +2,30 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log/slog"
+	"strings"
 	"time"
 	"tz_effective/deploy/config"
 	"tz_effective/internal/entities"
 )
 
+// subscriptionColumns колонки подписки в порядке, ожидаемом scanSubscription
+const subscriptionColumns = `id, service_name, amount_minor, currency, user_id, start_date, end_date, billing_period, billing_anchor,
+	version, created_at, updated_at, deleted_at`
+
+// querier общие методы пула соединений и транзакции, чтобы одни и те же запросы
+// выполнялись как отдельно, так и внутри пакета
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
 type Storage struct {
 	db  *pgxpool.Pool
 	cfg *config.Config
@@ -65,147 +81,524 @@ func New(ctx context.Context, cfg *config.Config) (*Storage, error) {
 	return storageBD, nil
 }
 
+// inTx выполняет fn в транзакции, чтобы изменение подписки и его событие в журнале сохранялись вместе
+func inTx[T any](ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) (T, error)) (T, error) {
+	var zero T
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return zero, fmt.Errorf("error starting transaction: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := fn(tx)
+	if err != nil {
+		return zero, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return zero, fmt.Errorf("error committing transaction: %w", mapError(err))
+	}
+	return result, nil
+}
+
 func (s *Storage) CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (int64, error) {
-	row := s.db.QueryRow(ctx, `INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-		sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate)
-	var id int64
-	if err := row.Scan(&id); err != nil {
-		slog.Error("Failed to create subscription", "error", err)
+	return inTx(ctx, s.db, func(tx pgx.Tx) (int64, error) {
+		return createSubscription(ctx, tx, sub)
+	})
+}
+
+func createSubscription(ctx context.Context, q querier, sub *entities.Subscriptions) (int64, error) {
+	startDate, endDate, err := subscriptionDates(sub)
+	if err != nil {
 		return 0, fmt.Errorf("error creating subscription: %w", err)
 	}
-	return id, nil
+	billingPeriod, billingAnchor, err := subscriptionBilling(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error creating subscription: %w", err)
+	}
+
+	created, err := scanSubscription(q.QueryRow(ctx, `
+		INSERT INTO subscriptions (service_name, amount_minor, currency, user_id, start_date, end_date, billing_period, billing_anchor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+subscriptionColumns,
+		sub.ServiceName, sub.AmountMinor, sub.CurrencyCode(), sub.UserID, startDate, endDate, billingPeriod, billingAnchor))
+	if err != nil {
+		slog.Error("Failed to create subscription", "error", err)
+		return 0, fmt.Errorf("error creating subscription: %w", mapError(err))
+	}
+
+	if err := recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventCreate, nil, created)); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
 }
 
 func (s *Storage) GetSubscription(ctx context.Context, id int64) (*entities.Subscriptions, error) {
-	row := s.db.QueryRow(ctx, `SELECT service_name, price, user_id, start_date, end_date FROM subscriptions WHERE id = $1`, id)
-	var sub entities.Subscriptions
-	if err := row.Scan(&sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &sub.EndDate); err != nil {
+	row := s.db.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1 AND deleted_at IS NULL`, id)
+	sub, err := scanSubscription(row)
+	if err == nil {
+		sub, err = withPrices(ctx, s.db, sub)
+	}
+	if err != nil {
 		slog.Error("Failed to get subscription", "error", err, "id", id)
-		return nil, fmt.Errorf("error getting subscription with ID %d: %w", id, err)
+		return nil, fmt.Errorf("error getting subscription with ID %d: %w", id, mapError(err))
 	}
-	return &sub, nil
+	return sub, nil
 }
 
-func (s *Storage) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions) error {
-	result, err := s.db.Exec(ctx, `UPDATE subscriptions SET service_name = $1, price = $2, user_id = $3, start_date = $4, end_date = $5 WHERE id = $6`,
-		sub.ServiceName, sub.Price, sub.UserID, sub.StartDate, sub.EndDate, id)
+func (s *Storage) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	return inTx(ctx, s.db, func(tx pgx.Tx) (int64, error) {
+		return updateSubscription(ctx, tx, id, sub, version)
+	})
+}
+
+func updateSubscription(ctx context.Context, q querier, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	startDate, endDate, err := subscriptionDates(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+	}
+	billingPeriod, billingAnchor, err := subscriptionBilling(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+	}
+
+	before, err := lockSubscription(ctx, q, id, version, false)
+	if err != nil {
+		slog.Warn("No subscription updated", "id", id, "version", version, "error", err)
+		return 0, err
+	}
+
+	after, err := scanSubscription(q.QueryRow(ctx, `
+		UPDATE subscriptions
+		SET service_name = $1, amount_minor = $2, currency = $3, user_id = $4, start_date = $5, end_date = $6,
+			billing_period = $7, billing_anchor = $8, updated_at = now(), version = version + 1
+		WHERE id = $9
+		RETURNING `+subscriptionColumns,
+		sub.ServiceName, sub.AmountMinor, sub.CurrencyCode(), sub.UserID, startDate, endDate, billingPeriod, billingAnchor, id))
 	if err != nil {
 		slog.Error("Failed to update subscription", "error", err, "id", id)
-		return fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, mapError(err))
 	}
+	after.Prices = before.Prices
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		slog.Warn("No subscription found for update", "id", id)
-		return fmt.Errorf("subscription with ID %d not found", id)
+	if err := recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventUpdate, before, after)); err != nil {
+		return 0, err
 	}
+	return after.Version, nil
+}
 
-	return nil
+func (s *Storage) PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64,
+	check func(*entities.Subscriptions) error) (*entities.Subscriptions, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	current, err := lockSubscription(ctx, tx, id, version, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Empty() {
+		return current, nil
+	}
+
+	patched := *current
+	patch.Apply(&patched)
+	if err := check(&patched); err != nil {
+		return nil, err
+	}
+
+	sets, params, err := patchAssignments(patch)
+	if err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, err)
+	}
+	params = append(params, id)
+
+	query := fmt.Sprintf(`UPDATE subscriptions SET %s, updated_at = now(), version = version + 1 WHERE id = $%d RETURNING `+subscriptionColumns,
+		strings.Join(sets, ", "), len(params))
+	updated, err := scanSubscription(tx.QueryRow(ctx, query, params...))
+	if err != nil {
+		slog.Error("Failed to patch subscription", "error", err, "id", id)
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+	updated.Prices = current.Prices
+
+	if err := recordEvent(ctx, tx, entities.NewEvent(ctx, entities.EventUpdate, current, updated)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+
+	return updated, nil
+}
+
+// patchAssignments возвращает присваивания SET только для переданных в патче полей
+func patchAssignments(patch *entities.SubscriptionPatch) ([]string, []interface{}, error) {
+	var sets []string
+	var params []interface{}
+	set := func(column string, value interface{}) {
+		params = append(params, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(params)))
+	}
+
+	if patch.ServiceName != nil {
+		set("service_name", *patch.ServiceName)
+	}
+	if patch.AmountMinor != nil {
+		set("amount_minor", *patch.AmountMinor)
+	} else if patch.Price != nil {
+		set("amount_minor", *patch.Price*entities.MinorUnits)
+	}
+	if patch.Currency != nil {
+		set("currency", *patch.Currency)
+	}
+	if patch.UserID != nil {
+		set("user_id", *patch.UserID)
+	}
+	if patch.StartDate != nil {
+		startDate, err := toDate(*patch.StartDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("start_date: %w", err)
+		}
+		set("start_date", startDate)
+	}
+	if patch.SetEndDate {
+		endDate, err := toNullDate(patch.EndDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("end_date: %w", err)
+		}
+		set("end_date", endDate)
+	}
+	if patch.BillingPeriod != nil {
+		set("billing_period", *patch.BillingPeriod)
+	}
+	if patch.SetBillingAnchor {
+		billingAnchor, err := toNullDate(patch.BillingAnchor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("billing_anchor: %w", err)
+		}
+		set("billing_anchor", billingAnchor)
+	}
+
+	return sets, params, nil
+}
+
+func (s *Storage) DeleteSubscription(ctx context.Context, id int64, version int64) error {
+	_, err := inTx(ctx, s.db, func(tx pgx.Tx) (struct{}, error) {
+		return struct{}{}, deleteSubscription(ctx, tx, id, version)
+	})
+	return err
 }
 
-func (s *Storage) DeleteSubscription(ctx context.Context, id int64) error {
-	result, err := s.db.Exec(ctx, `DELETE FROM subscriptions WHERE id = $1`, id)
+func deleteSubscription(ctx context.Context, q querier, id int64, version int64) error {
+	before, err := lockSubscription(ctx, q, id, version, false)
+	if err != nil {
+		slog.Warn("No subscription deleted", "id", id, "version", version, "error", err)
+		return err
+	}
+
+	after, err := scanSubscription(q.QueryRow(ctx, `
+		UPDATE subscriptions
+		SET deleted_at = now(), updated_at = now(), version = version + 1
+		WHERE id = $1
+		RETURNING `+subscriptionColumns, id))
 	if err != nil {
 		slog.Error("Failed to delete subscription", "error", err, "id", id)
-		return fmt.Errorf("error deleting subscription with ID %d: %w", id, err)
+		return fmt.Errorf("error deleting subscription with ID %d: %w", id, mapError(err))
 	}
+	after.Prices = before.Prices
+
+	return recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventDelete, before, after))
+}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		slog.Warn("No subscription found for deletion", "id", id)
-		return fmt.Errorf("subscription with ID %d not found", id)
+// lockSubscription читает подписку, блокирует ее строку до конца транзакции и проверяет версию
+func lockSubscription(ctx context.Context, q querier, id int64, version int64, deleted bool) (*entities.Subscriptions, error) {
+	condition := "deleted_at IS NULL"
+	if deleted {
+		condition = "deleted_at IS NOT NULL"
 	}
 
-	return nil
+	sub, err := scanSubscription(q.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1 AND `+condition+` FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("subscription with ID %d: %w", id, entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error locking subscription with ID %d: %w", id, mapError(err))
+	}
+
+	if version != entities.AnyVersion && sub.Version != version {
+		return nil, fmt.Errorf("subscription with ID %d has version %d: %w", id, sub.Version, entities.ErrVersionMismatch)
+	}
+
+	sub, err = withPrices(ctx, q, sub)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prices of subscription with ID %d: %w", id, mapError(err))
+	}
+	return sub, nil
+}
+
+// ListSubscriptions возвращает страницу подписок по фильтру в порядке filter.Order().
+// Размер страницы ограничен entities.MaxPageSize.
+func (s *Storage) ListSubscriptions(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error) {
+	conditions, params, err := listConditions(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+
+	order := filter.Order()
+	orderClause, err := orderBy(order)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE 1=1` + conditions
+	pageParams := append([]interface{}{}, params...)
+
+	if filter.After != nil {
+		keyset, keysetParams, err := keysetCondition(order, filter.After, len(pageParams)+1)
+		if err != nil {
+			return nil, fmt.Errorf("error listing subscriptions: %w", err)
+		}
+		query += " AND " + keyset
+		pageParams = append(pageParams, keysetParams...)
+	}
+
+	limit := filter.PageSize()
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderClause, len(pageParams)+1)
+	pageParams = append(pageParams, limit+1)
+
+	rows, err := s.db.Query(ctx, query, pageParams...)
+	if err != nil {
+		slog.Error("Failed to list subscriptions", "error", err)
+		return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+	}
+	defer rows.Close()
+
+	var subs []entities.Subscriptions
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+		}
+		subs = append(subs, *sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Failed to list subscriptions", "error", err)
+		return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+	}
+
+	page := entities.NewPage(subs, limit, order)
+	if err := attachPrices(ctx, s.db, page.Items); err != nil {
+		slog.Error("Failed to list subscription prices", "error", err)
+		return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+	}
+
+	if filter.WithTotal {
+		var total int64
+		if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM subscriptions WHERE 1=1`+conditions, params...).Scan(&total); err != nil {
+			slog.Error("Failed to count subscriptions", "error", err)
+			return nil, fmt.Errorf("error counting subscriptions: %w", mapError(err))
+		}
+		page.Total = &total
+	}
+
+	return page, nil
 }
 
-func (s *Storage) ListSubscriptions(ctx context.Context, filter *entities.ListFilter) ([]entities.Subscriptions, error) {
-	query := `SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE 1=1`
+// listConditions собирает условия WHERE фильтра списка (без пагинации) и их параметры, начиная с $1
+func listConditions(filter *entities.ListFilter) (string, []interface{}, error) {
+	query := " AND deleted_at IS NULL"
+	if filter.Deleted {
+		query = " AND deleted_at IS NOT NULL"
+	}
 	params := []interface{}{}
 	paramIndex := 1
 
-	if filter.UserID != nil {
-		query += fmt.Sprintf(" AND user_id = $%d", paramIndex)
-		params = append(params, *filter.UserID)
+	if len(filter.UserIDs) > 0 {
+		query += fmt.Sprintf(" AND user_id = ANY($%d::uuid[])", paramIndex)
+		params = append(params, filter.UserIDs)
+		paramIndex++
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		query += fmt.Sprintf(" AND service_name = ANY($%d)", paramIndex)
+		params = append(params, filter.ServiceNames)
 		paramIndex++
 	}
 
-	if filter.ServiceName != nil {
-		query += fmt.Sprintf(" AND service_name = $%d", paramIndex)
-		params = append(params, *filter.ServiceName)
+	if filter.Search != nil {
+		query += fmt.Sprintf(" AND service_name ILIKE $%d", paramIndex)
+		params = append(params, "%"+likeEscaper.Replace(*filter.Search)+"%")
 		paramIndex++
 	}
 
 	if filter.StartDate != nil {
+		startDate, err := toDate(*filter.StartDate)
+		if err != nil {
+			return "", nil, fmt.Errorf("start_date: %w", err)
+		}
 		query += fmt.Sprintf(" AND start_date >= $%d", paramIndex)
-		params = append(params, *filter.StartDate)
+		params = append(params, startDate)
 		paramIndex++
 	}
 
 	if filter.EndDate != nil {
+		endDate, err := toDate(*filter.EndDate)
+		if err != nil {
+			return "", nil, fmt.Errorf("end_date: %w", err)
+		}
 		query += fmt.Sprintf(" AND end_date <= $%d", paramIndex)
-		params = append(params, *filter.EndDate)
+		params = append(params, endDate)
 		paramIndex++
 	}
 
-	rows, err := s.db.Query(ctx, query, params...)
-	if err != nil {
-		return nil, err
+	if filter.ActiveOn != nil {
+		activeOn, err := toDate(*filter.ActiveOn)
+		if err != nil {
+			return "", nil, fmt.Errorf("active_on: %w", err)
+		}
+		query += fmt.Sprintf(" AND start_date <= $%d AND (end_date IS NULL OR end_date >= $%d)", paramIndex, paramIndex)
+		params = append(params, activeOn)
+		paramIndex++
 	}
-	defer rows.Close()
 
-	var subs []entities.Subscriptions
-	for rows.Next() {
-		var sub entities.Subscriptions
-		var id int64
-		var endDate *string
+	if len(filter.Currencies) > 0 {
+		query += fmt.Sprintf(" AND currency = ANY($%d)", paramIndex)
+		params = append(params, filter.Currencies)
+		paramIndex++
+	}
 
-		if err := rows.Scan(&id, &sub.ServiceName, &sub.Price, &sub.UserID, &sub.StartDate, &endDate); err != nil {
-			return nil, err
-		}
+	minAmount, maxAmount := filter.AmountRange()
+	if minAmount != nil {
+		query += fmt.Sprintf(" AND amount_minor >= $%d", paramIndex)
+		params = append(params, *minAmount)
+		paramIndex++
+	}
 
-		sub.EndDate = endDate
-		subs = append(subs, sub)
+	if maxAmount != nil {
+		query += fmt.Sprintf(" AND amount_minor <= $%d", paramIndex)
+		params = append(params, *maxAmount)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if filter.HasEndDate != nil {
+		if *filter.HasEndDate {
+			query += " AND end_date IS NOT NULL"
+		} else {
+			query += " AND end_date IS NULL"
+		}
 	}
 
-	return subs, nil
+	return query, params, nil
 }
 
-func (s *Storage) CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (int64, error) {
-	query := `
-		SELECT COALESCE(SUM(price), 0) AS total_cost
-		FROM subscriptions
-		WHERE 1=1
-		AND (
-			(end_date IS NULL OR end_date >= $1)
-			AND start_date <= $2
-		)
-	`
-	params := []interface{}{filter.StartPeriod, filter.EndPeriod}
-	paramIndex := 3
+// likeEscaper экранирует спецсимволы шаблона LIKE (экранирующий символ по умолчанию \)
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sortColumns выражения ORDER BY для полей сортировки из entities. В SQL попадают только они,
+// а не значения параметров запроса. Названия сравниваются побайтно, как в остальных хранилищах.
+var sortColumns = map[string]string{
+	entities.SortID:          "id",
+	entities.SortServiceName: `service_name COLLATE "C"`,
+	entities.SortPrice:       "amount_minor",
+	entities.SortStartDate:   "start_date",
+	entities.SortCreatedAt:   "created_at",
+	entities.SortUpdatedAt:   "updated_at",
+}
 
-	if filter.UserID != nil {
-		query += fmt.Sprintf(" AND user_id = $%d", paramIndex)
-		params = append(params, *filter.UserID)
-		paramIndex++
+func orderBy(order []entities.SortField) (string, error) {
+	parts := make([]string, 0, len(order))
+	for _, f := range order {
+		column, ok := sortColumns[f.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported sort field %q", entities.ErrValidation, f.Field)
+		}
+		if f.Desc {
+			column += " DESC"
+		}
+		parts = append(parts, column)
 	}
+	return strings.Join(parts, ", "), nil
+}
+
+// keysetCondition строит условие "запись после курсора" для порядка order с параметрами от paramIndex
+func keysetCondition(order []entities.SortField, cursor *entities.ListCursor, paramIndex int) (string, []interface{}, error) {
+	key, err := cursor.Key(order)
+	if err != nil {
+		return "", nil, err
+	}
+
+	params := make([]interface{}, 0, len(order))
+	terms := make([]string, 0, len(order))
+	var equal []string
 
-	if filter.ServiceName != nil {
-		query += fmt.Sprintf(" AND service_name = $%d", paramIndex)
-		params = append(params, *filter.ServiceName)
+	for _, f := range order {
+		value, err := sortValue(key, f.Field)
+		if err != nil {
+			return "", nil, fmt.Errorf("cursor: %w", err)
+		}
+		params = append(params, value)
+
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		column := sortColumns[f.Field]
+		placeholder := fmt.Sprintf("$%d", paramIndex)
 		paramIndex++
+
+		term := append(append([]string{}, equal...), fmt.Sprintf("%s %s %s", column, op, placeholder))
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+		equal = append(equal, fmt.Sprintf("%s = %s", column, placeholder))
 	}
 
-	var totalCost int64
-	err := s.db.QueryRow(ctx, query, params...).Scan(&totalCost)
-	if err != nil {
-		slog.Error("Failed to calculate total cost", "error", err, "filter", filter)
-		return 0, fmt.Errorf("error calculating total cost: %w", err)
+	return "(" + strings.Join(terms, " OR ") + ")", params, nil
+}
+
+// sortValue возвращает значение ключа курсора в виде параметра запроса
+func sortValue(key *entities.Subscriptions, field string) (interface{}, error) {
+	switch field {
+	case entities.SortID:
+		return key.ID, nil
+	case entities.SortServiceName:
+		return key.ServiceName, nil
+	case entities.SortPrice:
+		return key.AmountMinor, nil
+	case entities.SortStartDate:
+		return toDate(key.StartDate)
+	case entities.SortCreatedAt:
+		return key.CreatedAt, nil
+	case entities.SortUpdatedAt:
+		return key.UpdatedAt, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported sort field %q", entities.ErrValidation, field)
+	}
+}
+
+// scanSubscription читает подписку из строки с колонками subscriptionColumns
+func scanSubscription(row pgx.Row) (*entities.Subscriptions, error) {
+	var sub entities.Subscriptions
+	var startDate time.Time
+	var endDate, billingAnchor *time.Time
+
+	if err := row.Scan(&sub.ID, &sub.ServiceName, &sub.AmountMinor, &sub.Currency, &sub.UserID, &startDate, &endDate,
+		&sub.BillingPeriod, &billingAnchor, &sub.Version, &sub.CreatedAt, &sub.UpdatedAt, &sub.DeletedAt); err != nil {
+		return nil, err
 	}
 
-	return totalCost, nil
+	sub.StartDate = fromDate(startDate)
+	sub.EndDate = fromNullDate(endDate)
+	sub.BillingAnchor = fromNullDate(billingAnchor)
+	sub.FillAmounts()
+	sub.CreatedAt = sub.CreatedAt.UTC()
+	sub.UpdatedAt = sub.UpdatedAt.UTC()
+	if sub.DeletedAt != nil {
+		deletedAt := sub.DeletedAt.UTC()
+		sub.DeletedAt = &deletedAt
+	}
+
+	return &sub, nil
 }
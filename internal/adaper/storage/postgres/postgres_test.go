@@ -0,0 +1,98 @@
+package postgres_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/deploy/migrations"
+	"tz_effective/internal/adaper/storage/postgres"
+	"tz_effective/internal/service"
+	"tz_effective/internal/service/storagetest"
+)
+
+// Тесты выполняются против реальной БД, DSN которой задан в TEST_POSTGRES_DSN; в CI без нее тесты падают.
+// Схема приводится к последней версии встроенными миграциями, таблицы очищаются перед каждым тестом.
+func TestStorage(t *testing.T) {
+	pool, _ := connect(t)
+
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		truncate(t, pool)
+		return postgres.NewStorage(pool, &config.Config{})
+	})
+}
+
+// TestCurrencyMigration откатывает миграцию валют мигратором и применяет ее снова
+func TestCurrencyMigration(t *testing.T) {
+	ctx := context.Background()
+	pool, migrator := connect(t)
+	truncate(t, pool)
+	s := postgres.NewStorage(pool, &config.Config{})
+
+	storagetest.RunCurrencyMigration(t, storagetest.CurrencyMigration{
+		Storage: s,
+		Down: func(t *testing.T) error {
+			_, err := migrator.Down(ctx, 1)
+			return err
+		},
+		Up: func(t *testing.T) service.Storage {
+			if _, err := migrator.Up(ctx, 0); err != nil {
+				t.Fatalf("migrator.Up: %v", err)
+			}
+			// подготовленные до отката запросы ссылаются на прежнюю схему
+			pool.Reset()
+			return s
+		},
+		Exec: func(t *testing.T, query string) {
+			if _, err := pool.Exec(ctx, query); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+		},
+		QueryInt: func(t *testing.T, query string) int64 {
+			var value int64
+			if err := pool.QueryRow(ctx, query).Scan(&value); err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			return value
+		},
+	})
+}
+
+// connect подключается к тестовой БД и применяет встроенные миграции
+func connect(t *testing.T) (*pgxpool.Pool, *postgres.Migrator) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_POSTGRES_DSN is not set in CI")
+		}
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("pgxpool.New: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := postgres.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatalf("migrator.Up: %v", err)
+	}
+	return pool, migrator
+}
+
+func truncate(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	if _, err := pool.Exec(context.Background(), `TRUNCATE subscriptions, subscription_prices, subscription_events, idempotency_keys, exchange_rates RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
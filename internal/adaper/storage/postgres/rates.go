@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) ListExchangeRates(ctx context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error) {
+	rates, err := exchangeRates(ctx, s.db, filter.Currencies)
+	if err != nil {
+		slog.Error("Failed to list exchange rates", "error", err)
+		return nil, fmt.Errorf("error listing exchange rates: %w", mapError(err))
+	}
+	return rates, nil
+}
+
+func (s *Storage) SetExchangeRates(ctx context.Context, rates []entities.ExchangeRate) error {
+	_, err := inTx(ctx, s.db, func(tx pgx.Tx) (struct{}, error) {
+		for _, r := range rates {
+			month, err := toDate(r.Month)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("error saving exchange rate %s: month: %w", r.Currency, err)
+			}
+			rate, err := entities.NormalizeRate(r.Rate)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("error saving exchange rate %s for %s: %w", r.Currency, r.Month, err)
+			}
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO exchange_rates (currency, month, rate)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (currency, month) DO UPDATE SET rate = excluded.rate`,
+				r.Currency, month, rate); err != nil {
+				slog.Error("Failed to save exchange rate", "error", err, "currency", r.Currency, "month", r.Month)
+				return struct{}{}, fmt.Errorf("error saving exchange rate %s for %s: %w", r.Currency, r.Month, mapError(err))
+			}
+		}
+		return struct{}{}, nil
+	})
+	return err
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, currency, month string) error {
+	date, err := toDate(month)
+	if err != nil {
+		return fmt.Errorf("error deleting exchange rate %s: month: %w", currency, err)
+	}
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM exchange_rates WHERE currency = $1 AND month = $2`, currency, date)
+	if err != nil {
+		slog.Error("Failed to delete exchange rate", "error", err, "currency", currency, "month", month)
+		return fmt.Errorf("error deleting exchange rate %s for %s: %w", currency, month, mapError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("error deleting exchange rate %s for %s: %w", currency, month, entities.ErrNotFound)
+	}
+	return nil
+}
+
+// exchangeRates выбирает курсы валют currencies (всех валют, если список пуст) по валюте и месяцу
+func exchangeRates(ctx context.Context, q querier, currencies []string) ([]entities.ExchangeRate, error) {
+	rows, err := q.Query(ctx, `
+		SELECT currency, month, rate::text
+		FROM exchange_rates
+		WHERE coalesce(cardinality($1::text[]), 0) = 0 OR currency = ANY($1)
+		ORDER BY currency, month`, currencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []entities.ExchangeRate{}
+	for rows.Next() {
+		var r entities.ExchangeRate
+		var month time.Time
+		if err := rows.Scan(&r.Currency, &month, &r.Rate); err != nil {
+			return nil, err
+		}
+		r.Month = fromDate(month)
+		// NUMERIC возвращается с нулями до масштаба колонки
+		if r.Rate, err = entities.NormalizeRate(r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
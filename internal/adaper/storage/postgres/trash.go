@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) RestoreSubscription(ctx context.Context, id int64, version int64) (*entities.Subscriptions, error) {
+	return inTx(ctx, s.db, func(tx pgx.Tx) (*entities.Subscriptions, error) {
+		before, err := lockSubscription(ctx, tx, id, version, true)
+		if err != nil {
+			slog.Warn("No subscription restored", "id", id, "version", version, "error", err)
+			return nil, err
+		}
+
+		after, err := scanSubscription(tx.QueryRow(ctx, `
+			UPDATE subscriptions
+			SET deleted_at = NULL, updated_at = now(), version = version + 1
+			WHERE id = $1
+			RETURNING `+subscriptionColumns, id))
+		if err != nil {
+			slog.Error("Failed to restore subscription", "error", err, "id", id)
+			return nil, fmt.Errorf("error restoring subscription with ID %d: %w", id, mapError(err))
+		}
+		after.Prices = before.Prices
+
+		if err := recordEvent(ctx, tx, entities.NewEvent(ctx, entities.EventRestore, before, after)); err != nil {
+			return nil, err
+		}
+		return after, nil
+	})
+}
+
+func (s *Storage) PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM subscriptions WHERE deleted_at <= $1`, before)
+	if err != nil {
+		slog.Error("Failed to purge deleted subscriptions", "error", err)
+		return 0, fmt.Errorf("error purging deleted subscriptions: %w", mapError(err))
+	}
+	return tag.RowsAffected(), nil
+}
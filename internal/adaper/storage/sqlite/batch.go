@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// ApplyBatch выполняет операции пакета в одной транзакции, каждую в неатомарном режиме - в своей точке сохранения
+func (s *Storage) ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]entities.BatchResult, len(ops))
+	for i := range ops {
+		if atomic {
+			results[i] = applyOperation(ctx, tx, &ops[i])
+			if results[i].Err != nil {
+				slog.Warn("Batch aborted", "index", i, "error", results[i].Err)
+				return entities.AbortBatch(ops, results[i], i), nil
+			}
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_operation`); err != nil {
+			return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+		}
+		results[i] = applyOperation(ctx, tx, &ops[i])
+		if results[i].Err != nil {
+			// ROLLBACK TO оставляет точку сохранения, RELEASE ее снимает
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_operation`); err != nil {
+				return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE batch_operation`); err != nil {
+			return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error applying batch: %w", mapError(err))
+	}
+	return results, nil
+}
+
+// applyOperation выполняет одну операцию пакета
+func applyOperation(ctx context.Context, q querier, op *entities.BatchOperation) entities.BatchResult {
+	switch op.Op {
+	case entities.BatchCreate:
+		id, err := createSubscription(ctx, q, op.Subscription)
+		if err != nil {
+			return entities.BatchResult{Err: err}
+		}
+		return entities.BatchResult{ID: id, Version: 1}
+	case entities.BatchUpdate:
+		version, err := updateSubscription(ctx, q, op.ID, op.Subscription, op.Version)
+		return entities.BatchResult{ID: op.ID, Version: version, Err: err}
+	case entities.BatchDelete:
+		return entities.BatchResult{ID: op.ID, Err: deleteSubscription(ctx, q, op.ID, op.Version)}
+	default:
+		return entities.BatchResult{ID: op.ID, Err: fmt.Errorf("unknown batch operation %q: %w", op.Op, entities.ErrValidation)}
+	}
+}
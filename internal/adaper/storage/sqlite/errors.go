@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"tz_effective/internal/entities"
+)
+
+// mapError оборачивает ошибку SQLite в ошибку предметной области из entities, сохраняя исходную в цепочке
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", entities.ErrNotFound, err)
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		switch primary := code & 0xff; {
+		case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE, code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %w", entities.ErrConflict, err)
+		case primary == sqlite3.SQLITE_CONSTRAINT:
+			return fmt.Errorf("%w: %w", entities.ErrValidation, err)
+		case primary == sqlite3.SQLITE_BUSY, primary == sqlite3.SQLITE_LOCKED, primary == sqlite3.SQLITE_CANTOPEN,
+			primary == sqlite3.SQLITE_IOERR, primary == sqlite3.SQLITE_FULL:
+			return fmt.Errorf("%w: %w", entities.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, sql.ErrConnDone) {
+		return fmt.Errorf("%w: %w", entities.ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// eventColumns колонки журнала в порядке, ожидаемом scanEvent
+const eventColumns = `id, subscription_id, action, actor, request_id, before_data, after_data, created_at`
+
+// recordEvent записывает событие в журнал в транзакции изменения
+func recordEvent(ctx context.Context, q querier, event *entities.SubscriptionEvent) error {
+	before, err := snapshot(event.Before)
+	if err != nil {
+		return fmt.Errorf("error recording event for subscription with ID %d: %w", event.SubscriptionID, err)
+	}
+	after, err := snapshot(event.After)
+	if err != nil {
+		return fmt.Errorf("error recording event for subscription with ID %d: %w", event.SubscriptionID, err)
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO subscription_events (subscription_id, action, actor, request_id, before_data, after_data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.SubscriptionID, event.Action, event.Actor, event.RequestID, before, after, timestamp(time.Now()))
+	if err != nil {
+		slog.Error("Failed to record subscription event", "error", err, "id", event.SubscriptionID, "action", event.Action)
+		return fmt.Errorf("error recording event for subscription with ID %d: %w", event.SubscriptionID, mapError(err))
+	}
+	return nil
+}
+
+// snapshot возвращает JSON снимка подписки для колонки журнала, nil для отсутствующего снимка
+func snapshot(sub *entities.Subscriptions) (*string, error) {
+	if sub == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	value := string(data)
+	return &value, nil
+}
+
+// ListEvents возвращает страницу журнала по фильтру в порядке записи
+func (s *Storage) ListEvents(ctx context.Context, filter *entities.EventFilter) (*entities.EventPage, error) {
+	query := `SELECT ` + eventColumns + ` FROM subscription_events WHERE id > ?`
+	params := []interface{}{filter.After}
+
+	if filter.SubscriptionID != nil {
+		query += " AND subscription_id = ?"
+		params = append(params, *filter.SubscriptionID)
+	}
+	if filter.Actor != nil {
+		query += " AND actor = ?"
+		params = append(params, *filter.Actor)
+	}
+	if len(filter.Actions) > 0 {
+		query += " AND action IN (" + placeholders(len(filter.Actions)) + ")"
+		for _, action := range filter.Actions {
+			params = append(params, action)
+		}
+	}
+	if filter.From != nil {
+		query += " AND created_at >= ?"
+		params = append(params, timestamp(*filter.From))
+	}
+	if filter.To != nil {
+		query += " AND created_at < ?"
+		params = append(params, timestamp(*filter.To))
+	}
+
+	limit := filter.PageSize()
+	query += " ORDER BY id LIMIT ?"
+	params = append(params, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		slog.Error("Failed to list subscription events", "error", err)
+		return nil, fmt.Errorf("error listing subscription events: %w", mapError(err))
+	}
+	defer rows.Close()
+
+	var events []entities.SubscriptionEvent
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error listing subscription events: %w", mapError(err))
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Failed to list subscription events", "error", err)
+		return nil, fmt.Errorf("error listing subscription events: %w", mapError(err))
+	}
+
+	return entities.NewEventPage(events, limit), nil
+}
+
+// scanEvent читает событие из строки с колонками eventColumns
+func scanEvent(row scanner) (*entities.SubscriptionEvent, error) {
+	var event entities.SubscriptionEvent
+	var before, after sql.NullString
+	var createdAt string
+
+	if err := row.Scan(&event.ID, &event.SubscriptionID, &event.Action, &event.Actor, &event.RequestID,
+		&before, &after, &createdAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if event.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+		return nil, err
+	}
+	if before.Valid {
+		if event.Before, err = entities.DecodeSnapshot([]byte(before.String)); err != nil {
+			return nil, fmt.Errorf("invalid stored snapshot of event %d: %w", event.ID, err)
+		}
+	}
+	if after.Valid {
+		if event.After, err = entities.DecodeSnapshot([]byte(after.String)); err != nil {
+			return nil, fmt.Errorf("invalid stored snapshot of event %d: %w", event.ID, err)
+		}
+	}
+	return &event, nil
+}
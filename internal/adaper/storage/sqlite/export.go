@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// ExportSubscriptions передает fn подписки по мере чтения строк, занимая единственное соединение с БД
+func (s *Storage) ExportSubscriptions(ctx context.Context, filter *entities.ListFilter, fn func(*entities.Subscriptions) error) error {
+	conditions, params, err := listConditions(filter)
+	if err != nil {
+		return fmt.Errorf("error exporting subscriptions: %w", err)
+	}
+	orderClause, err := orderBy(filter.Order())
+	if err != nil {
+		return fmt.Errorf("error exporting subscriptions: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE 1=1`+conditions+` ORDER BY `+orderClause, params...)
+	if err != nil {
+		slog.Error("Failed to export subscriptions", "error", err)
+		return fmt.Errorf("error exporting subscriptions: %w", mapError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return fmt.Errorf("error exporting subscriptions: %w", mapError(err))
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error("Failed to export subscriptions", "error", err)
+		return fmt.Errorf("error exporting subscriptions: %w", mapError(err))
+	}
+	return nil
+}
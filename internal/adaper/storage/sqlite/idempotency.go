@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) (*entities.IdempotencyRecord, error) {
+	now := timestamp(time.Now())
+	var key string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (idempotency_key, fingerprint, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (idempotency_key) DO UPDATE
+		SET fingerprint = excluded.fingerprint, status = 0, content_type = '', body = NULL,
+			created_at = excluded.created_at, expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= ?
+		RETURNING idempotency_key`,
+		rec.Key, rec.Fingerprint, now, timestamp(rec.ExpiresAt), now).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to reserve idempotency key", "error", err)
+		return nil, fmt.Errorf("error reserving idempotency key: %w", mapError(err))
+	}
+
+	existing := &entities.IdempotencyRecord{Key: rec.Key}
+	var expiresAt string
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status, content_type, body, expires_at
+		FROM idempotency_keys WHERE idempotency_key = ?`, rec.Key).
+		Scan(&existing.Fingerprint, &existing.Status, &existing.ContentType, &existing.Body, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Ключ удалили между вставкой и чтением: запрос с ним как раз завершился ошибкой
+		return nil, fmt.Errorf("error reserving idempotency key: %w", entities.ErrConflict)
+	}
+	if err != nil {
+		slog.Error("Failed to get idempotency key", "error", err)
+		return nil, fmt.Errorf("error reserving idempotency key: %w", mapError(err))
+	}
+	if existing.ExpiresAt, err = parseTimestamp(expiresAt); err != nil {
+		return nil, fmt.Errorf("error reserving idempotency key: %w", err)
+	}
+	return existing, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status = ?, content_type = ?, body = ?
+		WHERE idempotency_key = ? AND fingerprint = ?`,
+		rec.Status, rec.ContentType, rec.Body, rec.Key, rec.Fingerprint)
+	if err != nil {
+		slog.Error("Failed to complete idempotency key", "error", err)
+		return fmt.Errorf("error completing idempotency key: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = ? AND status = 0`, key)
+	if err != nil {
+		slog.Error("Failed to release idempotency key", "error", err)
+		return fmt.Errorf("error releasing idempotency key: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, timestamp(before))
+	if err != nil {
+		slog.Error("Failed to purge idempotency keys", "error", err)
+		return 0, fmt.Errorf("error purging idempotency keys: %w", mapError(err))
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// ImportSubscriptions сохраняет подписки в одной транзакции.
+// В SQLite вставки внутри транзакции не требуют отдельной массовой загрузки.
+func (s *Storage) ImportSubscriptions(ctx context.Context, subs []entities.Subscriptions) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids := make([]int64, len(subs))
+	for i := range subs {
+		if ids[i], err = createSubscription(ctx, tx, &subs[i]); err != nil {
+			return nil, fmt.Errorf("error importing subscriptions: row %d: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error importing subscriptions: %w", mapError(err))
+	}
+
+	slog.Info("Subscriptions imported", "count", len(ids))
+	return ids, nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS subscriptions_user_id_idx;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,5 @@
+-- SQLite не допускает CURRENT_TIMESTAMP в DEFAULT при ADD COLUMN,
+-- поэтому существующие строки заполняются отдельным UPDATE
+ALTER TABLE subscriptions ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions ADD COLUMN updated_at TEXT NOT NULL DEFAULT '';
+UPDATE subscriptions SET created_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now'), updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now');
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS subscriptions_start_date_id_idx ON subscriptions (start_date, id);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS subscription_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscription_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    before_data TEXT,
+    after_data TEXT,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS subscription_events_subscription_id_idx ON subscription_events (subscription_id, id);
+CREATE INDEX IF NOT EXISTS subscription_events_created_at_idx ON subscription_events (created_at);
+
+CREATE TRIGGER IF NOT EXISTS subscription_events_no_update
+BEFORE UPDATE ON subscription_events
+BEGIN
+    SELECT RAISE(ABORT, 'subscription_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS subscription_events_no_delete
+BEFORE DELETE ON subscription_events
+BEGIN
+    SELECT RAISE(ABORT, 'subscription_events is append-only');
+END;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    effective_from TEXT NOT NULL,
+    price INTEGER NOT NULL,
+    PRIMARY KEY (subscription_id, effective_from)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP COLUMN billing_anchor;
+ALTER TABLE subscriptions DROP COLUMN billing_period;
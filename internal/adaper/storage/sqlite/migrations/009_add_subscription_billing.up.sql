@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions ADD COLUMN billing_period TEXT NOT NULL DEFAULT 'month'
+    CHECK (billing_period IN ('month', 'quarter', 'year', 'week'));
+ALTER TABLE subscriptions ADD COLUMN billing_anchor TEXT;
@@ -0,0 +1,19 @@
+-- в старой схеме цены хранятся в рублях без копеек: откат не должен терять валюты и дробные суммы
+CREATE TEMP TABLE currency_revert_guard (blocked INTEGER NOT NULL);
+CREATE TEMP TRIGGER currency_revert_guard BEFORE INSERT ON currency_revert_guard WHEN NEW.blocked
+BEGIN
+    SELECT RAISE(ABORT, 'cannot revert currencies: subscriptions with non-RUB currency or fractional amounts exist');
+END;
+INSERT INTO currency_revert_guard
+SELECT EXISTS (SELECT 1 FROM subscriptions WHERE currency <> 'RUB' OR amount_minor % 100 <> 0)
+    OR EXISTS (SELECT 1 FROM subscription_prices WHERE amount_minor % 100 <> 0);
+DROP TABLE currency_revert_guard;
+
+DROP TABLE IF EXISTS exchange_rates;
+
+UPDATE subscription_prices SET amount_minor = amount_minor / 100;
+UPDATE subscriptions SET amount_minor = amount_minor / 100;
+ALTER TABLE subscription_prices RENAME COLUMN amount_minor TO price;
+ALTER TABLE subscriptions RENAME COLUMN amount_minor TO price;
+
+ALTER TABLE subscriptions DROP COLUMN currency;
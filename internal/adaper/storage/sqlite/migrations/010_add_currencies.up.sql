@@ -0,0 +1,17 @@
+ALTER TABLE subscriptions ADD COLUMN currency TEXT NOT NULL DEFAULT 'RUB'
+    CHECK (currency IN ('RUB', 'USD', 'EUR', 'KZT'));
+
+-- цены хранятся в минимальных единицах валюты: рубли переводятся в копейки
+ALTER TABLE subscriptions RENAME COLUMN price TO amount_minor;
+ALTER TABLE subscription_prices RENAME COLUMN price TO amount_minor;
+UPDATE subscriptions SET amount_minor = amount_minor * 100;
+UPDATE subscription_prices SET amount_minor = amount_minor * 100;
+
+-- журнал не меняется: снимки без amount_minor приводятся к минимальным единицам при чтении
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency TEXT NOT NULL,
+    month TEXT NOT NULL,
+    rate TEXT NOT NULL,
+    PRIMARY KEY (currency, month)
+);
@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// pricesChunkSize сколько ID подписок передается в одном запросе истории цен,
+// чтобы не превысить ограничение SQLite на число параметров
+const pricesChunkSize = 500
+
+func (s *Storage) SetPriceChange(ctx context.Context, id int64, change *entities.PriceChange, version int64,
+	check func(*entities.Subscriptions) error) (*entities.Subscriptions, error) {
+	return inTx(ctx, s.db, func(tx *sql.Tx) (*entities.Subscriptions, error) {
+		before, err := lockSubscription(ctx, tx, id, version, false)
+		if err != nil {
+			slog.Warn("No subscription price changed", "id", id, "version", version, "error", err)
+			return nil, err
+		}
+
+		changed := *before
+		if err := changed.SetPrice(*change); err != nil {
+			return nil, fmt.Errorf("error changing price of subscription with ID %d: %w", id, err)
+		}
+		if err := check(&changed); err != nil {
+			return nil, err
+		}
+
+		effectiveFrom, err := toDate(change.EffectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("error changing price of subscription with ID %d: effective_from: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO subscription_prices (subscription_id, effective_from, amount_minor)
+			VALUES (?, ?, ?)
+			ON CONFLICT (subscription_id, effective_from) DO UPDATE SET amount_minor = excluded.amount_minor`,
+			id, effectiveFrom, change.AmountMinor); err != nil {
+			slog.Error("Failed to change subscription price", "error", err, "id", id)
+			return nil, fmt.Errorf("error changing price of subscription with ID %d: %w", id, mapError(err))
+		}
+
+		return savePrices(ctx, tx, before, changed.Prices)
+	})
+}
+
+func (s *Storage) DeletePriceChange(ctx context.Context, id int64, effectiveFrom string, version int64) (*entities.Subscriptions, error) {
+	return inTx(ctx, s.db, func(tx *sql.Tx) (*entities.Subscriptions, error) {
+		before, err := lockSubscription(ctx, tx, id, version, false)
+		if err != nil {
+			slog.Warn("No subscription price change deleted", "id", id, "version", version, "error", err)
+			return nil, err
+		}
+
+		changed := *before
+		if err := changed.RemovePrice(effectiveFrom); err != nil {
+			return nil, fmt.Errorf("subscription with ID %d: %w", id, err)
+		}
+
+		date, err := toDate(effectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("error deleting price change of subscription with ID %d: effective_from: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM subscription_prices WHERE subscription_id = ? AND effective_from = ?`, id, date); err != nil {
+			slog.Error("Failed to delete subscription price change", "error", err, "id", id)
+			return nil, fmt.Errorf("error deleting price change of subscription with ID %d: %w", id, mapError(err))
+		}
+
+		return savePrices(ctx, tx, before, changed.Prices)
+	})
+}
+
+// savePrices увеличивает версию подписки после изменения истории цен и записывает изменение в журнал
+func savePrices(ctx context.Context, q querier, before *entities.Subscriptions, prices []entities.PriceChange) (*entities.Subscriptions, error) {
+	after, err := scanSubscription(q.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING `+subscriptionColumns, timestamp(time.Now()), before.ID))
+	if err != nil {
+		slog.Error("Failed to update subscription prices", "error", err, "id", before.ID)
+		return nil, fmt.Errorf("error updating subscription with ID %d: %w", before.ID, mapError(err))
+	}
+	after.Prices = prices
+
+	if err := recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventUpdate, before, after)); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// withPrices дополняет подписку историей цен
+func withPrices(ctx context.Context, q querier, sub *entities.Subscriptions) (*entities.Subscriptions, error) {
+	subs := []entities.Subscriptions{*sub}
+	if err := attachPrices(ctx, q, subs); err != nil {
+		return nil, err
+	}
+	return &subs[0], nil
+}
+
+// attachPrices заполняет историю цен подписок
+func attachPrices(ctx context.Context, q querier, subs []entities.Subscriptions) error {
+	index := make(map[int64]int, len(subs))
+	for i := range subs {
+		index[subs[i].ID] = i
+	}
+
+	for start := 0; start < len(subs); start += pricesChunkSize {
+		end := min(start+pricesChunkSize, len(subs))
+		params := make([]interface{}, 0, end-start)
+		for _, sub := range subs[start:end] {
+			params = append(params, sub.ID)
+		}
+
+		rows, err := q.QueryContext(ctx, `
+			SELECT subscription_id, effective_from, amount_minor
+			FROM subscription_prices
+			WHERE subscription_id IN (`+placeholders(len(params))+`)
+			ORDER BY subscription_id, effective_from`, params...)
+		if err != nil {
+			return err
+		}
+		if err := scanPrices(rows, subs, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanPrices(rows *sql.Rows, subs []entities.Subscriptions, index map[int64]int) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var effectiveFrom string
+		var change entities.PriceChange
+		if err := rows.Scan(&id, &effectiveFrom, &change.AmountMinor); err != nil {
+			return err
+		}
+		month, err := fromDate(effectiveFrom)
+		if err != nil {
+			return err
+		}
+		change.EffectiveFrom = month
+		change.Price = entities.MajorUnits(change.AmountMinor)
+		sub := &subs[index[id]]
+		sub.Prices = append(sub.Prices, change)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+func (s *Storage) ListExchangeRates(ctx context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error) {
+	rates, err := exchangeRates(ctx, s.db, filter.Currencies)
+	if err != nil {
+		slog.Error("Failed to list exchange rates", "error", err)
+		return nil, fmt.Errorf("error listing exchange rates: %w", mapError(err))
+	}
+	return rates, nil
+}
+
+func (s *Storage) SetExchangeRates(ctx context.Context, rates []entities.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error saving exchange rates: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range rates {
+		month, err := toDate(r.Month)
+		if err != nil {
+			return fmt.Errorf("error saving exchange rate %s: month: %w", r.Currency, err)
+		}
+		rate, err := entities.NormalizeRate(r.Rate)
+		if err != nil {
+			return fmt.Errorf("error saving exchange rate %s for %s: %w", r.Currency, r.Month, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO exchange_rates (currency, month, rate)
+			VALUES (?, ?, ?)
+			ON CONFLICT (currency, month) DO UPDATE SET rate = excluded.rate`,
+			r.Currency, month, rate); err != nil {
+			slog.Error("Failed to save exchange rate", "error", err, "currency", r.Currency, "month", r.Month)
+			return fmt.Errorf("error saving exchange rate %s for %s: %w", r.Currency, r.Month, mapError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error saving exchange rates: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, currency, month string) error {
+	date, err := toDate(month)
+	if err != nil {
+		return fmt.Errorf("error deleting exchange rate %s: month: %w", currency, err)
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM exchange_rates WHERE currency = ? AND month = ?`, currency, date)
+	if err != nil {
+		slog.Error("Failed to delete exchange rate", "error", err, "currency", currency, "month", month)
+		return fmt.Errorf("error deleting exchange rate %s for %s: %w", currency, month, mapError(err))
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("error deleting exchange rate %s for %s: %w", currency, month, entities.ErrNotFound)
+	}
+	return nil
+}
+
+// exchangeRates выбирает курсы валют currencies (всех валют, если список пуст) по валюте и месяцу
+func exchangeRates(ctx context.Context, q querier, currencies []string) ([]entities.ExchangeRate, error) {
+	query := `SELECT currency, month, rate FROM exchange_rates`
+	params := make([]interface{}, 0, len(currencies))
+	if len(currencies) > 0 {
+		for _, c := range currencies {
+			params = append(params, c)
+		}
+		query += ` WHERE currency IN (` + placeholders(len(params)) + `)`
+	}
+	query += ` ORDER BY currency, month`
+
+	rows, err := q.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []entities.ExchangeRate{}
+	for rows.Next() {
+		var r entities.ExchangeRate
+		var month string
+		if err := rows.Scan(&r.Currency, &month, &r.Rate); err != nil {
+			return nil, err
+		}
+		if r.Month, err = fromDate(month); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,903 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"modernc.org/sqlite"
+	"strings"
+	"time"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/migrate"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service/cost"
+)
+
+// driverName имя драйвера database/sql, регистрируемого modernc.org/sqlite (чистый Go, без CGO)
+const driverName = "sqlite"
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// lowerFunc SQL-функция перевода строки в нижний регистр с поддержкой Unicode.
+// Встроенная lower() в SQLite без ICU меняет регистр только у ASCII-символов.
+const lowerFunc = "unicode_lower"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction(lowerFunc, 1,
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case []byte:
+				return strings.ToLower(string(v)), nil
+			default:
+				return v, nil
+			}
+		})
+}
+
+// subscriptionColumns колонки подписки в порядке, ожидаемом scanSubscription
+const subscriptionColumns = `id, service_name, amount_minor, currency, user_id, start_date, end_date, billing_period, billing_anchor,
+	version, created_at, updated_at, deleted_at`
+
+// querier общие методы *sql.DB и *sql.Tx, чтобы одни и те же запросы
+// выполнялись как отдельно, так и внутри пакета
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type Storage struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+func NewStorage(db *sql.DB, cfg *config.Config) *Storage {
+	return &Storage{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// New открывает файл БД SQLite и применяет к нему встроенные миграции.
+// Хранилище рассчитано на один процесс, поэтому миграции выполняются при каждом запуске.
+func New(ctx context.Context, cfg *config.Config) (*Storage, error) {
+	const op = "storage.sqlite.New"
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		cfg.Storage.SQLitePath)
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: open failed: %w", op, err)
+	}
+	// SQLite допускает одного писателя, единственное соединение исключает SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Storage.Timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		slog.Error("sqlite ping failed", "error", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: ping failed: %w", op, err)
+	}
+
+	if err := applyMigrations(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	storageBD := NewStorage(db, cfg)
+
+	slog.Info("SQLite storage initialized successfully", "path", cfg.Storage.SQLitePath)
+	return storageBD, nil
+}
+
+// Close закрывает файл БД
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// inTx выполняет fn в транзакции, чтобы изменение подписки и его событие в журнале сохранялись вместе
+func inTx[T any](ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) (T, error)) (T, error) {
+	var zero T
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return zero, fmt.Errorf("error starting transaction: %w", mapError(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := fn(tx)
+	if err != nil {
+		return zero, err
+	}
+	if err := tx.Commit(); err != nil {
+		return zero, fmt.Errorf("error committing transaction: %w", mapError(err))
+	}
+	return result, nil
+}
+
+func (s *Storage) CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (int64, error) {
+	return inTx(ctx, s.db, func(tx *sql.Tx) (int64, error) {
+		return createSubscription(ctx, tx, sub)
+	})
+}
+
+func createSubscription(ctx context.Context, q querier, sub *entities.Subscriptions) (int64, error) {
+	startDate, endDate, err := subscriptionDates(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error creating subscription: %w", err)
+	}
+	billingPeriod, billingAnchor, err := subscriptionBilling(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error creating subscription: %w", err)
+	}
+
+	now := timestamp(time.Now())
+	created, err := scanSubscription(q.QueryRowContext(ctx, `
+		INSERT INTO subscriptions (service_name, amount_minor, currency, user_id, start_date, end_date, billing_period, billing_anchor,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+subscriptionColumns,
+		sub.ServiceName, sub.AmountMinor, sub.CurrencyCode(), sub.UserID, startDate, endDate, billingPeriod, billingAnchor, now, now))
+	if err != nil {
+		slog.Error("Failed to create subscription", "error", err)
+		return 0, fmt.Errorf("error creating subscription: %w", mapError(err))
+	}
+
+	if err := recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventCreate, nil, created)); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+func (s *Storage) GetSubscription(ctx context.Context, id int64) (*entities.Subscriptions, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = ? AND deleted_at IS NULL`, id)
+	sub, err := scanSubscription(row)
+	if err == nil {
+		sub, err = withPrices(ctx, s.db, sub)
+	}
+	if err != nil {
+		slog.Error("Failed to get subscription", "error", err, "id", id)
+		return nil, fmt.Errorf("error getting subscription with ID %d: %w", id, mapError(err))
+	}
+	return sub, nil
+}
+
+func (s *Storage) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	return inTx(ctx, s.db, func(tx *sql.Tx) (int64, error) {
+		return updateSubscription(ctx, tx, id, sub, version)
+	})
+}
+
+func updateSubscription(ctx context.Context, q querier, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	startDate, endDate, err := subscriptionDates(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+	}
+	billingPeriod, billingAnchor, err := subscriptionBilling(sub)
+	if err != nil {
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, err)
+	}
+
+	before, err := lockSubscription(ctx, q, id, version, false)
+	if err != nil {
+		slog.Warn("No subscription updated", "id", id, "version", version, "error", err)
+		return 0, err
+	}
+
+	after, err := scanSubscription(q.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET service_name = ?, amount_minor = ?, currency = ?, user_id = ?, start_date = ?, end_date = ?,
+			billing_period = ?, billing_anchor = ?, updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING `+subscriptionColumns,
+		sub.ServiceName, sub.AmountMinor, sub.CurrencyCode(), sub.UserID, startDate, endDate, billingPeriod, billingAnchor, timestamp(time.Now()), id))
+	if err != nil {
+		slog.Error("Failed to update subscription", "error", err, "id", id)
+		return 0, fmt.Errorf("error updating subscription with ID %d: %w", id, mapError(err))
+	}
+	after.Prices = before.Prices
+
+	if err := recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventUpdate, before, after)); err != nil {
+		return 0, err
+	}
+	return after.Version, nil
+}
+
+func (s *Storage) PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64,
+	check func(*entities.Subscriptions) error) (*entities.Subscriptions, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	current, err := lockSubscription(ctx, tx, id, version, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Empty() {
+		return current, nil
+	}
+
+	patched := *current
+	patch.Apply(&patched)
+	if err := check(&patched); err != nil {
+		return nil, err
+	}
+
+	sets, params, err := patchAssignments(patch)
+	if err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, err)
+	}
+	sets = append(sets, "updated_at = ?", "version = version + 1")
+	params = append(params, timestamp(time.Now()), id)
+
+	updated, err := scanSubscription(tx.QueryRowContext(ctx,
+		`UPDATE subscriptions SET `+strings.Join(sets, ", ")+` WHERE id = ? RETURNING `+subscriptionColumns, params...))
+	if err != nil {
+		slog.Error("Failed to patch subscription", "error", err, "id", id)
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+	updated.Prices = current.Prices
+
+	if err := recordEvent(ctx, tx, entities.NewEvent(ctx, entities.EventUpdate, current, updated)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error patching subscription with ID %d: %w", id, mapError(err))
+	}
+
+	return updated, nil
+}
+
+// patchAssignments возвращает присваивания SET только для переданных в патче полей
+func patchAssignments(patch *entities.SubscriptionPatch) ([]string, []interface{}, error) {
+	var sets []string
+	var params []interface{}
+
+	if patch.ServiceName != nil {
+		sets = append(sets, "service_name = ?")
+		params = append(params, *patch.ServiceName)
+	}
+	if patch.AmountMinor != nil {
+		sets = append(sets, "amount_minor = ?")
+		params = append(params, *patch.AmountMinor)
+	} else if patch.Price != nil {
+		sets = append(sets, "amount_minor = ?")
+		params = append(params, *patch.Price*entities.MinorUnits)
+	}
+	if patch.Currency != nil {
+		sets = append(sets, "currency = ?")
+		params = append(params, *patch.Currency)
+	}
+	if patch.UserID != nil {
+		sets = append(sets, "user_id = ?")
+		params = append(params, *patch.UserID)
+	}
+	if patch.StartDate != nil {
+		startDate, err := toDate(*patch.StartDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("start_date: %w", err)
+		}
+		sets = append(sets, "start_date = ?")
+		params = append(params, startDate)
+	}
+	if patch.SetEndDate {
+		var endDate *string
+		if patch.EndDate != nil {
+			date, err := toDate(*patch.EndDate)
+			if err != nil {
+				return nil, nil, fmt.Errorf("end_date: %w", err)
+			}
+			endDate = &date
+		}
+		sets = append(sets, "end_date = ?")
+		params = append(params, endDate)
+	}
+	if patch.BillingPeriod != nil {
+		sets = append(sets, "billing_period = ?")
+		params = append(params, *patch.BillingPeriod)
+	}
+	if patch.SetBillingAnchor {
+		var billingAnchor *string
+		if patch.BillingAnchor != nil {
+			date, err := toDate(*patch.BillingAnchor)
+			if err != nil {
+				return nil, nil, fmt.Errorf("billing_anchor: %w", err)
+			}
+			billingAnchor = &date
+		}
+		sets = append(sets, "billing_anchor = ?")
+		params = append(params, billingAnchor)
+	}
+
+	return sets, params, nil
+}
+
+func (s *Storage) DeleteSubscription(ctx context.Context, id int64, version int64) error {
+	_, err := inTx(ctx, s.db, func(tx *sql.Tx) (struct{}, error) {
+		return struct{}{}, deleteSubscription(ctx, tx, id, version)
+	})
+	return err
+}
+
+func deleteSubscription(ctx context.Context, q querier, id int64, version int64) error {
+	before, err := lockSubscription(ctx, q, id, version, false)
+	if err != nil {
+		slog.Warn("No subscription deleted", "id", id, "version", version, "error", err)
+		return err
+	}
+
+	now := timestamp(time.Now())
+	after, err := scanSubscription(q.QueryRowContext(ctx, `
+		UPDATE subscriptions
+		SET deleted_at = ?, updated_at = ?, version = version + 1
+		WHERE id = ?
+		RETURNING `+subscriptionColumns, now, now, id))
+	if err != nil {
+		slog.Error("Failed to delete subscription", "error", err, "id", id)
+		return fmt.Errorf("error deleting subscription with ID %d: %w", id, mapError(err))
+	}
+	after.Prices = before.Prices
+
+	return recordEvent(ctx, q, entities.NewEvent(ctx, entities.EventDelete, before, after))
+}
+
+// lockSubscription читает подписку в транзакции изменения и проверяет версию; SQLite допускает одного писателя, блокировка строки не нужна
+func lockSubscription(ctx context.Context, q querier, id int64, version int64, deleted bool) (*entities.Subscriptions, error) {
+	condition := "deleted_at IS NULL"
+	if deleted {
+		condition = "deleted_at IS NOT NULL"
+	}
+
+	sub, err := scanSubscription(q.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = ? AND `+condition, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("subscription with ID %d: %w", id, entities.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading subscription with ID %d: %w", id, mapError(err))
+	}
+
+	if version != entities.AnyVersion && sub.Version != version {
+		return nil, fmt.Errorf("subscription with ID %d has version %d: %w", id, sub.Version, entities.ErrVersionMismatch)
+	}
+
+	sub, err = withPrices(ctx, q, sub)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prices of subscription with ID %d: %w", id, mapError(err))
+	}
+	return sub, nil
+}
+
+// ListSubscriptions возвращает страницу подписок по фильтру в порядке filter.Order().
+// Размер страницы ограничен entities.MaxPageSize.
+func (s *Storage) ListSubscriptions(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error) {
+	conditions, params, err := listConditions(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+
+	order := filter.Order()
+	orderClause, err := orderBy(order)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE 1=1` + conditions
+	pageParams := append([]interface{}{}, params...)
+
+	if filter.After != nil {
+		keyset, keysetParams, err := keysetCondition(order, filter.After)
+		if err != nil {
+			return nil, fmt.Errorf("error listing subscriptions: %w", err)
+		}
+		query += " AND " + keyset
+		pageParams = append(pageParams, keysetParams...)
+	}
+
+	limit := filter.PageSize()
+	query += " ORDER BY " + orderClause + " LIMIT ?"
+	pageParams = append(pageParams, limit+1)
+
+	subs, err := s.querySubscriptions(ctx, query, pageParams...)
+	if err != nil {
+		slog.Error("Failed to list subscriptions", "error", err)
+		return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+	}
+
+	page := entities.NewPage(subs, limit, order)
+	if err := attachPrices(ctx, s.db, page.Items); err != nil {
+		slog.Error("Failed to list subscription prices", "error", err)
+		return nil, fmt.Errorf("error listing subscriptions: %w", mapError(err))
+	}
+
+	if filter.WithTotal {
+		var total int64
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions WHERE 1=1`+conditions, params...).Scan(&total); err != nil {
+			slog.Error("Failed to count subscriptions", "error", err)
+			return nil, fmt.Errorf("error counting subscriptions: %w", mapError(err))
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// listConditions собирает условия WHERE фильтра списка (без пагинации) и их параметры
+func listConditions(filter *entities.ListFilter) (string, []interface{}, error) {
+	query := " AND deleted_at IS NULL"
+	if filter.Deleted {
+		query = " AND deleted_at IS NOT NULL"
+	}
+	params := []interface{}{}
+
+	if len(filter.UserIDs) > 0 {
+		query += " AND user_id IN (" + placeholders(len(filter.UserIDs)) + ")"
+		for _, id := range filter.UserIDs {
+			params = append(params, id)
+		}
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		query += " AND service_name IN (" + placeholders(len(filter.ServiceNames)) + ")"
+		for _, name := range filter.ServiceNames {
+			params = append(params, name)
+		}
+	}
+
+	if filter.Search != nil {
+		query += " AND instr(" + lowerFunc + "(service_name), " + lowerFunc + "(?)) > 0"
+		params = append(params, *filter.Search)
+	}
+
+	if filter.StartDate != nil {
+		startDate, err := toDate(*filter.StartDate)
+		if err != nil {
+			return "", nil, fmt.Errorf("start_date: %w", err)
+		}
+		query += " AND start_date >= ?"
+		params = append(params, startDate)
+	}
+
+	if filter.EndDate != nil {
+		endDate, err := toDate(*filter.EndDate)
+		if err != nil {
+			return "", nil, fmt.Errorf("end_date: %w", err)
+		}
+		query += " AND end_date <= ?"
+		params = append(params, endDate)
+	}
+
+	if filter.ActiveOn != nil {
+		activeOn, err := toDate(*filter.ActiveOn)
+		if err != nil {
+			return "", nil, fmt.Errorf("active_on: %w", err)
+		}
+		query += " AND start_date <= ? AND (end_date IS NULL OR end_date >= ?)"
+		params = append(params, activeOn, activeOn)
+	}
+
+	if len(filter.Currencies) > 0 {
+		query += " AND currency IN (" + placeholders(len(filter.Currencies)) + ")"
+		for _, currency := range filter.Currencies {
+			params = append(params, currency)
+		}
+	}
+
+	minAmount, maxAmount := filter.AmountRange()
+	if minAmount != nil {
+		query += " AND amount_minor >= ?"
+		params = append(params, *minAmount)
+	}
+
+	if maxAmount != nil {
+		query += " AND amount_minor <= ?"
+		params = append(params, *maxAmount)
+	}
+
+	if filter.HasEndDate != nil {
+		if *filter.HasEndDate {
+			query += " AND end_date IS NOT NULL"
+		} else {
+			query += " AND end_date IS NULL"
+		}
+	}
+
+	return query, params, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// sortColumns колонки ORDER BY для полей сортировки из entities. В SQL попадают только они,
+// а не значения параметров запроса.
+var sortColumns = map[string]string{
+	entities.SortID:          "id",
+	entities.SortServiceName: "service_name",
+	entities.SortPrice:       "amount_minor",
+	entities.SortStartDate:   "start_date",
+	entities.SortCreatedAt:   "created_at",
+	entities.SortUpdatedAt:   "updated_at",
+}
+
+func orderBy(order []entities.SortField) (string, error) {
+	parts := make([]string, 0, len(order))
+	for _, f := range order {
+		column, ok := sortColumns[f.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported sort field %q", entities.ErrValidation, f.Field)
+		}
+		if f.Desc {
+			column += " DESC"
+		}
+		parts = append(parts, column)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// keysetCondition строит условие "запись после курсора" для порядка order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., где > заменяется на < для ключей по убыванию
+func keysetCondition(order []entities.SortField, cursor *entities.ListCursor) (string, []interface{}, error) {
+	key, err := cursor.Key(order)
+	if err != nil {
+		return "", nil, err
+	}
+
+	values := make([]interface{}, 0, len(order))
+	for _, f := range order {
+		value, err := sortValue(key, f.Field)
+		if err != nil {
+			return "", nil, fmt.Errorf("cursor: %w", err)
+		}
+		values = append(values, value)
+	}
+
+	var params []interface{}
+	terms := make([]string, 0, len(order))
+	for i, f := range order {
+		term := make([]string, 0, i+1)
+		for k := 0; k < i; k++ {
+			term = append(term, sortColumns[order[k].Field]+" = ?")
+			params = append(params, values[k])
+		}
+
+		op := " > ?"
+		if f.Desc {
+			op = " < ?"
+		}
+		term = append(term, sortColumns[f.Field]+op)
+		params = append(params, values[i])
+
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+	}
+
+	return "(" + strings.Join(terms, " OR ") + ")", params, nil
+}
+
+// sortValue возвращает значение ключа курсора в виде параметра запроса
+func sortValue(key *entities.Subscriptions, field string) (interface{}, error) {
+	switch field {
+	case entities.SortID:
+		return key.ID, nil
+	case entities.SortServiceName:
+		return key.ServiceName, nil
+	case entities.SortPrice:
+		return key.AmountMinor, nil
+	case entities.SortStartDate:
+		return toDate(key.StartDate)
+	case entities.SortCreatedAt:
+		return timestamp(key.CreatedAt), nil
+	case entities.SortUpdatedAt:
+		return timestamp(key.UpdatedAt), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported sort field %q", entities.ErrValidation, field)
+	}
+}
+
+func (s *Storage) CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating total cost: %w", err)
+	}
+
+	subs, err := s.costSubscriptions(ctx, filter, &q)
+	if err != nil {
+		slog.Error("Failed to calculate total cost", "error", err, "filter", filter)
+		return nil, fmt.Errorf("error calculating total cost: %w", mapError(err))
+	}
+
+	return cost.Total(subs, q)
+}
+
+func (s *Storage) CalculateCostBreakdown(ctx context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", err)
+	}
+
+	subs, err := s.costSubscriptions(ctx, filter, &q)
+	if err != nil {
+		slog.Error("Failed to calculate cost breakdown", "error", err, "filter", filter)
+		return nil, fmt.Errorf("error calculating cost breakdown: %w", mapError(err))
+	}
+
+	return cost.Breakdown(subs, q)
+}
+
+func (s *Storage) CalculateGroupedCost(ctx context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error) {
+	q, err := cost.ParseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating grouped cost: %w", err)
+	}
+
+	subs, err := s.costSubscriptions(ctx, filter, &q)
+	if err != nil {
+		slog.Error("Failed to calculate grouped cost", "error", err, "filter", filter, "group_by", groupBy)
+		return nil, fmt.Errorf("error calculating grouped cost: %w", mapError(err))
+	}
+
+	return cost.Grouped(subs, q, groupBy)
+}
+
+// costSubscriptions выбирает подписки, пересекающиеся с периодом фильтра стоимости,
+// и заполняет q.Rates курсами валют, нужными для пересчета их цен
+func (s *Storage) costSubscriptions(ctx context.Context, filter *entities.CostFilter, q *cost.Query) ([]entities.Subscriptions, error) {
+	startPeriod, err := toDate(filter.StartPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("start_period: %w", err)
+	}
+	endPeriod, err := toDate(filter.EndPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("end_period: %w", err)
+	}
+
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE deleted_at IS NULL
+		AND (end_date IS NULL OR end_date >= ?)
+		AND start_date <= ?
+	`
+	params := []interface{}{startPeriod, endPeriod}
+
+	if filter.UserID != nil {
+		query += " AND user_id = ?"
+		params = append(params, *filter.UserID)
+	}
+
+	if filter.ServiceName != nil {
+		query += " AND service_name = ?"
+		params = append(params, *filter.ServiceName)
+	}
+
+	query += " ORDER BY id"
+
+	subs, err := s.querySubscriptions(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachPrices(ctx, s.db, subs); err != nil {
+		return nil, err
+	}
+	if currencies := q.Currencies(subs); len(currencies) > 0 {
+		rates, err := exchangeRates(ctx, s.db, currencies)
+		if err != nil {
+			return nil, err
+		}
+		if q.Rates, err = cost.NewRates(rates); err != nil {
+			return nil, err
+		}
+	}
+	return subs, nil
+}
+
+func (s *Storage) querySubscriptions(ctx context.Context, query string, params ...interface{}) ([]entities.Subscriptions, error) {
+	rows, err := s.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []entities.Subscriptions
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSubscription читает подписку из строки с колонками subscriptionColumns
+func scanSubscription(row scanner) (*entities.Subscriptions, error) {
+	var sub entities.Subscriptions
+	var startDate, createdAt, updatedAt string
+	var endDate, billingAnchor, deletedAt sql.NullString
+
+	if err := row.Scan(&sub.ID, &sub.ServiceName, &sub.AmountMinor, &sub.Currency, &sub.UserID, &startDate, &endDate,
+		&sub.BillingPeriod, &billingAnchor, &sub.Version, &createdAt, &updatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if sub.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+		return nil, err
+	}
+	if sub.UpdatedAt, err = parseTimestamp(updatedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		deleted, err := parseTimestamp(deletedAt.String)
+		if err != nil {
+			return nil, err
+		}
+		sub.DeletedAt = &deleted
+	}
+
+	start, err := fromDate(startDate)
+	if err != nil {
+		return nil, err
+	}
+	sub.StartDate = start
+
+	if endDate.Valid {
+		end, err := fromDate(endDate.String)
+		if err != nil {
+			return nil, err
+		}
+		sub.EndDate = &end
+	}
+
+	if billingAnchor.Valid {
+		anchor, err := fromDate(billingAnchor.String)
+		if err != nil {
+			return nil, err
+		}
+		sub.BillingAnchor = &anchor
+	}
+	sub.FillAmounts()
+
+	return &sub, nil
+}
+
+// В SQLite периоды подписки хранятся текстом YYYY-MM-DD (первое число месяца),
+// чтобы строковое сравнение совпадало с хронологическим.
+const dateLayout = "2006-01-02"
+
+func toDate(month string) (string, error) {
+	m, err := entities.ParseMonth(month)
+	if err != nil {
+		return "", err
+	}
+	return m.Time().Format(dateLayout), nil
+}
+
+func fromDate(date string) (string, error) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return "", fmt.Errorf("invalid stored date %q: %w", date, err)
+	}
+	return entities.MonthOf(t).String(), nil
+}
+
+// timestampLayout формат отметок времени created_at, updated_at и deleted_at (UTC, сортируется как строка)
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid stored timestamp %q: %w", value, err)
+	}
+	return t.UTC(), nil
+}
+
+// subscriptionDates переводит даты подписки в значения для колонок start_date и end_date
+func subscriptionDates(sub *entities.Subscriptions) (start string, end *string, err error) {
+	start, err = toDate(sub.StartDate)
+	if err != nil {
+		return "", nil, fmt.Errorf("start_date: %w", err)
+	}
+	if sub.EndDate != nil {
+		endDate, err := toDate(*sub.EndDate)
+		if err != nil {
+			return "", nil, fmt.Errorf("end_date: %w", err)
+		}
+		end = &endDate
+	}
+	return start, end, nil
+}
+
+// subscriptionBilling переводит период оплаты подписки в значения для колонок billing_period и billing_anchor
+func subscriptionBilling(sub *entities.Subscriptions) (period string, anchor *string, err error) {
+	if sub.BillingAnchor != nil {
+		date, err := toDate(*sub.BillingAnchor)
+		if err != nil {
+			return "", nil, fmt.Errorf("billing_anchor: %w", err)
+		}
+		anchor = &date
+	}
+	return sub.Billing(), anchor, nil
+}
+
+// applyMigrations применяет встроенные миграции, еще не отмеченные в schema_migrations
+func applyMigrations(ctx context.Context, db *sql.DB) error {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	list, err := migrate.Load(sub)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	for _, mig := range list {
+		var applied bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, mig.Version).Scan(&applied); err != nil {
+			return fmt.Errorf("read schema_migrations: %w", err)
+		}
+		if applied {
+			continue
+		}
+
+		slog.Info("Applying migration", "version", mig.Version, "name", mig.Name)
+		if err := runMigration(ctx, db, mig); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func runMigration(ctx context.Context, db *sql.DB, mig migrate.Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, mig.Version, mig.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
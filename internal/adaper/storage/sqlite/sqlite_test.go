@@ -0,0 +1,95 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/sqlite"
+	"tz_effective/internal/service"
+	"tz_effective/internal/service/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		return open(t, filepath.Join(t.TempDir(), "subscriptions.db"))
+	})
+}
+
+// TestCurrencyMigration откатывает миграцию валют файлом из migrations и применяет ее снова при открытии хранилища
+func TestCurrencyMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.db")
+	ctx := context.Background()
+	withDB := func(t *testing.T, fn func(db *sql.DB) error) error {
+		t.Helper()
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer db.Close()
+		return fn(db)
+	}
+
+	storagetest.RunCurrencyMigration(t, storagetest.CurrencyMigration{
+		Storage: open(t, path),
+		Down: func(t *testing.T) error {
+			down, err := os.ReadFile("migrations/010_add_currencies.down.sql")
+			if err != nil {
+				t.Fatalf("read down migration: %v", err)
+			}
+			return withDB(t, func(db *sql.DB) error {
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				defer func() { _ = tx.Rollback() }()
+
+				if _, err := tx.ExecContext(ctx, string(down)); err != nil {
+					return err
+				}
+				if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = 10`); err != nil {
+					return err
+				}
+				return tx.Commit()
+			})
+		},
+		Up: func(t *testing.T) service.Storage {
+			return open(t, path)
+		},
+		Exec: func(t *testing.T, query string) {
+			if err := withDB(t, func(db *sql.DB) error {
+				_, err := db.ExecContext(ctx, query)
+				return err
+			}); err != nil {
+				t.Fatalf("exec: %v", err)
+			}
+		},
+		QueryInt: func(t *testing.T, query string) int64 {
+			var value int64
+			if err := withDB(t, func(db *sql.DB) error {
+				return db.QueryRowContext(ctx, query).Scan(&value)
+			}); err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			return value
+		},
+	})
+}
+
+func open(t *testing.T, path string) *sqlite.Storage {
+	t.Helper()
+
+	cfg := &config.Config{Storage: config.Storage{
+		Timeout:    5 * time.Second,
+		SQLitePath: path,
+	}}
+	s, err := sqlite.New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
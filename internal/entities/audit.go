@@ -0,0 +1,147 @@
+package entities
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в журнал изменений подписок
+const (
+	EventCreate  = "create"
+	EventUpdate  = "update"
+	EventDelete  = "delete"
+	EventRestore = "restore"
+)
+
+// eventActions допустимые действия журнала
+var eventActions = map[string]bool{
+	EventCreate:  true,
+	EventUpdate:  true,
+	EventDelete:  true,
+	EventRestore: true,
+}
+
+// ValidEventAction сообщает, является ли action действием журнала
+func ValidEventAction(action string) bool {
+	return eventActions[action]
+}
+
+// SubscriptionEvent запись журнала изменений подписки со снимками до и после изменения.
+// У создания нет Before, у удаления в After задан DeletedAt. Записи журнала не изменяются.
+type SubscriptionEvent struct {
+	ID             int64          `json:"id"`
+	SubscriptionID int64          `json:"subscription_id"`
+	Action         string         `json:"action" enums:"create,update,delete,restore"`
+	Actor          string         `json:"actor,omitempty"`      // Кто выполнил изменение
+	RequestID      string         `json:"request_id,omitempty"` // ID запроса, в котором выполнено изменение
+	Before         *Subscriptions `json:"before,omitempty"`
+	After          *Subscriptions `json:"after,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// NewEvent собирает событие журнала по снимкам подписки; автора и ID запроса берет из ctx (см. WithAuditInfo)
+func NewEvent(ctx context.Context, action string, before, after *Subscriptions) *SubscriptionEvent {
+	info := AuditInfoFromContext(ctx)
+	event := &SubscriptionEvent{
+		Action:    action,
+		Actor:     info.Actor,
+		RequestID: info.RequestID,
+		Before:    before,
+		After:     after,
+	}
+	if after != nil {
+		event.SubscriptionID = after.ID
+	} else if before != nil {
+		event.SubscriptionID = before.ID
+	}
+	return event
+}
+
+// DecodeSnapshot читает снимок подписки из журнала. Снимки, записанные до появления валют, не содержат
+// amount_minor и хранят цены в рублях: они приводятся к текущему формату при чтении, записи журнала не меняются.
+func DecodeSnapshot(data []byte) (*Subscriptions, error) {
+	var snapshot struct {
+		Subscriptions
+		AmountMinor *int64 `json:"amount_minor"`
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	sub := snapshot.Subscriptions
+	if snapshot.AmountMinor != nil {
+		sub.AmountMinor = *snapshot.AmountMinor
+		return &sub, nil
+	}
+
+	sub.AmountMinor = sub.Price * MinorUnits
+	sub.Currency = BaseCurrency
+	for i := range sub.Prices {
+		sub.Prices[i].AmountMinor = sub.Prices[i].Price * MinorUnits
+	}
+	sub.FillAmounts()
+	return &sub, nil
+}
+
+// EventFilter параметры выборки журнала изменений. События возвращаются в порядке записи.
+type EventFilter struct {
+	SubscriptionID *int64     // События одной подписки
+	Actor          *string    // События одного автора
+	Actions        []string   // События с любым из действий
+	From           *time.Time // Записаны не раньше
+	To             *time.Time // Записаны раньше
+	After          int64      // Вернуть события с ID больше этого
+	Limit          int        // Размер страницы, см. PageSize
+}
+
+// PageSize возвращает размер страницы с учетом значения по умолчанию и серверного максимума
+func (f *EventFilter) PageSize() int {
+	return pageSize(f.Limit)
+}
+
+// EventPage страница журнала изменений
+type EventPage struct {
+	Items []SubscriptionEvent
+	Next  int64 // ID последнего события страницы, если есть следующая, иначе 0
+}
+
+// NewEventPage собирает страницу из событий, запрошенных с лимитом limit+1 (см. NewPage)
+func NewEventPage(items []SubscriptionEvent, limit int) *EventPage {
+	page := &EventPage{Items: items}
+	if page.Items == nil {
+		page.Items = []SubscriptionEvent{}
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.Next = page.Items[limit-1].ID
+	}
+
+	return page
+}
+
+// EventListResponse структура для ответа со страницей журнала изменений
+type EventListResponse struct {
+	Items      []SubscriptionEvent `json:"items"`                 // События страницы
+	NextCursor string              `json:"next_cursor,omitempty"` // Курсор следующей страницы, отсутствует на последней
+}
+
+// AuditInfo сведения о запросе, которые записываются в журнал вместе с изменением
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+type auditInfoKey struct{}
+
+// WithAuditInfo возвращает контекст, изменения в котором записываются в журнал с info
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// AuditInfoFromContext возвращает сведения, сохраненные WithAuditInfo, или пустые
+func AuditInfoFromContext(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditInfoKey{}).(AuditInfo)
+	return info
+}
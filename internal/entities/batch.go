@@ -0,0 +1,35 @@
+package entities
+
+// Виды операций пакета
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation одна операция пакетного изменения подписок.
+// Для update и delete Version работает как If-Match: AnyVersion отключает проверку.
+type BatchOperation struct {
+	Op           string         `json:"op" enums:"create,update,delete" example:"create"`
+	ID           int64          `json:"id,omitempty" example:"1"`
+	Version      int64          `json:"version,omitempty" example:"3"`
+	Subscription *Subscriptions `json:"subscription,omitempty"`
+}
+
+// BatchResult результат операции пакета. ID для create заполняется только после успешного выполнения.
+type BatchResult struct {
+	ID      int64
+	Version int64
+	Err     error
+}
+
+// AbortBatch возвращает результаты пакета, откатанного из-за ошибки операции failed:
+// она сохраняет свою ошибку, остальные получают ErrBatchAborted
+func AbortBatch(ops []BatchOperation, failed BatchResult, index int) []BatchResult {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchResult{ID: op.ID, Err: ErrBatchAborted}
+	}
+	results[index] = failed
+	return results
+}
@@ -0,0 +1,51 @@
+package entities
+
+// Периоды оплаты подписки: цена подписки списывается один раз за период
+const (
+	BillingMonth   = "month"
+	BillingQuarter = "quarter"
+	BillingYear    = "year"
+	BillingWeek    = "week"
+)
+
+// ValidBillingPeriod сообщает, что period - известный период оплаты
+func ValidBillingPeriod(period string) bool {
+	switch period {
+	case BillingMonth, BillingQuarter, BillingYear, BillingWeek:
+		return true
+	default:
+		return false
+	}
+}
+
+// Billing возвращает период оплаты подписки; пустой период означает оплату раз в месяц
+func (s *Subscriptions) Billing() string {
+	if s.BillingPeriod == "" {
+		return BillingMonth
+	}
+	return s.BillingPeriod
+}
+
+// MonthlyPrice возвращает цену за период оплаты в пересчете на месяц с округлением до целого.
+// Для недельной оплаты месяц считается равным 365,25 / 12 дням.
+func MonthlyPrice(price int64, period string) int64 {
+	switch period {
+	case BillingQuarter:
+		return divRound(price, 3)
+	case BillingYear:
+		return divRound(price, 12)
+	case BillingWeek:
+		// 365,25 / 12 / 7 = 1461 / 336 недели в месяце
+		return divRound(price*1461, 336)
+	default:
+		return price
+	}
+}
+
+// divRound делит a на положительное b с округлением половины от нуля
+func divRound(a, b int64) int64 {
+	if a < 0 {
+		return -divRound(-a, b)
+	}
+	return (a + b/2) / b
+}
@@ -0,0 +1,132 @@
+package entities
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Валюты цен подписок. Суммы во всех валютах хранятся в минимальных единицах: копейках, центах, тиынах.
+const (
+	CurrencyRUB = "RUB"
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+	CurrencyKZT = "KZT"
+)
+
+// BaseCurrency валюта, в которой задаются курсы обмена и считается стоимость по умолчанию
+const BaseCurrency = CurrencyRUB
+
+// Currencies поддерживаемые валюты
+var Currencies = []string{CurrencyRUB, CurrencyUSD, CurrencyEUR, CurrencyKZT}
+
+// MinorUnits число минимальных единиц в единице любой поддерживаемой валюты
+const MinorUnits = 100
+
+// MajorUnits возвращает целую часть суммы amount, заданной в минимальных единицах
+func MajorUnits(amount int64) int64 {
+	return amount / MinorUnits
+}
+
+// ResolveAmount возвращает цену в минимальных единицах по price или amountMinor; ok false, если они расходятся
+func ResolveAmount(price, amountMinor int64) (amount int64, ok bool) {
+	if amountMinor == 0 {
+		return price * MinorUnits, true
+	}
+	return amountMinor, price == 0 || price == MajorUnits(amountMinor)
+}
+
+// ValidCurrency сообщает, что code - поддерживаемая валюта
+func ValidCurrency(code string) bool {
+	for _, c := range Currencies {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FillAmounts выводит из цены в минимальных единицах AmountMinor цену в основных единицах
+// и цены в пересчете на месяц
+func (s *Subscriptions) FillAmounts() {
+	s.Price = MajorUnits(s.AmountMinor)
+	s.MonthlyAmountMinor = MonthlyPrice(s.AmountMinor, s.BillingPeriod)
+	s.MonthlyPrice = MajorUnits(s.MonthlyAmountMinor)
+}
+
+// AmountRange возвращает границы MinPrice и MaxPrice фильтра в минимальных единицах:
+// подходят цены, целая часть которых не меньше MinPrice и не больше MaxPrice
+func (f *ListFilter) AmountRange() (min, max *int64) {
+	if f.MinPrice != nil {
+		v := *f.MinPrice * MinorUnits
+		min = &v
+	}
+	if f.MaxPrice != nil {
+		v := *f.MaxPrice*MinorUnits + MinorUnits - 1
+		max = &v
+	}
+	return min, max
+}
+
+// CurrencyCode возвращает валюту цены подписки; пустая валюта означает BaseCurrency
+func (s *Subscriptions) CurrencyCode() string {
+	if s.Currency == "" {
+		return BaseCurrency
+	}
+	return s.Currency
+}
+
+// ExchangeRate курс валюты за месяц: сколько единиц BaseCurrency стоит единица валюты Currency.
+// Курс задается десятичной строкой, чтобы не терять точность.
+type ExchangeRate struct {
+	Currency string `json:"currency" example:"USD"`
+	Month    string `json:"month" example:"03-2025"`
+	Rate     string `json:"rate" example:"92.5"`
+}
+
+// ExchangeRateListResponse список курсов валют
+type ExchangeRateListResponse struct {
+	Items []ExchangeRate `json:"items"`
+}
+
+// ExchangeRateRow курс валюты из строки CSV вместе с номером строки файла
+type ExchangeRateRow struct {
+	Line int
+	Rate ExchangeRate
+}
+
+// ExchangeRateFilter параметры выборки курсов валют
+type ExchangeRateFilter struct {
+	Currencies []string // Курсы любой из валют; пустой список - все валюты
+}
+
+// rateScale наибольшее число знаков курса после точки
+const rateScale = 8
+
+// ParseRate разбирает курс: положительное десятичное число, не больше 8 знаков после точки
+func ParseRate(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "eE/") || r.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: invalid rate %q, expected a positive decimal number", ErrValidation, s)
+	}
+	if _, frac, found := strings.Cut(s, "."); found && len(frac) > rateScale {
+		return nil, fmt.Errorf("%w: rate %q has more than %d decimal places", ErrValidation, s, rateScale)
+	}
+	return r, nil
+}
+
+// FormatRate возвращает курс без незначащих нулей
+func FormatRate(r *big.Rat) string {
+	s := r.FloatString(rateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// NormalizeRate приводит курс к виду FormatRate
+func NormalizeRate(s string) (string, error) {
+	r, err := ParseRate(s)
+	if err != nil {
+		return "", err
+	}
+	return FormatRate(r), nil
+}
@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+)
+
+// Ошибки предметной области. Хранилища и сервис оборачивают ими свои ошибки,
+// а HTTP-обработчики по ним выбирают код ответа (errors.Is).
+var (
+	ErrNotFound        = errors.New("not found")           // Запись не существует
+	ErrConflict        = errors.New("conflict")            // Нарушена уникальность или параллельное изменение
+	ErrValidation      = errors.New("validation failed")   // Данные не прошли проверку
+	ErrUnavailable     = errors.New("storage unavailable") // Хранилище недоступно, запрос можно повторить позже
+	ErrVersionMismatch = errors.New("version mismatch")    // Запись изменилась после чтения клиентом
+	ErrBatchAborted    = errors.New("batch aborted")       // Операция пакета отменена из-за ошибки другой операции
+)
+
+// FieldError описывает ошибку проверки одного поля запроса
+type FieldError struct {
+	Field   string `json:"field" example:"start_date"`
+	Message string `json:"message" example:"expected format MM-YYYY"`
+}
+
+// ValidationError набор ошибок проверки по полям. Сопоставляется с ErrValidation через errors.Is,
+// поэтому обработчики отвечают на нее как на любую ошибку валидации, дополнительно перечисляя поля.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add добавляет ошибку поля
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err возвращает nil, если ошибок полей нет, иначе саму ошибку
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return ErrValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// IdempotencyRecord сохраненный результат запроса с заголовком Idempotency-Key.
+// Пока первый запрос с ключом выполняется, Status равен нулю.
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string // Хеш метода, пути и тела запроса
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// Completed сообщает, сохранен ли ответ на запрос
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
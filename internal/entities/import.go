@@ -0,0 +1,29 @@
+package entities
+
+// ImportRow подписка из строки CSV импорта.
+// Errors содержит ошибки формата, найденные при разборе; такая строка не проверяется дальше.
+type ImportRow struct {
+	Line         int
+	Subscription Subscriptions
+	Errors       []FieldError
+}
+
+// ImportedRow принятая строка импорта. ID не заполняется при пробном импорте.
+type ImportedRow struct {
+	Line int   `json:"line" example:"2"`
+	ID   int64 `json:"id,omitempty" example:"1"`
+}
+
+// RejectedRow отклоненная строка импорта с причинами по полям
+type RejectedRow struct {
+	Line   int          `json:"line" example:"3"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportReport результат импорта подписок. Line - номер строки CSV, заголовок в строке 1.
+type ImportReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Total    int           `json:"total" example:"2"`
+	Accepted []ImportedRow `json:"accepted"`
+	Rejected []RejectedRow `json:"rejected"`
+}
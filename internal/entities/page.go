@@ -0,0 +1,108 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Ограничения размера страницы списка подписок
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ListCursor позиция в списке подписок: значения ключей сортировки последней записи страницы.
+// Следующая страница начинается с записи строго после этой позиции.
+type ListCursor struct {
+	Sort   string   `json:"o"` // Полный порядок списка (FormatSort), для которого выдан курсор
+	Values []string `json:"v"` // Значения ключей порядка в том же порядке (SortValue)
+}
+
+// SubscriptionPage страница списка подписок
+type SubscriptionPage struct {
+	Items []Subscriptions
+	Next  *ListCursor // nil на последней странице
+	Total *int64      // Количество подписок по фильтру без учета пагинации, если запрошено ListFilter.WithTotal
+}
+
+// SubscriptionListResponse структура для ответа со страницей списка подписок
+type SubscriptionListResponse struct {
+	Items      []Subscriptions `json:"items"`                 // Подписки страницы
+	NextCursor string          `json:"next_cursor,omitempty"` // Курсор следующей страницы, отсутствует на последней
+	Total      *int64          `json:"total,omitempty"`       // Количество подписок по фильтру, если передан include_total=true
+}
+
+// PageSize возвращает размер страницы с учетом значения по умолчанию и серверного максимума
+func (f *ListFilter) PageSize() int {
+	return pageSize(f.Limit)
+}
+
+func pageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultPageSize
+	case limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return limit
+	}
+}
+
+// NewPage собирает страницу из выборки, упорядоченной по order и запрошенной с лимитом limit+1:
+// лишняя запись означает, что есть следующая страница, и отбрасывается
+func NewPage(items []Subscriptions, limit int, order []SortField) *SubscriptionPage {
+	page := &SubscriptionPage{Items: items}
+	if page.Items == nil {
+		page.Items = []Subscriptions{}
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := &page.Items[limit-1]
+		page.Next = &ListCursor{Sort: FormatSort(order)}
+		for _, f := range order {
+			page.Next.Values = append(page.Next.Values, SortValue(last, f.Field))
+		}
+	}
+
+	return page
+}
+
+// EncodeCursor кодирует курсор в непрозрачную для клиента строку
+func EncodeCursor(c *ListCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает строку, полученную от EncodeCursor
+func DecodeCursor(s string) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrValidation)
+	}
+
+	var c ListCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrValidation)
+	}
+
+	return &c, nil
+}
+
+// Key возвращает подписку, в которой заполнены поля ключей порядка order значениями курсора.
+// Курсор должен быть выдан для того же порядка.
+func (c *ListCursor) Key(order []SortField) (*Subscriptions, error) {
+	if c.Sort != FormatSort(order) || len(c.Values) != len(order) {
+		return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrValidation, c.Sort)
+	}
+
+	var key Subscriptions
+	for i, f := range order {
+		if err := setSortValue(&key, f.Field, c.Values[i]); err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor: %w", ErrValidation, err)
+		}
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,63 @@
+package entities
+
+// SubscriptionPatch частичное обновление подписки (RFC 7396): nil - поле не меняется, SetEndDate и SetBillingAnchor отличают null
+type SubscriptionPatch struct {
+	ServiceName *string
+	Price       *int64
+	AmountMinor *int64
+	Currency    *string
+	UserID      *string
+	StartDate   *string
+	EndDate     *string
+	SetEndDate  bool
+
+	BillingPeriod    *string
+	BillingAnchor    *string
+	SetBillingAnchor bool
+}
+
+// Empty сообщает, что патч не меняет ни одного поля
+func (p *SubscriptionPatch) Empty() bool {
+	return p.ServiceName == nil && p.Price == nil && p.AmountMinor == nil && p.Currency == nil && p.UserID == nil && p.StartDate == nil && !p.SetEndDate &&
+		p.BillingPeriod == nil && !p.SetBillingAnchor
+}
+
+// Apply переносит переданные поля патча в подписку. Цена переносится в AmountMinor,
+// Price и цены в пересчете на месяц выводятся из нее заново (см. FillAmounts).
+func (p *SubscriptionPatch) Apply(sub *Subscriptions) {
+	if p.ServiceName != nil {
+		sub.ServiceName = *p.ServiceName
+	}
+	if p.AmountMinor != nil {
+		sub.AmountMinor = *p.AmountMinor
+	} else if p.Price != nil {
+		sub.AmountMinor = *p.Price * MinorUnits
+	}
+	if p.Currency != nil {
+		sub.Currency = *p.Currency
+	}
+	if p.UserID != nil {
+		sub.UserID = *p.UserID
+	}
+	if p.StartDate != nil {
+		sub.StartDate = *p.StartDate
+	}
+	if p.SetEndDate {
+		sub.EndDate = nil
+		if p.EndDate != nil {
+			endDate := *p.EndDate
+			sub.EndDate = &endDate
+		}
+	}
+	if p.BillingPeriod != nil {
+		sub.BillingPeriod = *p.BillingPeriod
+	}
+	if p.SetBillingAnchor {
+		sub.BillingAnchor = nil
+		if p.BillingAnchor != nil {
+			anchor := *p.BillingAnchor
+			sub.BillingAnchor = &anchor
+		}
+	}
+	sub.FillAmounts()
+}
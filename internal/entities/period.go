@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// monthLayout формат месяца, используемый в API (MM-YYYY)
+const monthLayout = "01-2006"
+
+// Month порядковый номер календарного месяца (год*12 + номер месяца - 1).
+// Позволяет сравнивать месяцы и считать их количество обычной арифметикой.
+type Month int
+
+// ParseMonth разбирает месяц в формате MM-YYYY
+func ParseMonth(s string) (Month, error) {
+	t, err := time.Parse(monthLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid month %q, expected format MM-YYYY", ErrValidation, s)
+	}
+	return MonthOf(t), nil
+}
+
+// MonthOf возвращает месяц, к которому относится момент времени t
+func MonthOf(t time.Time) Month {
+	return Month(t.Year()*12 + int(t.Month()) - 1)
+}
+
+// Time возвращает первое число месяца (UTC)
+func (m Month) Time() time.Time {
+	return time.Date(int(m)/12, time.Month(int(m)%12+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// String возвращает месяц в формате MM-YYYY
+func (m Month) String() string {
+	return m.Time().Format(monthLayout)
+}
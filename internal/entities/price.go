@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PriceChange изменение цены подписки с месяца EffectiveFrom (MM-YYYY)
+type PriceChange struct {
+	EffectiveFrom string `json:"effective_from" example:"03-2026"`
+	Price         int64  `json:"price" example:"1299"`
+	AmountMinor   int64  `json:"amount_minor" example:"129900"`
+}
+
+// PriceListResponse история цен подписки: цена с начала подписки и все запланированные изменения по порядку
+type PriceListResponse struct {
+	Items []PriceChange `json:"items"`
+}
+
+// PriceHistory возвращает цены подписки по месяцам, с которых они действуют, начиная с start_date
+func PriceHistory(sub *Subscriptions) []PriceChange {
+	history := make([]PriceChange, 0, len(sub.Prices)+1)
+	history = append(history, PriceChange{EffectiveFrom: sub.StartDate, Price: sub.Price, AmountMinor: sub.AmountMinor})
+	return append(history, sub.Prices...)
+}
+
+// SetPrice добавляет изменение цены или заменяет изменение с тем же месяцем.
+// Изменения остаются упорядоченными по месяцу, Price выводится из AmountMinor.
+func (s *Subscriptions) SetPrice(change PriceChange) error {
+	month, err := ParseMonth(change.EffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("effective_from: %w", err)
+	}
+	change.EffectiveFrom = month.String()
+	change.Price = MajorUnits(change.AmountMinor)
+
+	prices := make([]PriceChange, 0, len(s.Prices)+1)
+	for _, p := range s.Prices {
+		if p.EffectiveFrom != change.EffectiveFrom {
+			prices = append(prices, p)
+		}
+	}
+	prices = append(prices, change)
+
+	months := make(map[string]Month, len(prices))
+	for _, p := range prices {
+		if months[p.EffectiveFrom], err = ParseMonth(p.EffectiveFrom); err != nil {
+			return fmt.Errorf("prices: %w", err)
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return months[prices[i].EffectiveFrom] < months[prices[j].EffectiveFrom] })
+
+	s.Prices = prices
+	return nil
+}
+
+// RemovePrice удаляет изменение цены с месяца effectiveFrom (MM-YYYY).
+// Возвращает ErrNotFound, если такого изменения нет.
+func (s *Subscriptions) RemovePrice(effectiveFrom string) error {
+	month, err := ParseMonth(effectiveFrom)
+	if err != nil {
+		return fmt.Errorf("effective_from: %w", err)
+	}
+
+	for i, p := range s.Prices {
+		if p.EffectiveFrom == month.String() {
+			prices := append(append([]PriceChange{}, s.Prices[:i]...), s.Prices[i+1:]...)
+			if len(prices) == 0 {
+				prices = nil
+			}
+			s.Prices = prices
+			return nil
+		}
+	}
+	return fmt.Errorf("price change from %s: %w", month, ErrNotFound)
+}
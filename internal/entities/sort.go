@@ -0,0 +1,135 @@
+package entities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поля, по которым можно сортировать список подписок. Все они NOT NULL,
+// поэтому keyset-пагинация по ним не требует особой обработки NULL.
+const (
+	SortID          = "id"
+	SortServiceName = "service_name"
+	SortPrice       = "price"
+	SortStartDate   = "start_date"
+	SortCreatedAt   = "created_at"
+	SortUpdatedAt   = "updated_at"
+)
+
+// sortFields допустимые поля сортировки. Хранилища строят ORDER BY только по ним.
+var sortFields = map[string]bool{
+	SortID:          true,
+	SortServiceName: true,
+	SortPrice:       true,
+	SortStartDate:   true,
+	SortCreatedAt:   true,
+	SortUpdatedAt:   true,
+}
+
+// SortField ключ сортировки списка
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort порядок списка, если сортировка не задана
+var DefaultSort = []SortField{{Field: SortStartDate}}
+
+// ParseSort разбирает значение вида "price,-start_date": поля через запятую, минус означает убывание
+func ParseSort(spec string) ([]SortField, error) {
+	var sort []SortField
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+
+		if !sortFields[field.Field] {
+			return nil, fmt.Errorf("unsupported sort field %q", part)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+		seen[field.Field] = true
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+// FormatSort возвращает строку сортировки в формате ParseSort
+func FormatSort(sort []SortField) string {
+	parts := make([]string, 0, len(sort))
+	for _, f := range sort {
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Order возвращает полный порядок списка: сортировку фильтра (или DefaultSort)
+// и id последним ключом, чтобы порядок был однозначным
+func (f *ListFilter) Order() []SortField {
+	sort := f.Sort
+	if len(sort) == 0 {
+		sort = DefaultSort
+	}
+
+	for _, field := range sort {
+		if field.Field == SortID {
+			return sort
+		}
+	}
+
+	order := make([]SortField, 0, len(sort)+1)
+	order = append(order, sort...)
+	return append(order, SortField{Field: SortID})
+}
+
+// SortValue возвращает значение поля сортировки подписки в виде строки для курсора
+func SortValue(sub *Subscriptions, field string) string {
+	switch field {
+	case SortID:
+		return strconv.FormatInt(sub.ID, 10)
+	case SortServiceName:
+		return sub.ServiceName
+	case SortPrice:
+		return strconv.FormatInt(sub.AmountMinor, 10)
+	case SortStartDate:
+		return sub.StartDate
+	case SortCreatedAt:
+		return sub.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortUpdatedAt:
+		return sub.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+// setSortValue записывает значение поля сортировки из курсора в подписку
+func setSortValue(sub *Subscriptions, field, value string) error {
+	var err error
+	switch field {
+	case SortID:
+		sub.ID, err = strconv.ParseInt(value, 10, 64)
+	case SortServiceName:
+		sub.ServiceName = value
+	case SortPrice:
+		sub.AmountMinor, err = strconv.ParseInt(value, 10, 64)
+	case SortStartDate:
+		_, err = ParseMonth(value)
+		sub.StartDate = value
+	case SortCreatedAt:
+		sub.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+	case SortUpdatedAt:
+		sub.UpdatedAt, err = time.Parse(time.RFC3339Nano, value)
+	default:
+		err = fmt.Errorf("unsupported sort field %q", field)
+	}
+	return err
+}
@@ -1,18 +1,50 @@
 package entities
 
+import "time"
+
+// Subscriptions подписка пользователя. ID, версию, отметки времени и MonthlyPrice назначает хранилище
+// Цена хранится в минимальных единицах AmountMinor валюты Currency, Price - ее целая часть
 type Subscriptions struct {
-	ServiceName string  `json:"service_name"`
-	Price       int64   `json:"price"`
-	UserID      string  `json:"user_id"`
-	StartDate   string  `json:"start_date"`
-	EndDate     *string `json:"end_date,omitempty"`
+	ID                 int64         `json:"id" readonly:"true"`
+	ServiceName        string        `json:"service_name"`
+	Price              int64         `json:"price" example:"999"`
+	AmountMinor        int64         `json:"amount_minor" example:"99900"`
+	Currency           string        `json:"currency" enums:"RUB,USD,EUR,KZT"`
+	UserID             string        `json:"user_id"`
+	StartDate          string        `json:"start_date"`
+	EndDate            *string       `json:"end_date,omitempty"`
+	BillingPeriod      string        `json:"billing_period" enums:"month,quarter,year,week"`
+	BillingAnchor      *string       `json:"billing_anchor,omitempty"`
+	MonthlyPrice       int64         `json:"monthly_price" readonly:"true"`
+	MonthlyAmountMinor int64         `json:"monthly_amount_minor" readonly:"true"`
+	Version            int64         `json:"version" readonly:"true"`
+	CreatedAt          time.Time     `json:"created_at" readonly:"true"`
+	UpdatedAt          time.Time     `json:"updated_at" readonly:"true"`
+	DeletedAt          *time.Time    `json:"deleted_at,omitempty" readonly:"true"`
+	Prices             []PriceChange `json:"prices,omitempty" readonly:"true"`
 }
 
+// AnyVersion значение ожидаемой версии, при котором изменение выполняется без проверки версии
+const AnyVersion int64 = 0
+
+// ListFilter параметры выборки списка подписок. Условия объединяются через AND.
 type ListFilter struct {
-	UserID      *string
-	ServiceName *string
-	StartDate   *string
-	EndDate     *string
+	UserIDs      []string // Подписки любого из пользователей
+	ServiceNames []string // Подписки любого из сервисов (точное совпадение названия)
+	Search       *string  // Подстрока названия сервиса без учета регистра
+	StartDate    *string  // Начало не раньше месяца MM-YYYY
+	EndDate      *string  // Окончание не позже месяца MM-YYYY, бессрочные подписки не подходят
+	ActiveOn     *string  // Подписка действует в месяце MM-YYYY
+	Currencies   []string // Подписки с ценой в любой из валют
+	MinPrice     *int64   // Цена в основных единицах не меньше, сравнивается в одной валюте из Currencies; см. AmountRange
+	MaxPrice     *int64   // Цена в основных единицах не больше, сравнивается в одной валюте из Currencies; см. AmountRange
+	HasEndDate   *bool    // true - только с датой окончания, false - только бессрочные
+	Deleted      bool     // Выбрать подписки из корзины вместо действующих
+
+	Sort      []SortField // Порядок списка, по умолчанию DefaultSort; см. Order
+	Limit     int         // Размер страницы, см. PageSize
+	After     *ListCursor // Вернуть записи после этой позиции
+	WithTotal bool        // Посчитать общее количество записей по фильтру
 }
 
 // CostFilter содержит параметры для фильтрации при подсчете стоимости подписок
@@ -21,9 +53,67 @@ type CostFilter struct {
 	ServiceName *string `json:"service_name,omitempty"` // Фильтр по названию сервиса
 	StartPeriod string  `json:"start_period"`           // Начало периода в формате MM-YYYY
 	EndPeriod   string  `json:"end_period"`             // Конец периода в формате MM-YYYY
+	Basis       string  `json:"basis,omitempty"`        // Метод учета: BasisCash (по умолчанию) или BasisAccrual
+	Currency    string  `json:"currency,omitempty"`     // Валюта результата, по умолчанию BaseCurrency
 }
 
+// MaxBreakdownMonths наибольшая длина периода помесячной разбивки стоимости в месяцах
+const MaxBreakdownMonths = 120
+
+// Методы учета стоимости подписок
+const (
+	// BasisCash - списания в месяцах, когда они произошли
+	BasisCash = "cash"
+	// BasisAccrual - каждое списание равномерно распределяется по месяцам оплаченного периода
+	BasisAccrual = "accrual"
+)
+
+// Поля, по которым можно группировать стоимость подписок
+const (
+	GroupByServiceName = "service_name"
+	GroupByUserID      = "user_id"
+)
+
 // TotalCostResponse структура для ответа с суммарной стоимостью
 type TotalCostResponse struct {
-	TotalCost int64 `json:"total_cost"` // Суммарная стоимость в рублях
+	TotalCost          int64          `json:"total_cost"`          // Суммарная стоимость в основных единицах Currency (целая часть)
+	TotalCostMinor     int64          `json:"total_cost_minor"`    // Суммарная стоимость в минимальных единицах Currency
+	SubscriptionMonths int64          `json:"subscription_months"` // Сумма месяцев периода, в которых действовала каждая подписка
+	Currency           string         `json:"currency"`            // Валюта стоимости
+	Rates              []ExchangeRate `json:"rates,omitempty"`     // Курсы, по которым пересчитаны цены в других валютах
+}
+
+// CostBreakdownResponse структура для ответа с помесячной разбивкой стоимости
+type CostBreakdownResponse struct {
+	TotalCost      int64          `json:"total_cost"`       // Суммарная стоимость за весь период в основных единицах Currency
+	TotalCostMinor int64          `json:"total_cost_minor"` // Суммарная стоимость за весь период в минимальных единицах Currency
+	Months         []MonthlyCost  `json:"months"`           // Стоимость по каждому календарному месяцу периода
+	Currency       string         `json:"currency"`         // Валюта стоимости
+	Rates          []ExchangeRate `json:"rates,omitempty"`  // Курсы, по которым пересчитаны цены в других валютах
+}
+
+// MonthlyCost стоимость подписок за один календарный месяц
+type MonthlyCost struct {
+	Month          string             `json:"month"`            // Месяц в формате MM-YYYY
+	TotalCost      int64              `json:"total_cost"`       // Стоимость за месяц в основных единицах
+	TotalCostMinor int64              `json:"total_cost_minor"` // Стоимость за месяц в минимальных единицах
+	Subscriptions  []SubscriptionCost `json:"subscriptions"`    // Подписки, вошедшие в стоимость месяца
+}
+
+// SubscriptionCost вклад одной подписки в стоимость месяца
+type SubscriptionCost struct {
+	ServiceName string `json:"service_name"` // Название сервиса
+	UserID      string `json:"user_id"`      // ID пользователя
+	Cost        int64  `json:"cost"`         // Стоимость в основных единицах валюты ответа
+	CostMinor   int64  `json:"cost_minor"`   // Стоимость в минимальных единицах валюты ответа
+}
+
+// GroupedCost стоимость подписок одной группы
+type GroupedCost struct {
+	Key                map[string]string `json:"key"`                 // Значения полей группировки, например {"service_name": "Netflix"}
+	TotalCost          int64             `json:"total_cost"`          // Суммарная стоимость группы в основных единицах Currency
+	TotalCostMinor     int64             `json:"total_cost_minor"`    // Суммарная стоимость группы в минимальных единицах Currency
+	SubscriptionMonths int64             `json:"subscription_months"` // Сумма месяцев периода, в которых действовала каждая подписка группы
+	Currency           string            `json:"currency"`            // Валюта стоимости
+	Rates              []ExchangeRate    `json:"rates,omitempty"`     // Курсы, по которым пересчитаны цены подписок группы
 }
@@ -0,0 +1,29 @@
+package public
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminOnly middleware пропускает только запросы с Authorization: Bearer <token>; при пустом token отвечает 403
+func AdminOnly(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				RespondWithError(w, r, http.StatusForbidden, "admin operations are disabled, set HTTP_ADMIN_TOKEN to enable them")
+				return
+			}
+
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				RespondWithError(w, r, http.StatusUnauthorized, "admin token required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,43 @@
+package public
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminOnly(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	do := func(token, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/exchange-rates/USD/01-2025", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		AdminOnly(token)(ok).ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "disabled", token: "", authorization: "Bearer ", want: http.StatusForbidden},
+		{name: "no header", token: "secret", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "secret", authorization: "Basic secret", want: http.StatusUnauthorized},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", want: http.StatusNoContent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(tc.token, tc.authorization)
+			if rec.Code != tc.want {
+				t.Fatalf("got %d, want %d", rec.Code, tc.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package public
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"strconv"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// actorHeader заголовок с автором изменения для журнала. Аутентификации в сервисе нет,
+// поэтому автора передает вызывающая сторона, например шлюз после проверки токена.
+const actorHeader = "X-Actor"
+
+// maxActorLength ограничение длины автора, как у колонки журнала
+const maxActorLength = 255
+
+// AuditContext передает в контекст запроса автора из X-Actor и ID запроса,
+// с которыми хранилище записывает изменения в журнал. Должен стоять после middleware.RequestID.
+func AuditContext(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get(actorHeader)
+		if len(actor) > maxActorLength {
+			RespondWithError(w, r, http.StatusBadRequest, "invalid "+actorHeader+" header",
+				entities.FieldError{Field: actorHeader, Message: "must be at most 255 characters"})
+			return
+		}
+
+		ctx := entities.WithAuditInfo(r.Context(), entities.AuditInfo{
+			Actor:     actor,
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// SubscriptionHistory возвращает журнал изменений подписки
+// @Summary История подписки
+// @Description Возвращает изменения подписки в порядке их выполнения: кто, когда и в каком запросе изменил подписку,
+// @Description со снимками до и после изменения. История сохраняется и после очистки подписки из корзины.
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор страницы из next_cursor предыдущего ответа"
+// @Success 200 {object} entities.EventListResponse "Страница истории"
+// @Header 200 {string} Link "Ссылки на первую и следующую страницы (RFC 8288)"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id}/history [get]
+func (s *Server) SubscriptionHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+
+	filter, err := parseEventFilter(r, false)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+	filter.SubscriptionID = &id
+
+	s.respondWithEvents(w, r, filter)
+}
+
+// ListAudit возвращает журнал изменений всех подписок
+// @Summary Журнал изменений
+// @Description Возвращает изменения подписок в порядке их выполнения с фильтрацией по подписке, автору, действию и времени
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param subscription_id query int false "ID подписки"
+// @Param actor query string false "Автор изменения (X-Actor)"
+// @Param action query []string false "Действие, можно повторять" collectionFormat(multi) Enums(create, update, delete, restore)
+// @Param from query string false "Изменения не раньше (RFC 3339)"
+// @Param to query string false "Изменения раньше (RFC 3339)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор страницы из next_cursor предыдущего ответа"
+// @Success 200 {object} entities.EventListResponse "Страница журнала"
+// @Header 200 {string} Link "Ссылки на первую и следующую страницы (RFC 8288)"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /audit [get]
+func (s *Server) ListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r, true)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	s.respondWithEvents(w, r, filter)
+}
+
+// respondWithEvents отвечает страницей журнала по фильтру
+func (s *Server) respondWithEvents(w http.ResponseWriter, r *http.Request, filter *entities.EventFilter) {
+	page, err := s.Service.ListEvents(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to list subscription events")
+		return
+	}
+
+	res := entities.EventListResponse{Items: page.Items}
+	if page.Next != 0 {
+		res.NextCursor = strconv.FormatInt(page.Next, 10)
+	}
+
+	w.Header().Set("Link", pageLinks(r, res.NextCursor))
+	RespondWithJSON(w, http.StatusOK, res)
+}
+
+// parseEventFilter разбирает параметры выборки журнала; фильтры глобального журнала только при global
+func parseEventFilter(r *http.Request, global bool) (*entities.EventFilter, error) {
+	query := r.URL.Query()
+	verr := &entities.ValidationError{}
+	filter := &entities.EventFilter{}
+
+	if global {
+		if v := query.Get("subscription_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				verr.Add("subscription_id", "must be an integer")
+			}
+			filter.SubscriptionID = &id
+		}
+		if v := query.Get("actor"); v != "" {
+			filter.Actor = &v
+		}
+		for _, v := range query["action"] {
+			if !entities.ValidEventAction(v) {
+				verr.Add("action", "must be one of create, update, delete, restore")
+				continue
+			}
+			filter.Actions = append(filter.Actions, v)
+		}
+		parseTime := func(field string) *time.Time {
+			v := query.Get(field)
+			if v == "" {
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				verr.Add(field, "must be an RFC 3339 timestamp")
+			}
+			return &t
+		}
+		filter.From = parseTime("from")
+		filter.To = parseTime("to")
+		if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+			verr.Add("to", "must be after from")
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			verr.Add("limit", "must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		after, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 1 {
+			verr.Add("cursor", "invalid cursor")
+		}
+		filter.After = after
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
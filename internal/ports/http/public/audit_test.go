@@ -0,0 +1,81 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestAudit(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	server := &Server{Service: svc}
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID, AuditContext)
+	router.Post("/subscriptions", server.CreateSubscription)
+	router.Patch("/subscriptions/{id}", server.PatchSubscription)
+	router.Get("/subscriptions/{id}/history", server.SubscriptionHistory)
+	router.Get("/audit", server.ListAudit)
+	do := func(method, target, actor, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if actor != "" {
+			req.Header.Set(actorHeader, actor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	events := func(rec *httptest.ResponseRecorder) entities.EventListResponse {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var res entities.EventListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return res
+	}
+
+	body := `{"service_name": "Netflix", "price": 999, "user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "start_date": "07-2025"}`
+	if rec := do(http.MethodPost, "/subscriptions", "alice", body); rec.Code != http.StatusCreated {
+		t.Fatalf("create: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPatch, "/subscriptions/1", "bob", `{"price": 1099}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch: got %d: %s", rec.Code, rec.Body)
+	}
+
+	history := events(do(http.MethodGet, "/subscriptions/1/history", "", ""))
+	if len(history.Items) != 2 || history.Items[0].Actor != "alice" || history.Items[1].Actor != "bob" ||
+		history.Items[1].RequestID == "" || history.Items[1].Before.Price != 999 || history.Items[1].After.Price != 1099 {
+		t.Fatalf("history = %+v", history)
+	}
+
+	page := events(do(http.MethodGet, "/audit?actor=bob&limit=1", "", ""))
+	if len(page.Items) != 1 || page.Items[0].Action != entities.EventUpdate || page.NextCursor != "" {
+		t.Fatalf("audit by bob = %+v", page)
+	}
+	page = events(do(http.MethodGet, "/audit?limit=1", "", ""))
+	if len(page.Items) != 1 || page.NextCursor == "" {
+		t.Fatalf("first audit page = %+v", page)
+	}
+	if next := events(do(http.MethodGet, "/audit?limit=1&cursor="+page.NextCursor, "", "")); len(next.Items) != 1 || next.Items[0].Actor != "bob" {
+		t.Fatalf("second audit page = %+v", next)
+	}
+
+	for _, query := range []string{"action=purge", "from=yesterday", "subscription_id=x", "cursor=-1"} {
+		if rec := do(http.MethodGet, "/audit?"+query, "", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /audit?%s: got %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if rec := do(http.MethodPost, "/subscriptions", strings.Repeat("a", 256), body); rec.Code != http.StatusBadRequest {
+		t.Errorf("long X-Actor: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,173 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/ports/http/public/utils"
+)
+
+// defaultBatchLimit предельное число операций пакета, если оно не задано в конфигурации
+const defaultBatchLimit = 1000
+
+// BatchRequest тело пакетного запроса
+type BatchRequest struct {
+	Operations []entities.BatchOperation `json:"operations"`
+}
+
+// BatchItemResult результат одной операции пакета. Status - HTTP-статус, который получил бы
+// отдельный запрос: 201 для create, 200 для update, 204 для delete, 424 для отмененной операции.
+type BatchItemResult struct {
+	Index   int      `json:"index" example:"0"`
+	Op      string   `json:"op" example:"create"`
+	Status  int      `json:"status" example:"201"`
+	ID      int64    `json:"id,omitempty" example:"1"`
+	Version int64    `json:"version,omitempty" example:"1"`
+	Error   *Problem `json:"error,omitempty"`
+}
+
+// BatchResponse результаты пакета в порядке операций запроса
+type BatchResponse struct {
+	Atomic    bool              `json:"atomic"`
+	Succeeded int               `json:"succeeded" example:"2"`
+	Failed    int               `json:"failed" example:"0"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// ApplyBatch выполняет пакет операций над подписками
+// @Summary Пакетное изменение подписок
+// @Description Выполняет до HTTP_BATCH_LIMIT операций create, update и delete в одной транзакции.
+// @Description При atomic=true ошибка любой операции откатывает весь пакет, остальные операции получают статус 424.
+// @Description При atomic=false (по умолчанию) ошибка отменяет только свою операцию.
+// @Description version в update и delete работает как If-Match. Ответ 200, если все операции выполнены, иначе 207.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param atomic query bool false "Выполнить пакет целиком или не выполнять совсем" default(false)
+// @Param Idempotency-Key header string false "Ключ идемпотентности запроса (до 255 символов)"
+// @Param batch body BatchRequest true "Операции пакета"
+// @Success 200 {object} BatchResponse "Все операции выполнены"
+// @Success 207 {object} BatchResponse "Часть операций не выполнена, подробности в results"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 409 {object} Problem "Запрос с тем же Idempotency-Key еще выполняется"
+// @Failure 422 {object} Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/batch [post]
+func (s *Server) ApplyBatch(w http.ResponseWriter, r *http.Request) {
+	atomic := false
+	if value := r.URL.Query().Get("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			RespondWithError(w, r, http.StatusBadRequest, "invalid query parameters",
+				entities.FieldError{Field: "atomic", Message: "must be true or false"})
+			return
+		}
+	}
+
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := s.validateBatch(req.Operations); err != nil {
+		RespondWithServiceError(w, r, err, "invalid batch")
+		return
+	}
+
+	results, err := s.Service.ApplyBatch(r.Context(), req.Operations, atomic)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to apply batch")
+		return
+	}
+
+	res := BatchResponse{Atomic: atomic, Results: make([]BatchItemResult, len(results))}
+	for i, result := range results {
+		item := BatchItemResult{Index: i, Op: req.Operations[i].Op, ID: result.ID, Version: result.Version}
+		if result.Err != nil {
+			item.Error = batchProblem(r, i, result.Err)
+			item.Status = item.Error.Status
+			res.Failed++
+		} else {
+			item.Status = batchStatus(item.Op)
+			res.Succeeded++
+		}
+		res.Results[i] = item
+	}
+
+	code := http.StatusOK
+	if res.Failed > 0 {
+		code = http.StatusMultiStatus
+	}
+	RespondWithJSON(w, code, res)
+}
+
+// validateBatch проверяет размер пакета и формат полей подписок, как это делают обработчики отдельных запросов
+func (s *Server) validateBatch(ops []entities.BatchOperation) error {
+	limit := defaultBatchLimit
+	if s.cfg != nil && s.cfg.HTTPServer.BatchLimit > 0 {
+		limit = s.cfg.HTTPServer.BatchLimit
+	}
+
+	verr := &entities.ValidationError{}
+	switch {
+	case len(ops) == 0:
+		verr.Add("operations", "must not be empty")
+	case len(ops) > limit:
+		verr.Add("operations", fmt.Sprintf("must contain at most %d operations", limit))
+	}
+	for i, op := range ops {
+		if op.Subscription == nil {
+			continue
+		}
+		var subErr *entities.ValidationError
+		if errors.As(utils.ValidateSubscription(op.Subscription), &subErr) {
+			for _, f := range subErr.Fields {
+				verr.Add(fmt.Sprintf("operations[%d].subscription.%s", i, f.Field), f.Message)
+			}
+		}
+	}
+
+	return verr.Err()
+}
+
+// batchStatus HTTP-статус успешной операции пакета
+func batchStatus(op string) int {
+	switch op {
+	case entities.BatchCreate:
+		return http.StatusCreated
+	case entities.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// batchProblem описывает ошибку операции пакета так же, как RespondWithServiceError описал бы ошибку отдельного запроса
+func batchProblem(r *http.Request, index int, err error) *Problem {
+	code := errorStatus(err)
+	problem := &Problem{
+		Type:   problemType(code),
+		Title:  http.StatusText(code),
+		Status: code,
+	}
+
+	var verr *entities.ValidationError
+	switch {
+	case code >= http.StatusInternalServerError:
+		slog.Error("Batch operation failed", "error", err, "index", index, "request_id", middleware.GetReqID(r.Context()))
+		problem.Detail = "operation failed"
+	case errors.As(err, &verr):
+		problem.Detail = "invalid operation"
+		problem.Errors = verr.Fields
+	default:
+		problem.Detail = err.Error()
+	}
+
+	return problem
+}
@@ -0,0 +1,61 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/service"
+)
+
+func TestApplyBatch(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	router := chi.NewRouter()
+	router.Post("/subscriptions/batch", (&Server{Service: svc}).ApplyBatch)
+	post := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions/batch"+query, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	const sub = `{"service_name": "Netflix", "price": 999, "user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "start_date": "07-2025"}`
+
+	rec := post("", `{"operations": [{"op": "create", "subscription": `+sub+`}, {"op": "delete", "id": 42}]}`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusMultiStatus, rec.Body)
+	}
+	var res BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 1 || res.Results[0].Status != http.StatusCreated || res.Results[0].ID != 1 ||
+		res.Results[1].Status != http.StatusNotFound || res.Results[1].Error == nil {
+		t.Fatalf("response = %+v", res)
+	}
+
+	rec = post("?atomic=true", `{"operations": [{"op": "update", "id": 1, "version": 1, "subscription": `+sub+`}, {"op": "delete", "id": 42}]}`)
+	res = BatchResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !res.Atomic || res.Failed != 2 || res.Results[0].Status != http.StatusFailedDependency {
+		t.Fatalf("atomic response = %+v", res)
+	}
+
+	rec = post("", `{"operations": [{"op": "create", "subscription": {"service_name": "Netflix", "user_id": "bad", "start_date": "07-2025"}}]}`)
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "operations[0].subscription.user_id" {
+		t.Fatalf("invalid user_id: got %d %+v", rec.Code, problem)
+	}
+
+	if rec := post("", `{"operations": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
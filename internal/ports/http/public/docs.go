@@ -15,3 +15,8 @@ package public
 // @host localhost:8082
 // @BasePath /
 // @schemes http
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer)
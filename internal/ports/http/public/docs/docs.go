@@ -24,9 +24,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/subscriptions": {
+        "/audit": {
             "get": {
-                "description": "Получает список подписок с возможностью фильтрации",
+                "description": "Возвращает изменения подписок в порядке их выполнения с фильтрацией по подписке, автору, действию и времени",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,105 +34,100 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "audit"
                 ],
-                "summary": "Список подписок",
+                "summary": "Журнал изменений",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID пользователя (UUID)",
-                        "name": "user_id",
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "subscription_id",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Название сервиса",
-                        "name": "service_name",
+                        "description": "Автор изменения (X-Actor)",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "create",
+                                "update",
+                                "delete",
+                                "restore"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Действие, можно повторять",
+                        "name": "action",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Дата начала подписки (MM-YYYY)",
-                        "name": "start_date",
+                        "description": "Изменения не раньше (RFC 3339)",
+                        "name": "from",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Дата окончания подписки (MM-YYYY)",
-                        "name": "end_date",
+                        "description": "Изменения раньше (RFC 3339)",
+                        "name": "to",
                         "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Список подписок",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/entities.Subscriptions"
-                            }
-                        }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            },
-            "post": {
-                "description": "Создает новую запись о подписке пользователя",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "subscriptions"
-                ],
-                "summary": "Создание подписки",
-                "parameters": [
                     {
-                        "description": "Данные подписки",
-                        "name": "subscription",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/entities.Subscriptions"
-                        }
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор страницы из next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "id созданной подписки",
+                    "200": {
+                        "description": "Страница журнала",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer",
-                                "format": "int64"
+                            "$ref": "#/definitions/entities.EventListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую и следующую страницы (RFC 8288)"
                             }
                         }
                     },
                     "400": {
-                        "description": "Ошибка в запросе",
+                        "description": "Ошибка в параметрах запроса",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     }
                 }
             }
         },
-        "/subscriptions/cost": {
+        "/exchange-rates": {
             "get": {
-                "description": "Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией",
+                "description": "Возвращает курсы валют к рублю по месяцам. Курс действует только в своем месяце, на другие месяцы он не переносится.",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,104 +135,138 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "exchange-rates"
                 ],
-                "summary": "Расчет стоимости подписок",
+                "summary": "Курсы валют",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Начало периода (MM-YYYY)",
-                        "name": "start_period",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Конец периода (MM-YYYY)",
-                        "name": "end_period",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "ID пользователя (UUID)",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Название сервиса",
-                        "name": "service_name",
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "USD",
+                                "EUR",
+                                "KZT"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Валюты",
+                        "name": "currency",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Суммарная стоимость",
+                        "description": "Курсы по валюте и месяцу",
                         "schema": {
-                            "$ref": "#/definitions/entities.TotalCostResponse"
+                            "$ref": "#/definitions/entities.ExchangeRateListResponse"
                         }
                     },
                     "400": {
                         "description": "Ошибка в параметрах запроса",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     }
                 }
             }
         },
-        "/subscriptions/{id}": {
-            "get": {
-                "description": "Получает детальную информацию о подписке по её ID",
+        "/exchange-rates/import": {
+            "post": {
+                "description": "Принимает CSV с заголовком и колонками currency, month (MM-YYYY), rate.\nКурсы сохраняются все вместе или ни одного; курсы тех же валют и месяцев заменяются.\nОшибки перечисляются по полям с номером строки файла.\nТребует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).",
                 "consumes": [
-                    "application/json"
+                    "text/csv"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "exchange-rates"
+                ],
+                "summary": "Загрузка курсов валют из CSV",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
-                "summary": "Получение подписки",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID подписки",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "CSV-файл с курсами",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Данные подписки",
+                        "description": "Сохраненные курсы",
                         "schema": {
-                            "$ref": "#/definitions/entities.Subscriptions"
+                            "$ref": "#/definitions/entities.ExchangeRateListResponse"
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID",
+                        "description": "Некорректный CSV",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Подписка не найдена",
+                    "401": {
+                        "description": "Нет токена администратора или он неверный",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый тип содержимого",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/exchange-rates/{currency}/{month}": {
             "put": {
-                "description": "Обновляет информацию о существующей подписке",
+                "description": "Задает, сколько рублей стоит единица валюты в месяце month. Курс за тот же месяц заменяется.\nТребует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).",
                 "consumes": [
                     "application/json"
                 ],
@@ -245,59 +274,85 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "exchange-rates"
+                ],
+                "summary": "Установка курса валюты",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
-                "summary": "Обновление подписки",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID подписки",
-                        "name": "id",
+                        "enum": [
+                            "USD",
+                            "EUR",
+                            "KZT"
+                        ],
+                        "type": "string",
+                        "description": "Валюта",
+                        "name": "currency",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Новые данные подписки",
-                        "name": "subscription",
+                        "type": "string",
+                        "description": "Месяц (MM-YYYY)",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Курс: положительное десятичное число, не больше 8 знаков после точки",
+                        "name": "rate",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/entities.Subscriptions"
+                            "$ref": "#/definitions/public.ExchangeRateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Статус обновления",
+                        "description": "Сохраненный курс",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/entities.ExchangeRate"
                         }
                     },
                     "400": {
                         "description": "Ошибка в запросе",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Подписка не найдена",
+                    "401": {
+                        "description": "Нет токена администратора или он неверный",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Удаляет существующую подписку по её ID",
+                "description": "Удаляет курс за месяц: суммы этого месяца в валюте больше не пересчитываются.\nТребует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).",
                 "consumes": [
                     "application/json"
                 ],
@@ -305,14 +360,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "exchange-rates"
+                ],
+                "summary": "Удаление курса валюты",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
-                "summary": "Удаление подписки",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID подписки",
-                        "name": "id",
+                        "enum": [
+                            "USD",
+                            "EUR",
+                            "KZT"
+                        ],
+                        "type": "string",
+                        "description": "Валюта",
+                        "name": "currency",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Месяц (MM-YYYY)",
+                        "name": "month",
                         "in": "path",
                         "required": true
                     }
@@ -328,56 +400,2146 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID",
+                        "description": "Ошибка в запросе",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет токена администратора или он неверный",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
                     "404": {
-                        "description": "Подписка не найдена",
+                        "description": "Курс не найден",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "entities.Subscriptions": {
-            "type": "object",
-            "properties": {
-                "end_date": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "integer"
-                },
-                "service_name": {
-                    "type": "string"
-                },
-                "start_date": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "string"
-                }
-            }
         },
-        "entities.TotalCostResponse": {
-            "type": "object",
-            "properties": {
-                "total_cost": {
-                    "description": "Суммарная стоимость в рублях",
-                    "type": "integer"
-                }
-            }
+        "/subscriptions": {
+            "get": {
+                "description": "Получает список подписок с возможностью фильтрации, сортировки и постраничной выдачей по курсору.\nКурсор действителен только для той сортировки, с которой он получен.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Список подписок",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "ID пользователя (UUID), можно повторять",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Название сервиса (точное совпадение), можно повторять",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока названия сервиса без учета регистра",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата начала подписки не раньше (MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата окончания подписки не позже (MM-YYYY)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подписка действует в этом месяце (MM-YYYY)",
+                        "name": "active_on",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "RUB",
+                                "USD",
+                                "EUR",
+                                "KZT"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Валюты цены",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true - только с датой окончания, false - только бессрочные",
+                        "name": "has_end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "start_date",
+                        "description": "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор страницы из next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Посчитать общее количество подписок по фильтру",
+                        "name": "include_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница списка подписок",
+                        "schema": {
+                            "$ref": "#/definitions/entities.SubscriptionListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую и следующую страницы (RFC 8288)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создает новую запись о подписке пользователя.\nПовтор запроса с тем же Idempotency-Key возвращает сохраненный ответ и не создает новую подписку.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Создание подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности запроса (до 255 символов)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "id созданной подписки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true, если ответ повторен по Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт с существующими данными или запрос с тем же Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/batch": {
+            "post": {
+                "description": "Выполняет до HTTP_BATCH_LIMIT операций create, update и delete в одной транзакции.\nПри atomic=true ошибка любой операции откатывает весь пакет, остальные операции получают статус 424.\nПри atomic=false (по умолчанию) ошибка отменяет только свою операцию.\nversion в update и delete работает как If-Match. Ответ 200, если все операции выполнены, иначе 207.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Пакетное изменение подписок",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Выполнить пакет целиком или не выполнять совсем",
+                        "name": "atomic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности запроса (до 255 символов)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Операции пакета",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/public.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Все операции выполнены",
+                        "schema": {
+                            "$ref": "#/definitions/public.BatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Часть операций не выполнена, подробности в results",
+                        "schema": {
+                            "$ref": "#/definitions/public.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с тем же Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/cost": {
+            "get": {
+                "description": "Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией.\nbasis=cash (по умолчанию) считает списания в месяцах периода по цене, действовавшей в месяце списания.\nbasis=accrual распределяет каждое списание поровну между месяцами оплаченного им периода\nи учитывает части, пришедшиеся на месяцы выбранного периода.\nЦены в других валютах пересчитываются в валюту currency (по умолчанию RUB) по курсу месяца,\nна который пришлась сумма; использованные курсы перечисляются в rates. Курс другого месяца не подставляется:\nесли курса за месяц нет, запрос отклоняется с ошибкой поля currency.\nПри указании group_by вместо одной суммы возвращается список групп (entities.GroupedCost).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Расчет стоимости подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода (MM-YYYY)",
+                        "name": "start_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (MM-YYYY)",
+                        "name": "end_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cash",
+                            "accrual"
+                        ],
+                        "type": "string",
+                        "default": "cash",
+                        "description": "Метод учета: cash - по списаниям, accrual - по начислению",
+                        "name": "basis",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "RUB",
+                            "USD",
+                            "EUR",
+                            "KZT"
+                        ],
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Валюта результата",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "service_name",
+                                "user_id"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Поля группировки",
+                        "name": "group_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Суммарная стоимость",
+                        "schema": {
+                            "$ref": "#/definitions/entities.TotalCostResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/cost/breakdown": {
+            "get": {
+                "description": "Возвращает стоимость подписок за каждый календарный месяц выбранного периода и подписки, из которых она сложилась.\nПри basis=accrual месяц содержит доли списаний, оплативших этот месяц, а не сами списания.\nСуммы в других валютах пересчитываются в валюту currency по курсу своего месяца (см. /subscriptions/cost).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Помесячная стоимость подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода (MM-YYYY)",
+                        "name": "start_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (MM-YYYY), не более 120 месяцев от начала",
+                        "name": "end_period",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название сервиса",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cash",
+                            "accrual"
+                        ],
+                        "type": "string",
+                        "default": "cash",
+                        "description": "Метод учета: cash - по списаниям, accrual - по начислению",
+                        "name": "basis",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "RUB",
+                            "USD",
+                            "EUR",
+                            "KZT"
+                        ],
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Валюта результата",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Помесячная стоимость",
+                        "schema": {
+                            "$ref": "#/definitions/entities.CostBreakdownResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/export": {
+            "get": {
+                "description": "Выгружает все подписки по фильтру в формате CSV или NDJSON (по заголовку Accept) без постраничной разбивки.\nСтроки передаются клиенту по мере чтения из БД, поэтому объем выгрузки не ограничен памятью сервера.\nПоддерживает фильтры и сортировку списка подписок; limit, cursor и include_total не допускаются.\nВ CSV ячейки, начинающиеся с =, +, - или @, экранируются апострофом, чтобы табличный редактор не выполнил их как формулы.\nЕсли выгрузка прервалась после начала передачи, соединение закрывается без завершения ответа.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Выгрузка подписок",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "ID пользователя (UUID), можно повторять",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Название сервиса (точное совпадение), можно повторять",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока названия сервиса без учета регистра",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата начала подписки не раньше (MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата окончания подписки не позже (MM-YYYY)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подписка действует в этом месяце (MM-YYYY)",
+                        "name": "active_on",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "RUB",
+                                "USD",
+                                "EUR",
+                                "KZT"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Валюты цены",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true - только с датой окончания, false - только бессрочные",
+                        "name": "has_end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "start_date",
+                        "description": "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписки в формате CSV (с заголовком) или NDJSON (по объекту на строку)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Формат из Accept не поддерживается",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/import": {
+            "post": {
+                "description": "Принимает CSV с заголовком и колонками service_name, user_id, start_date, ценой в price (в основных единицах)\nили amount_minor (в минимальных единицах) и необязательными currency (по умолчанию RUB), end_date, billing_period (по умолчанию month), billing_anchor.\nКаждая строка проверяется по тем же правилам, что и при создании подписки. Принятые строки сохраняются\nодной операцией, отклоненные перечисляются в отчете с номером строки файла и причинами.\nПри dry_run=true ничего не сохраняется, отчет показывает, что произойдет при импорте.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Импорт подписок из CSV",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Только проверить файл, ничего не сохраняя",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "CSV-файл с подписками",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчет об импорте",
+                        "schema": {
+                            "$ref": "#/definitions/entities.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный CSV или параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый тип содержимого",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/trash": {
+            "get": {
+                "description": "Получает удаленные подписки, которые еще можно восстановить. Фильтры, сортировка и пагинация как у списка подписок.\nПодписки удаляются из корзины окончательно по истечении срока хранения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Корзина подписок",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "ID пользователя (UUID), можно повторять",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Название сервиса (точное совпадение), можно повторять",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока названия сервиса без учета регистра",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата начала подписки не раньше (MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата окончания подписки не позже (MM-YYYY)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подписка действует в этом месяце (MM-YYYY)",
+                        "name": "active_on",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "RUB",
+                                "USD",
+                                "EUR",
+                                "KZT"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Валюты цены",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true - только с датой окончания, false - только бессрочные",
+                        "name": "has_end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "start_date",
+                        "description": "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор страницы из next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Посчитать общее количество подписок в корзине по фильтру",
+                        "name": "include_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница корзины",
+                        "schema": {
+                            "$ref": "#/definitions/entities.SubscriptionListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую и следующую страницы (RFC 8288)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}": {
+            "get": {
+                "description": "Получает детальную информацию о подписке по её ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Получение подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Данные подписки",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия подписки для If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Обновляет информацию о существующей подписке",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Обновление подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из GET; изменение выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новые данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Статус обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт с существующими данными",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Переносит подписку в корзину: она пропадает из списка и расчета стоимости.\nДо окончания срока хранения корзины подписку можно восстановить через POST /subscriptions/{id}/restore.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Удаление подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из GET; изменение выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Статус удаления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновляет только переданные поля подписки (JSON Merge Patch, RFC 7396).\n\"end_date\": null очищает дату окончания, \"billing_anchor\": null возвращает отсчет периодов оплаты\nот start_date, остальные поля не могут быть null.\nПравила подписки проверяются для результата, например новая end_date сверяется с сохраненной start_date.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Частичное обновление подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из GET; изменение выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Изменяемые поля подписки",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после обновления",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт с существующими данными",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый тип содержимого",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/history": {
+            "get": {
+                "description": "Возвращает изменения подписки в порядке их выполнения: кто, когда и в каком запросе изменил подписку,\nсо снимками до и после изменения. История сохраняется и после очистки подписки из корзины.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "История подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не более 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор страницы из next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница истории",
+                        "schema": {
+                            "$ref": "#/definitions/entities.EventListResponse"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки на первую и следующую страницы (RFC 8288)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в параметрах запроса",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/prices": {
+            "get": {
+                "description": "Возвращает цены подписки по месяцам, с которых они действуют: первая запись - цена с начала подписки,\nследующие - запланированные изменения. Стоимость каждого месяца считается по цене, действовавшей в нем.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "История цен подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "История цен",
+                        "schema": {
+                            "$ref": "#/definitions/entities.PriceListResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия подписки для If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Задает цену подписки начиная с месяца effective_from, не меняя стоимость предыдущих месяцев.\nЦена передается в price (в основных единицах) или amount_minor (в минимальных единицах валюты подписки).\nМесяц должен быть позже start_date и не позже end_date. Изменение с того же месяца заменяется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Изменение цены подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из GET; изменение выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новая цена и месяц, с которого она действует",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entities.PriceChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/prices/{month}": {
+            "delete": {
+                "description": "Удаляет изменение цены с указанного месяца: с него снова действует предыдущая цена",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Отмена изменения цены",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Месяц изменения цены (MM-YYYY)",
+                        "name": "month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из GET; изменение выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ошибка в запросе",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка или изменение цены не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/restore": {
+            "post": {
+                "description": "Возвращает удаленную подписку из корзины в список и расчет стоимости",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Восстановление подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки из корзины; восстановление выполняется, только если версия не изменилась",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Восстановленная подписка",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Subscriptions"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия подписки"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписки нет в корзине",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Подписка изменена после получения ETag",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Требуется заголовок If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Хранилище недоступно",
+                        "schema": {
+                            "$ref": "#/definitions/public.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "entities.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ],
+                    "example": "create"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/entities.Subscriptions"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "entities.CostBreakdownResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Валюта стоимости",
+                    "type": "string"
+                },
+                "months": {
+                    "description": "Стоимость по каждому календарному месяцу периода",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.MonthlyCost"
+                    }
+                },
+                "rates": {
+                    "description": "Курсы, по которым пересчитаны цены в других валютах",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.ExchangeRate"
+                    }
+                },
+                "total_cost": {
+                    "description": "Суммарная стоимость за весь период в основных единицах Currency",
+                    "type": "integer"
+                },
+                "total_cost_minor": {
+                    "description": "Суммарная стоимость за весь период в минимальных единицах Currency",
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.EventListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "События страницы",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.SubscriptionEvent"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Курсор следующей страницы, отсутствует на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "entities.ExchangeRate": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "month": {
+                    "type": "string",
+                    "example": "03-2025"
+                },
+                "rate": {
+                    "type": "string",
+                    "example": "92.5"
+                }
+            }
+        },
+        "entities.ExchangeRateListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.ExchangeRate"
+                    }
+                }
+            }
+        },
+        "entities.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "start_date"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "expected format MM-YYYY"
+                }
+            }
+        },
+        "entities.ImportReport": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.ImportedRow"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.RejectedRow"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "entities.ImportedRow": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "entities.MonthlyCost": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "description": "Месяц в формате MM-YYYY",
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "description": "Подписки, вошедшие в стоимость месяца",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.SubscriptionCost"
+                    }
+                },
+                "total_cost": {
+                    "description": "Стоимость за месяц в основных единицах",
+                    "type": "integer"
+                },
+                "total_cost_minor": {
+                    "description": "Стоимость за месяц в минимальных единицах",
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.PriceChange": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "integer",
+                    "example": 129900
+                },
+                "effective_from": {
+                    "type": "string",
+                    "example": "03-2026"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 1299
+                }
+            }
+        },
+        "entities.PriceListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.PriceChange"
+                    }
+                }
+            }
+        },
+        "entities.RejectedRow": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.FieldError"
+                    }
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "entities.SubscriptionCost": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "description": "Стоимость в основных единицах валюты ответа",
+                    "type": "integer"
+                },
+                "cost_minor": {
+                    "description": "Стоимость в минимальных единицах валюты ответа",
+                    "type": "integer"
+                },
+                "service_name": {
+                    "description": "Название сервиса",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "ID пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "entities.SubscriptionEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "restore"
+                    ]
+                },
+                "actor": {
+                    "description": "Кто выполнил изменение",
+                    "type": "string"
+                },
+                "after": {
+                    "$ref": "#/definitions/entities.Subscriptions"
+                },
+                "before": {
+                    "$ref": "#/definitions/entities.Subscriptions"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "description": "ID запроса, в котором выполнено изменение",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.SubscriptionListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Подписки страницы",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Subscriptions"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Курсор следующей страницы, отсутствует на последней",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Количество подписок по фильтру, если передан include_total=true",
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Subscriptions": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "integer",
+                    "example": 99900
+                },
+                "billing_anchor": {
+                    "type": "string"
+                },
+                "billing_period": {
+                    "type": "string",
+                    "enum": [
+                        "month",
+                        "quarter",
+                        "year",
+                        "week"
+                    ]
+                },
+                "created_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "currency": {
+                    "type": "string",
+                    "enum": [
+                        "RUB",
+                        "USD",
+                        "EUR",
+                        "KZT"
+                    ]
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "monthly_amount_minor": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "monthly_price": {
+                    "type": "integer",
+                    "readOnly": true
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 999
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.PriceChange"
+                    },
+                    "readOnly": true
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "readOnly": true
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "readOnly": true
+                }
+            }
+        },
+        "entities.TotalCostResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Валюта стоимости",
+                    "type": "string"
+                },
+                "rates": {
+                    "description": "Курсы, по которым пересчитаны цены в других валютах",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.ExchangeRate"
+                    }
+                },
+                "subscription_months": {
+                    "description": "Сумма месяцев периода, в которых действовала каждая подписка",
+                    "type": "integer"
+                },
+                "total_cost": {
+                    "description": "Суммарная стоимость в основных единицах Currency (целая часть)",
+                    "type": "integer"
+                },
+                "total_cost_minor": {
+                    "description": "Суммарная стоимость в минимальных единицах Currency",
+                    "type": "integer"
+                }
+            }
+        },
+        "public.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/public.Problem"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "op": {
+                    "type": "string",
+                    "example": "create"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 201
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "public.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.BatchOperation"
+                    }
+                }
+            }
+        },
+        "public.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/public.BatchItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "public.ExchangeRateRequest": {
+            "type": "object",
+            "properties": {
+                "rate": {
+                    "type": "string",
+                    "example": "92.5"
+                }
+            }
+        },
+        "public.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "invalid request body"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/subscriptions"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "host/abcdef-000001"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/validation-error"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer)",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,80 @@
+package public
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"tz_effective/internal/entities"
+)
+
+// errPreconditionRequired If-Match не передан, хотя сервер требует условные изменения
+var errPreconditionRequired = errors.New("If-Match header is required")
+
+// etag возвращает сильный ETag (RFC 9110, 8.8.3) для версии подписки
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// maxIfMatchTags ограничивает число ETag в If-Match: каждый из них проверяется отдельным обращением к хранилищу
+const maxIfMatchTags = 16
+
+// ifMatchVersions возвращает версии подписки из сильных ETag заголовка If-Match (RFC 9110, 13.1.1)
+func (s *Server) ifMatchVersions(r *http.Request) ([]int64, error) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" {
+		if s.cfg != nil && s.cfg.HTTPServer.RequireIfMatch {
+			return nil, errPreconditionRequired
+		}
+		return []int64{entities.AnyVersion}, nil
+	}
+	if header == "*" {
+		return []int64{entities.AnyVersion}, nil
+	}
+
+	tags := strings.Split(header, ",")
+	if len(tags) > maxIfMatchTags {
+		return nil, &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "If-Match", Message: "must contain at most " + strconv.Itoa(maxIfMatchTags) + " entity tags"},
+		}}
+	}
+
+	var versions []int64
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil || version <= 0 || slices.Contains(versions, version) {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, entities.ErrVersionMismatch
+	}
+	return versions, nil
+}
+
+// matchVersion выполняет apply с версиями из If-Match по очереди, пока одна из них не совпадет с текущей.
+// Хранилище сверяет версию при записи, поэтому изменение применяется не больше одного раза.
+func matchVersion(versions []int64, apply func(version int64) error) error {
+	err := entities.ErrVersionMismatch
+	for _, version := range versions {
+		if err = apply(version); !errors.Is(err, entities.ErrVersionMismatch) {
+			return err
+		}
+	}
+	return err
+}
+
+// respondPreconditionError отвечает на ошибку разбора If-Match
+func respondPreconditionError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errPreconditionRequired) {
+		RespondWithError(w, r, http.StatusPreconditionRequired, err.Error())
+		return
+	}
+	RespondWithServiceError(w, r, err, "invalid If-Match header")
+}
@@ -0,0 +1,239 @@
+package public
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// exportFormats форматы выгрузки; при Accept: */* выбирается первый
+var exportFormats = []string{csvContentType, ndjsonContentType}
+
+// exportColumns колонки CSV выгрузки, совпадают с полями JSON подписки
+var exportColumns = []string{"id", "service_name", "price", "amount_minor", "currency", "user_id", "start_date", "end_date",
+	"billing_period", "billing_anchor", "monthly_price", "monthly_amount_minor", "version", "created_at", "updated_at"}
+
+// ExportSubscriptions выгружает подписки по фильтру потоком
+// @Summary Выгрузка подписок
+// @Description Выгружает все подписки по фильтру в формате CSV или NDJSON (по заголовку Accept) без постраничной разбивки.
+// @Description Строки передаются клиенту по мере чтения из БД, поэтому объем выгрузки не ограничен памятью сервера.
+// @Description Поддерживает фильтры и сортировку списка подписок; limit, cursor и include_total не допускаются.
+// @Description В CSV ячейки, начинающиеся с =, +, - или @, экранируются апострофом, чтобы табличный редактор не выполнил их как формулы.
+// @Description Если выгрузка прервалась после начала передачи, соединение закрывается без завершения ответа.
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param user_id query []string false "ID пользователя (UUID), можно повторять" collectionFormat(multi)
+// @Param service_name query []string false "Название сервиса (точное совпадение), можно повторять" collectionFormat(multi)
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param start_date query string false "Дата начала подписки не раньше (MM-YYYY)"
+// @Param end_date query string false "Дата окончания подписки не позже (MM-YYYY)"
+// @Param active_on query string false "Подписка действует в этом месяце (MM-YYYY)"
+// @Param currency query []string false "Валюты цены" collectionFormat(csv) Enums(RUB, USD, EUR, KZT)
+// @Param min_price query int false "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param max_price query int false "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param has_end_date query bool false "true - только с датой окончания, false - только бессрочные"
+// @Param sort query string false "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)" default(start_date)
+// @Success 200 {string} string "Подписки в формате CSV (с заголовком) или NDJSON (по объекту на строку)"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 406 {object} Problem "Формат из Accept не поддерживается"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/export [get]
+func (s *Server) ExportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	format := negotiate(r.Header.Get("Accept"), exportFormats)
+	if format == "" {
+		RespondWithError(w, r, http.StatusNotAcceptable, "supported formats: "+strings.Join(exportFormats, ", "))
+		return
+	}
+
+	var unsupported []entities.FieldError
+	for _, name := range []string{"limit", "cursor", "include_total"} {
+		if r.URL.Query().Has(name) {
+			unsupported = append(unsupported, entities.FieldError{Field: name, Message: "is not supported by export"})
+		}
+	}
+	if len(unsupported) > 0 {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid query parameters", unsupported...)
+		return
+	}
+
+	filter, err := parseListFilter(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	// Выгрузка может идти дольше HTTP_TIMEOUT, ограничение записи для нее снимается
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("Failed to reset write deadline", "error", err)
+	}
+
+	enc := newExportEncoder(w, format)
+	started := false
+	err = s.Service.ExportSubscriptions(r.Context(), filter, func(sub *entities.Subscriptions) error {
+		if !started {
+			enc.start()
+			started = true
+		}
+		return enc.write(sub)
+	})
+	if err != nil {
+		if !started {
+			RespondWithServiceError(w, r, err, "failed to export subscriptions")
+			return
+		}
+		// Статус уже отправлен: обрыв соединения не дает клиенту принять неполную выгрузку за полную
+		slog.Error("Export interrupted", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if !started {
+		enc.start()
+	}
+	if err := enc.flush(); err != nil {
+		slog.Error("Failed to finish export", "error", err)
+	}
+}
+
+// exportEncoder пишет подписки в тело ответа в выбранном формате
+type exportEncoder struct {
+	w      http.ResponseWriter
+	format string
+	csv    *csv.Writer
+	json   *json.Encoder
+}
+
+func newExportEncoder(w http.ResponseWriter, format string) *exportEncoder {
+	enc := &exportEncoder{w: w, format: format}
+	if format == csvContentType {
+		enc.csv = csv.NewWriter(w)
+	} else {
+		enc.json = json.NewEncoder(w)
+	}
+	return enc
+}
+
+// start отправляет заголовки ответа и, для CSV, строку с названиями колонок
+func (e *exportEncoder) start() {
+	e.w.Header().Set("Content-Type", e.format+"; charset=utf-8")
+	if e.format == csvContentType {
+		e.w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+	}
+	e.w.WriteHeader(http.StatusOK)
+	if e.csv != nil {
+		_ = e.csv.Write(exportColumns)
+	}
+}
+
+func (e *exportEncoder) write(sub *entities.Subscriptions) error {
+	if e.json != nil {
+		return e.json.Encode(sub)
+	}
+
+	endDate, billingAnchor := "", ""
+	if sub.EndDate != nil {
+		endDate = *sub.EndDate
+	}
+	if sub.BillingAnchor != nil {
+		billingAnchor = *sub.BillingAnchor
+	}
+	record := []string{
+		strconv.FormatInt(sub.ID, 10),
+		sub.ServiceName,
+		strconv.FormatInt(sub.Price, 10),
+		strconv.FormatInt(sub.AmountMinor, 10),
+		sub.CurrencyCode(),
+		sub.UserID,
+		sub.StartDate,
+		endDate,
+		sub.Billing(),
+		billingAnchor,
+		strconv.FormatInt(sub.MonthlyPrice, 10),
+		strconv.FormatInt(sub.MonthlyAmountMinor, 10),
+		strconv.FormatInt(sub.Version, 10),
+		sub.CreatedAt.Format(time.RFC3339Nano),
+		sub.UpdatedAt.Format(time.RFC3339Nano),
+	}
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return e.csv.Write(record)
+}
+
+func (e *exportEncoder) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// escapeFormula экранирует апострофом ячейку, которую табличный редактор примет за формулу
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// negotiate выбирает из offers тип с наибольшим весом q в заголовке Accept (RFC 9110, 12.5.1).
+// Пустой Accept допускает любой тип. Возвращает "", если ни один тип не подходит.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality возвращает вес offer по самому точному подходящему диапазону из Accept
+func acceptQuality(accept, offer string) float64 {
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		var s int
+		switch mediaType {
+		case offer:
+			s = 2
+		case offer[:strings.Index(offer, "/")] + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		specificity, quality = s, 1
+		if v, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil {
+				quality = q
+			}
+		}
+	}
+	return quality
+}
@@ -0,0 +1,117 @@
+package public
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestExportSubscriptions(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	endDate := "12-2025"
+	for _, sub := range []entities.Subscriptions{
+		{ServiceName: "Netflix", Price: 999, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "07-2025"},
+		{ServiceName: "Spotify, Family", Price: 299, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "01-2025", EndDate: &endDate},
+		{ServiceName: "YouTube", Price: 349, UserID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", StartDate: "03-2025"},
+		{ServiceName: "=HYPERLINK(\"http://evil\")", Price: 1, UserID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", StartDate: "04-2025"},
+	} {
+		sub := sub
+		if _, err := svc.CreateSubscription(context.Background(), &sub); err != nil {
+			t.Fatalf("CreateSubscription: %v", err)
+		}
+	}
+
+	router := chi.NewRouter()
+	router.Get("/subscriptions/export", (&Server{Service: svc}).ExportSubscriptions)
+	get := func(query, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions/export"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("?user_id=60601fee-2bf1-4721-ae6f-7636e79a0cba", "text/csv")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("CSV export: got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Fatalf("CSV export = %v", records)
+	}
+	if records[1][1] != "Spotify, Family" || records[1][7] != "12-2025" || records[2][1] != "Netflix" || records[2][7] != "" {
+		t.Errorf("CSV rows = %v, want Spotify then Netflix", records[1:])
+	}
+
+	rec = get("?sort=-price", "application/json;q=0.5, application/x-ndjson")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), ndjsonContentType) {
+		t.Fatalf("NDJSON export: got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var prices []int64
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var sub entities.Subscriptions
+		if err := json.Unmarshal(scanner.Bytes(), &sub); err != nil {
+			t.Fatalf("decode NDJSON line %q: %v", scanner.Text(), err)
+		}
+		prices = append(prices, sub.Price)
+	}
+	if len(prices) != 4 || prices[0] != 999 || prices[2] != 299 {
+		t.Errorf("NDJSON prices = %v, want [999 349 299 1]", prices)
+	}
+
+	// Формулы экранируются только в CSV, NDJSON отдает название как есть
+	rec = get("?user_id=f47ac10b-58cc-4372-a567-0e02b2c3d479&sort=-start_date", "text/csv")
+	if records, err = csv.NewReader(rec.Body).ReadAll(); err != nil || len(records) != 3 || records[1][1] != `'=HYPERLINK("http://evil")` {
+		t.Errorf("CSV formula cell = %v, %v", records, err)
+	}
+	rec = get("?service_name="+url.QueryEscape(`=HYPERLINK("http://evil")`), ndjsonContentType)
+	if !strings.Contains(rec.Body.String(), `"service_name":"=HYPERLINK(\"http://evil\")"`) {
+		t.Errorf("NDJSON formula cell = %s", rec.Body)
+	}
+
+	if rec := get("?service_name=Missing", ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != strings.Join(exportColumns, ",") {
+		t.Errorf("empty CSV export: got %d %q", rec.Code, rec.Body)
+	}
+	if rec := get("", "application/json"); rec.Code != http.StatusNotAcceptable {
+		t.Errorf("JSON export: got %d, want %d", rec.Code, http.StatusNotAcceptable)
+	}
+	if rec := get("?limit=10", "text/csv"); rec.Code != http.StatusBadRequest {
+		t.Errorf("export with limit: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: csvContentType},
+		{accept: "*/*", want: csvContentType},
+		{accept: "application/*", want: ndjsonContentType},
+		{accept: "text/csv;q=0.2, application/x-ndjson;q=0.8", want: ndjsonContentType},
+		{accept: "text/*;q=0.9, text/csv;q=0", want: ""},
+		{accept: "application/json", want: ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.accept, exportFormats); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
@@ -2,53 +2,51 @@ package public
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5"
-	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"tz_effective/internal/entities"
 	"tz_effective/internal/ports/http/public/utils"
 )
 
 // CreateSubscription создает новую запись о подписке
 // @Summary Создание подписки
-// @Description Создает новую запись о подписке пользователя
+// @Description Создает новую запись о подписке пользователя.
+// @Description Повтор запроса с тем же Idempotency-Key возвращает сохраненный ответ и не создает новую подписку.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Ключ идемпотентности запроса (до 255 символов)"
 // @Param subscription body entities.Subscriptions true "Данные подписки"
 // @Success 201 {object} map[string]int64 "id созданной подписки"
-// @Failure 400 {string} string "Ошибка в запросе"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Header 201 {string} Idempotent-Replayed "true, если ответ повторен по Idempotency-Key"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 409 {object} Problem "Конфликт с существующими данными или запрос с тем же Idempotency-Key еще выполняется"
+// @Failure 422 {object} Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions [post]
 func (s *Server) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	var sub entities.Subscriptions
 	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid request body")
+		RespondWithError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
 		return
 	}
 
-	if err := utils.ValidateUUID(sub.UserID); err != nil {
-		RespondWithError(w, http.StatusBadRequest, err.Error())
+	if err := utils.ValidateSubscription(&sub); err != nil {
+		RespondWithServiceError(w, r, err, "invalid subscription")
 		return
 	}
 
-	if err := utils.ValidateDate(sub.StartDate); err != nil {
-		RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	if sub.EndDate != nil {
-		if err := utils.ValidateDate(*sub.EndDate); err != nil {
-			RespondWithError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-	}
-
 	id, err := s.Service.CreateSubscription(r.Context(), &sub)
 	if err != nil {
-		slog.Error("Failed to create subscription", "error", err)
-		RespondWithError(w, http.StatusInternalServerError, "failed to create subscription")
+		RespondWithServiceError(w, r, err, "failed to create subscription")
 		return
 	}
 	RespondWithJSON(w, http.StatusCreated, map[string]int64{"id": id})
@@ -62,21 +60,26 @@ func (s *Server) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "ID подписки"
 // @Success 200 {object} entities.Subscriptions "Данные подписки"
-// @Failure 400 {string} string "Некорректный ID"
-// @Failure 404 {string} string "Подписка не найдена"
+// @Header 200 {string} ETag "Версия подписки для If-Match"
+// @Failure 400 {object} Problem "Некорректный ID"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions/{id} [get]
 func (s *Server) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
 		return
 	}
 	sub, err := s.Service.GetSubscription(r.Context(), id)
 	if err != nil {
-		RespondWithError(w, http.StatusNotFound, "subscription not found")
+		RespondWithServiceError(w, r, err, "failed to get subscription")
 		return
 	}
+	w.Header().Set("ETag", etag(sub.Version))
 	RespondWithJSON(w, http.StatusOK, sub)
 }
 
@@ -87,71 +90,159 @@ func (s *Server) GetSubscription(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag подписки из GET; изменение выполняется, только если версия не изменилась"
 // @Param subscription body entities.Subscriptions true "Новые данные подписки"
 // @Success 200 {object} map[string]string "Статус обновления"
-// @Failure 400 {string} string "Ошибка в запросе"
-// @Failure 404 {string} string "Подписка не найдена"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 409 {object} Problem "Конфликт с существующими данными"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions/{id} [put]
 func (s *Server) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
 		return
 	}
 	var sub entities.Subscriptions
 	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid request body")
+		RespondWithError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if err := utils.ValidateSubscription(&sub); err != nil {
+		RespondWithServiceError(w, r, err, "invalid subscription")
 		return
 	}
 
-	if err := utils.ValidateUUID(sub.UserID); err != nil {
-		RespondWithError(w, http.StatusBadRequest, err.Error())
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
 		return
 	}
 
-	if err := utils.ValidateDate(sub.StartDate); err != nil {
-		RespondWithError(w, http.StatusBadRequest, err.Error())
+	var newVersion int64
+	err = matchVersion(versions, func(version int64) (err error) {
+		newVersion, err = s.Service.UpdateSubscription(r.Context(), id, &sub, version)
+		return err
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to update subscription")
 		return
 	}
+	w.Header().Set("ETag", etag(newVersion))
+	RespondWithJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
 
-	if sub.EndDate != nil {
-		if err := utils.ValidateDate(*sub.EndDate); err != nil {
-			RespondWithError(w, http.StatusBadRequest, err.Error())
+// PatchSubscription частично обновляет подписку
+// @Summary Частичное обновление подписки
+// @Description Обновляет только переданные поля подписки (JSON Merge Patch, RFC 7396).
+// @Description "end_date": null очищает дату окончания, "billing_anchor": null возвращает отсчет периодов оплаты
+// @Description от start_date, остальные поля не могут быть null.
+// @Description Правила подписки проверяются для результата, например новая end_date сверяется с сохраненной start_date.
+// @Tags subscriptions
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag подписки из GET; изменение выполняется, только если версия не изменилась"
+// @Param patch body entities.Subscriptions true "Изменяемые поля подписки"
+// @Success 200 {object} entities.Subscriptions "Подписка после обновления"
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 409 {object} Problem "Конфликт с существующими данными"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 415 {object} Problem "Неподдерживаемый тип содержимого"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id} [patch]
+func (s *Server) PatchSubscription(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			RespondWithError(w, r, http.StatusUnsupportedMediaType,
+				"expected application/merge-patch+json or application/json")
 			return
 		}
 	}
 
-	if err := s.Service.UpdateSubscription(r.Context(), id, &sub); err != nil {
-		slog.Error("Failed to update subscription", "error", err)
-		RespondWithError(w, http.StatusInternalServerError, "failed to update subscription")
+	patch, err := parseMergePatch(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid patch")
+		return
+	}
+
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
 		return
 	}
-	RespondWithJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+
+	var sub *entities.Subscriptions
+	err = matchVersion(versions, func(version int64) (err error) {
+		sub, err = s.Service.PatchSubscription(r.Context(), id, patch, version)
+		return err
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to patch subscription")
+		return
+	}
+	w.Header().Set("ETag", etag(sub.Version))
+	RespondWithJSON(w, http.StatusOK, sub)
 }
 
 // DeleteSubscription удаляет подписку по ID
 // @Summary Удаление подписки
-// @Description Удаляет существующую подписку по её ID
+// @Description Переносит подписку в корзину: она пропадает из списка и расчета стоимости.
+// @Description До окончания срока хранения корзины подписку можно восстановить через POST /subscriptions/{id}/restore.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag подписки из GET; изменение выполняется, только если версия не изменилась"
 // @Success 204 {object} map[string]string "Статус удаления"
-// @Failure 400 {string} string "Некорректный ID"
-// @Failure 404 {string} string "Подписка не найдена"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} Problem "Некорректный ID"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions/{id} [delete]
 func (s *Server) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid id")
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
 		return
 	}
-	if err := s.Service.DeleteSubscription(r.Context(), id); err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "failed to delete subscription")
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
+		return
+	}
+
+	err = matchVersion(versions, func(version int64) error {
+		return s.Service.DeleteSubscription(r.Context(), id, version)
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to delete subscription")
 		return
 	}
 	RespondWithJSON(w, http.StatusNoContent, map[string]string{"status": "deleted"})
@@ -159,43 +250,86 @@ func (s *Server) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 
 // ListSubscriptions возвращает список подписок с фильтрацией
 // @Summary Список подписок
-// @Description Получает список подписок с возможностью фильтрации
+// @Description Получает список подписок с возможностью фильтрации, сортировки и постраничной выдачей по курсору.
+// @Description Курсор действителен только для той сортировки, с которой он получен.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param user_id query string false "ID пользователя (UUID)"
-// @Param service_name query string false "Название сервиса"
-// @Param start_date query string false "Дата начала подписки (MM-YYYY)"
-// @Param end_date query string false "Дата окончания подписки (MM-YYYY)"
-// @Success 200 {array} entities.Subscriptions "Список подписок"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Param user_id query []string false "ID пользователя (UUID), можно повторять" collectionFormat(multi)
+// @Param service_name query []string false "Название сервиса (точное совпадение), можно повторять" collectionFormat(multi)
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param start_date query string false "Дата начала подписки не раньше (MM-YYYY)"
+// @Param end_date query string false "Дата окончания подписки не позже (MM-YYYY)"
+// @Param active_on query string false "Подписка действует в этом месяце (MM-YYYY)"
+// @Param currency query []string false "Валюты цены" collectionFormat(csv) Enums(RUB, USD, EUR, KZT)
+// @Param min_price query int false "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param max_price query int false "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param has_end_date query bool false "true - только с датой окончания, false - только бессрочные"
+// @Param sort query string false "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)" default(start_date)
+// @Param limit query int false "Размер страницы (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор страницы из next_cursor предыдущего ответа"
+// @Param include_total query bool false "Посчитать общее количество подписок по фильтру"
+// @Success 200 {object} entities.SubscriptionListResponse "Страница списка подписок"
+// @Header 200 {string} Link "Ссылки на первую и следующую страницы (RFC 8288)"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions [get]
 func (s *Server) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
-	filter := entities.ListFilter{}
-	if v := r.URL.Query().Get("user_id"); v != "" {
-		filter.UserID = &v
+	filter, err := parseListFilter(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
 	}
-	if v := r.URL.Query().Get("service_name"); v != "" {
-		filter.ServiceName = &v
+
+	page, err := s.Service.ListSubscriptions(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to list subscriptions")
+		return
 	}
-	if v := r.URL.Query().Get("start_date"); v != "" {
-		filter.StartDate = &v
+
+	respondWithPage(w, r, page)
+}
+
+// respondWithPage отвечает страницей списка подписок со ссылками на соседние страницы
+func respondWithPage(w http.ResponseWriter, r *http.Request, page *entities.SubscriptionPage) {
+	res := entities.SubscriptionListResponse{
+		Items: page.Items,
+		Total: page.Total,
 	}
-	if v := r.URL.Query().Get("end_date"); v != "" {
-		filter.EndDate = &v
+	if page.Next != nil {
+		res.NextCursor = entities.EncodeCursor(page.Next)
 	}
 
-	subs, err := s.Service.ListSubscriptions(r.Context(), &filter)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "failed to list subscriptions")
-		return
+	w.Header().Set("Link", pageLinks(r, res.NextCursor))
+	RespondWithJSON(w, http.StatusOK, res)
+}
+
+// pageLinks формирует заголовок Link (RFC 8288) со ссылками на первую и, если она есть, следующую страницу.
+// Ссылки сохраняют фильтры и размер страницы исходного запроса.
+func pageLinks(r *http.Request, nextCursor string) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String())}
+
+	if nextCursor != "" {
+		query.Set("cursor", nextCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()))
 	}
-	RespondWithJSON(w, http.StatusOK, subs)
+
+	return strings.Join(links, ", ")
 }
 
 // CalculateTotalCost рассчитывает суммарную стоимость подписок за период
 // @Summary Расчет стоимости подписок
-// @Description Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией
+// @Description Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией.
+// @Description basis=cash (по умолчанию) считает списания в месяцах периода по цене, действовавшей в месяце списания.
+// @Description basis=accrual распределяет каждое списание поровну между месяцами оплаченного им периода
+// @Description и учитывает части, пришедшиеся на месяцы выбранного периода.
+// @Description Цены в других валютах пересчитываются в валюту currency (по умолчанию RUB) по курсу месяца,
+// @Description на который пришлась сумма; использованные курсы перечисляются в rates. Курс другого месяца не подставляется:
+// @Description если курса за месяц нет, запрос отклоняется с ошибкой поля currency.
+// @Description При указании group_by вместо одной суммы возвращается список групп (entities.GroupedCost).
 // @Tags subscriptions
 // @Accept json
 // @Produce json
@@ -203,38 +337,311 @@ func (s *Server) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 // @Param end_period query string true "Конец периода (MM-YYYY)"
 // @Param user_id query string false "ID пользователя (UUID)"
 // @Param service_name query string false "Название сервиса"
+// @Param basis query string false "Метод учета: cash - по списаниям, accrual - по начислению" Enums(cash, accrual) default(cash)
+// @Param currency query string false "Валюта результата" Enums(RUB, USD, EUR, KZT) default(RUB)
+// @Param group_by query []string false "Поля группировки" collectionFormat(csv) Enums(service_name, user_id)
 // @Success 200 {object} entities.TotalCostResponse "Суммарная стоимость"
-// @Failure 400 {string} string "Ошибка в параметрах запроса"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
 // @Router /subscriptions/cost [get]
 func (s *Server) CalculateTotalCost(w http.ResponseWriter, r *http.Request) {
-	startPeriod := r.URL.Query().Get("start_period")
-	endPeriod := r.URL.Query().Get("end_period")
+	filter, err := parseCostFilter(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
 
-	if startPeriod == "" || endPeriod == "" {
-		RespondWithError(w, http.StatusBadRequest, "start_period and end_period are required")
+	groupBy, err := parseGroupBy(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
 		return
 	}
 
-	if err := utils.ValidateDate(startPeriod); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid start_period format: "+err.Error())
+	if len(groupBy) > 0 {
+		groups, err := s.Service.CalculateGroupedCost(r.Context(), filter, groupBy)
+		if err != nil {
+			RespondWithServiceError(w, r, err, "failed to calculate total cost")
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, groups)
 		return
 	}
-	if err := utils.ValidateDate(endPeriod); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "invalid end_period format: "+err.Error())
+
+	totalCost, err := s.Service.CalculateTotalCost(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to calculate total cost")
 		return
 	}
 
+	RespondWithJSON(w, http.StatusOK, totalCost)
+}
+
+// CalculateCostBreakdown рассчитывает стоимость подписок по месяцам периода
+// @Summary Помесячная стоимость подписок
+// @Description Возвращает стоимость подписок за каждый календарный месяц выбранного периода и подписки, из которых она сложилась.
+// @Description При basis=accrual месяц содержит доли списаний, оплативших этот месяц, а не сами списания.
+// @Description Суммы в других валютах пересчитываются в валюту currency по курсу своего месяца (см. /subscriptions/cost).
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param start_period query string true "Начало периода (MM-YYYY)"
+// @Param end_period query string true "Конец периода (MM-YYYY), не более 120 месяцев от начала"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса"
+// @Param basis query string false "Метод учета: cash - по списаниям, accrual - по начислению" Enums(cash, accrual) default(cash)
+// @Param currency query string false "Валюта результата" Enums(RUB, USD, EUR, KZT) default(RUB)
+// @Success 200 {object} entities.CostBreakdownResponse "Помесячная стоимость"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/cost/breakdown [get]
+func (s *Server) CalculateCostBreakdown(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseCostFilter(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	breakdown, err := s.Service.CalculateCostBreakdown(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to calculate cost breakdown")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, breakdown)
+}
+
+// parseMergePatch разбирает тело JSON Merge Patch подписки и проверяет формат переданных полей.
+// Ошибки полей возвращаются как *entities.ValidationError.
+func parseMergePatch(r *http.Request) (*entities.SubscriptionPatch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("%w: request body must be a JSON object: %w", entities.ErrValidation, err)
+	}
+
+	verr := &entities.ValidationError{}
+	patch := &entities.SubscriptionPatch{}
+
+	// decode разбирает значение поля; null допустим только там, где allowNull
+	decode := func(name string, raw json.RawMessage, dst interface{}, allowNull bool) bool {
+		if string(raw) == "null" {
+			if !allowNull {
+				verr.Add(name, "must not be null")
+			}
+			return false
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			verr.Add(name, "invalid value")
+			return false
+		}
+		return true
+	}
+
+	for name, raw := range fields {
+		switch name {
+		case "service_name":
+			var v string
+			if decode(name, raw, &v, false) {
+				patch.ServiceName = &v
+			}
+		case "price":
+			var v int64
+			if decode(name, raw, &v, false) {
+				patch.Price = &v
+			}
+		case "amount_minor":
+			var v int64
+			if decode(name, raw, &v, false) {
+				patch.AmountMinor = &v
+			}
+		case "currency":
+			var v string
+			if decode(name, raw, &v, false) {
+				patch.Currency = &v
+			}
+		case "user_id":
+			var v string
+			if decode(name, raw, &v, false) {
+				utils.CheckUUID(verr, name, v)
+				patch.UserID = &v
+			}
+		case "start_date":
+			var v string
+			if decode(name, raw, &v, false) {
+				utils.CheckDate(verr, name, v)
+				patch.StartDate = &v
+			}
+		case "end_date":
+			patch.SetEndDate = true
+			var v string
+			if decode(name, raw, &v, true) {
+				utils.CheckDate(verr, name, v)
+				patch.EndDate = &v
+			}
+		case "billing_period":
+			var v string
+			if decode(name, raw, &v, false) {
+				patch.BillingPeriod = &v
+			}
+		case "billing_anchor":
+			patch.SetBillingAnchor = true
+			var v string
+			if decode(name, raw, &v, true) {
+				utils.CheckDate(verr, name, v)
+				patch.BillingAnchor = &v
+			}
+		case "id", "created_at", "updated_at", "monthly_price", "monthly_amount_minor":
+			verr.Add(name, "is read-only")
+		default:
+			verr.Add(name, "unknown field")
+		}
+	}
+
+	// порядок ошибок не должен зависеть от порядка обхода map
+	sort.Slice(verr.Fields, func(i, j int) bool { return verr.Fields[i].Field < verr.Fields[j].Field })
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+// maxPriceParam наибольший модуль min_price и max_price: в минимальных единицах цена должна помещаться в int64
+const maxPriceParam = math.MaxInt64/entities.MinorUnits - 1
+
+// parseListFilter собирает фильтр списка подписок из query-параметров запроса.
+// Ошибки параметров возвращаются как *entities.ValidationError.
+func parseListFilter(r *http.Request) (*entities.ListFilter, error) {
+	query := r.URL.Query()
+	verr := &entities.ValidationError{}
+	filter := &entities.ListFilter{}
+
+	for _, v := range query["user_id"] {
+		utils.CheckUUID(verr, "user_id", v)
+		filter.UserIDs = append(filter.UserIDs, v)
+	}
+	for _, v := range query["service_name"] {
+		if v != "" {
+			filter.ServiceNames = append(filter.ServiceNames, v)
+		}
+	}
+	if v := query.Get("search"); v != "" {
+		filter.Search = &v
+	}
+	if v := query.Get("start_date"); v != "" {
+		utils.CheckDate(verr, "start_date", v)
+		filter.StartDate = &v
+	}
+	if v := query.Get("end_date"); v != "" {
+		utils.CheckDate(verr, "end_date", v)
+		filter.EndDate = &v
+	}
+	if v := query.Get("active_on"); v != "" {
+		utils.CheckDate(verr, "active_on", v)
+		filter.ActiveOn = &v
+	}
+	for _, raw := range query["currency"] {
+		for _, currency := range strings.Split(raw, ",") {
+			currency = strings.ToUpper(strings.TrimSpace(currency))
+			if !entities.ValidCurrency(currency) {
+				verr.Add("currency", "must be one of "+strings.Join(entities.Currencies, ", "))
+				continue
+			}
+			filter.Currencies = append(filter.Currencies, currency)
+		}
+	}
+	// цены в основных единицах переводятся в минимальные, поэтому их модуль ограничен
+	priceParam := func(name string) *int64 {
+		v := query.Get(name)
+		if v == "" {
+			return nil
+		}
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || price > maxPriceParam || price < -maxPriceParam {
+			verr.Add(name, fmt.Sprintf("must be an integer between %d and %d", -maxPriceParam, maxPriceParam))
+		}
+		return &price
+	}
+	filter.MinPrice = priceParam("min_price")
+	filter.MaxPrice = priceParam("max_price")
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		verr.Add("max_price", "must not be less than min_price")
+	}
+	if v := query.Get("has_end_date"); v != "" {
+		hasEndDate, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.Add("has_end_date", "must be a boolean")
+		}
+		filter.HasEndDate = &hasEndDate
+	}
+	if v := query.Get("sort"); v != "" {
+		order, err := entities.ParseSort(v)
+		if err != nil {
+			verr.Add("sort", err.Error())
+		}
+		filter.Sort = order
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			verr.Add("limit", "must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := entities.DecodeCursor(v)
+		if err == nil {
+			_, err = cursor.Key(filter.Order())
+		}
+		if err != nil {
+			verr.Add("cursor", "invalid cursor or cursor issued for another sort")
+		}
+		filter.After = cursor
+	}
+	if v := query.Get("include_total"); v != "" {
+		withTotal, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.Add("include_total", "must be a boolean")
+		}
+		filter.WithTotal = withTotal
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// parseCostFilter собирает фильтр расчета стоимости из query-параметров запроса.
+// Ошибки параметров возвращаются как *entities.ValidationError.
+func parseCostFilter(r *http.Request) (*entities.CostFilter, error) {
+	verr := &entities.ValidationError{}
 	filter := &entities.CostFilter{
-		StartPeriod: startPeriod,
-		EndPeriod:   endPeriod,
+		StartPeriod: r.URL.Query().Get("start_period"),
+		EndPeriod:   r.URL.Query().Get("end_period"),
+	}
+
+	if filter.StartPeriod == "" {
+		verr.Add("start_period", "is required")
+	} else {
+		utils.CheckDate(verr, "start_period", filter.StartPeriod)
+	}
+	if filter.EndPeriod == "" {
+		verr.Add("end_period", "is required")
+	} else {
+		utils.CheckDate(verr, "end_period", filter.EndPeriod)
+	}
+
+	if len(verr.Fields) == 0 && !utils.PeriodOrdered(filter.StartPeriod, filter.EndPeriod) {
+		verr.Add("end_period", "must not be before start_period")
 	}
 
 	if userID := r.URL.Query().Get("user_id"); userID != "" {
-		if err := utils.ValidateUUID(userID); err != nil {
-			RespondWithError(w, http.StatusBadRequest, "invalid user_id format: "+err.Error())
-			return
-		}
+		utils.CheckUUID(verr, "user_id", userID)
 		filter.UserID = &userID
 	}
 
@@ -242,16 +649,48 @@ func (s *Server) CalculateTotalCost(w http.ResponseWriter, r *http.Request) {
 		filter.ServiceName = &serviceName
 	}
 
-	totalCost, err := s.Service.CalculateTotalCost(r.Context(), filter)
-	if err != nil {
-		slog.Error("Failed to calculate total cost", "error", err)
-		RespondWithError(w, http.StatusInternalServerError, "failed to calculate total cost")
-		return
+	switch basis := r.URL.Query().Get("basis"); basis {
+	case "", entities.BasisCash, entities.BasisAccrual:
+		filter.Basis = basis
+	default:
+		verr.Add("basis", "must be one of cash, accrual")
 	}
 
-	response := entities.TotalCostResponse{
-		TotalCost: totalCost,
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		if !entities.ValidCurrency(currency) {
+			verr.Add("currency", "must be one of "+strings.Join(entities.Currencies, ", "))
+		}
+		filter.Currency = currency
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// parseGroupBy разбирает параметр group_by: допускаются повторы параметра и значения через запятую
+func parseGroupBy(r *http.Request) ([]string, error) {
+	var groupBy []string
+	seen := make(map[string]bool)
+
+	for _, raw := range r.URL.Query()["group_by"] {
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			switch field {
+			case entities.GroupByServiceName, entities.GroupByUserID:
+			default:
+				verr := &entities.ValidationError{}
+				verr.Add("group_by", fmt.Sprintf("unsupported value %q, expected service_name or user_id", field))
+				return nil, verr
+			}
+			if !seen[field] {
+				seen[field] = true
+				groupBy = append(groupBy, field)
+			}
+		}
 	}
 
-	RespondWithJSON(w, http.StatusOK, response)
+	return groupBy, nil
 }
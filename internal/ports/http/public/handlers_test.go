@@ -0,0 +1,259 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestPatchSubscription(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	endDate := "12-2025"
+	id, err := svc.CreateSubscription(context.Background(), &entities.Subscriptions{
+		ServiceName: "Netflix",
+		Price:       999,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+		EndDate:     &endDate,
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Patch("/subscriptions/{id}", (&Server{Service: svc}).PatchSubscription)
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/subscriptions/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := patch(`{"price": 1099, "end_date": null}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var sub entities.Subscriptions
+	if err := json.NewDecoder(rec.Body).Decode(&sub); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if sub.ID != id || sub.Price != 1099 || sub.AmountMinor != 109900 || sub.EndDate != nil || sub.StartDate != "07-2025" {
+		t.Fatalf("patched subscription = %+v", sub)
+	}
+
+	rec = patch(`{"price": null, "start_date": "2025-07", "color": "red"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	fields := make([]string, 0, len(problem.Errors))
+	for _, e := range problem.Errors {
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "color,price,start_date" {
+		t.Fatalf("errors fields: got %s, want color,price,start_date", got)
+	}
+
+	// end_date раньше сохраненной start_date проверяется по результату патча
+	if rec = patch(`{"end_date": "01-2025"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("end_date before start_date: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = patch(`{"billing_period": "year", "price": 11990, "billing_anchor": "01-2026"}`)
+	sub = entities.Subscriptions{}
+	if err := json.NewDecoder(rec.Body).Decode(&sub); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("billing patch: got %d, %v", rec.Code, err)
+	}
+	if sub.BillingPeriod != entities.BillingYear || sub.MonthlyPrice != 999 || sub.BillingAnchor == nil || *sub.BillingAnchor != "01-2026" {
+		t.Fatalf("billing patch = %+v", sub)
+	}
+	if rec = patch(`{"billing_period": "day"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown billing_period: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec = patch(`{"monthly_price": 1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("read-only monthly_price: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = patch(`{"currency": "USD"}`)
+	sub = entities.Subscriptions{}
+	if err := json.NewDecoder(rec.Body).Decode(&sub); rec.Code != http.StatusOK || err != nil || sub.Currency != entities.CurrencyUSD {
+		t.Fatalf("currency patch: got %d, %+v, %v", rec.Code, sub, err)
+	}
+	if rec = patch(`{"currency": "GBP"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown currency: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = patch(`{"amount_minor": 129950}`)
+	sub = entities.Subscriptions{}
+	if err := json.NewDecoder(rec.Body).Decode(&sub); rec.Code != http.StatusOK || err != nil || sub.AmountMinor != 129950 || sub.Price != 1299 {
+		t.Fatalf("amount_minor patch: got %d, %+v, %v", rec.Code, sub, err)
+	}
+	// price и amount_minor должны задавать одну цену
+	if rec = patch(`{"price": 1300, "amount_minor": 129950}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("price and amount_minor mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec = patch(`{"monthly_amount_minor": 1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("read-only monthly_amount_minor: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	sub := entities.Subscriptions{
+		ServiceName: "Netflix",
+		Price:       999,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+	}
+	if _, err := svc.CreateSubscription(context.Background(), &sub); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	cfg := &config.Config{}
+	server := &Server{Service: svc, cfg: cfg}
+	router := chi.NewRouter()
+	router.Get("/subscriptions/{id}", server.GetSubscription)
+	router.Put("/subscriptions/{id}", server.UpdateSubscription)
+	router.Delete("/subscriptions/{id}", server.DeleteSubscription)
+	do := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/subscriptions/1", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"service_name": "Netflix", "price": 1099, "user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "start_date": "07-2025"}`
+
+	if got := do(http.MethodGet, "", "").Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("GET ETag: got %s, want \"1\"", got)
+	}
+
+	rec := do(http.MethodPut, `"1"`, body)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT with current ETag: got %d, ETag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	for _, ifMatch := range []string{`"1"`, `W/"2"`, `2`} {
+		if rec := do(http.MethodPut, ifMatch, body); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("PUT with If-Match %s: got %d, want %d", ifMatch, rec.Code, http.StatusPreconditionFailed)
+		}
+	}
+	if rec := do(http.MethodPut, `"1", W/"2", "5"`, body); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with stale ETag list: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	// Изменение выполняется, если с текущей версией совпадает любой сильный ETag списка
+	rec = do(http.MethodPut, `W/"2", "1", "2"`, body)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("PUT with ETag list: got %d, ETag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := do(http.MethodPut, strings.Repeat(`"3", `, maxIfMatchTags)+`"3"`, body); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT with too many ETags: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := do(http.MethodDelete, `"1"`, ""); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	cfg.HTTPServer.RequireIfMatch = true
+	if rec := do(http.MethodDelete, "", ""); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("DELETE without If-Match: got %d, want %d", rec.Code, http.StatusPreconditionRequired)
+	}
+	if rec := do(http.MethodDelete, "*", ""); rec.Code >= 300 {
+		t.Errorf("DELETE with If-Match *: got %d", rec.Code)
+	}
+}
+
+func TestCostBasis(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	if _, err := svc.CreateSubscription(context.Background(), &entities.Subscriptions{
+		ServiceName:   "PlayStation Plus",
+		Price:         5990,
+		UserID:        "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:     "03-2025",
+		BillingPeriod: entities.BillingYear,
+	}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/subscriptions/cost", (&Server{Service: svc}).CalculateTotalCost)
+	get := func(basis string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions/cost?start_period=01-2026&end_period=06-2026&basis="+basis, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 01..02-2026 - последние 2 из 12 частей списания 03-2025 (5990 - 5990*10/12 = 999),
+	// 03..06-2026 - первые 4 части списания 03-2026 (5990*4/12 = 1996)
+	for basis, want := range map[string]int64{"": 5990, "cash": 5990, "accrual": 999 + 1996} {
+		rec := get(basis)
+		var total entities.TotalCostResponse
+		if err := json.NewDecoder(rec.Body).Decode(&total); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("basis=%s: got %d, %v", basis, rec.Code, err)
+		}
+		if total.TotalCost != want {
+			t.Errorf("basis=%s: total = %d, want %d", basis, total.TotalCost, want)
+		}
+	}
+
+	if rec := get("daily"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown basis: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCostPeriodLimit(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+
+	router := chi.NewRouter()
+	router.Get("/subscriptions/cost", (&Server{Service: svc}).CalculateTotalCost)
+	router.Get("/subscriptions/cost/breakdown", (&Server{Service: svc}).CalculateCostBreakdown)
+	get := func(start, end string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/subscriptions/cost/breakdown?start_period="+start+"&end_period="+end, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("01-2016", "12-2025"); rec.Code != http.StatusOK {
+		t.Errorf("120 months: got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec := get("01-0001", "12-9999")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("period too long: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "end_period" {
+		t.Errorf("errors = %+v, want end_period", problem.Errors)
+	}
+
+	if _, err := svc.CalculateCostBreakdown(context.Background(), &entities.CostFilter{StartPeriod: "01-2016", EndPeriod: "01-2026"}); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("service: got %v, want ErrValidation", err)
+	}
+
+	// Ограничение относится только к помесячной разбивке
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/cost?start_period=01-0001&end_period=12-9999", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("total cost for long period: got %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,185 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader помечает ответ, повторенный из сохраненного результата
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// maxIdempotentBodySize предельный размер тела запроса с ключом идемпотентности
+	maxIdempotentBodySize = 1 << 20
+	// idempotencyPurgeInterval период удаления ключей с истекшим сроком
+	idempotencyPurgeInterval = time.Hour
+)
+
+// Idempotency middleware выполняет запрос с Idempotency-Key не более одного раза за ttl, повтор получает сохраненный ответ
+func Idempotency(store service.IdempotencyStore, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				RespondWithError(w, r, http.StatusBadRequest, "invalid Idempotency-Key header",
+					entities.FieldError{Field: idempotencyKeyHeader, Message: "must be at most 255 characters"})
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				RespondWithError(w, r, http.StatusBadRequest, "failed to read request body: "+err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			rec := &entities.IdempotencyRecord{
+				Key:         key,
+				Fingerprint: fingerprint(r, body),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+			existing, err := store.ReserveIdempotencyKey(r.Context(), rec)
+			if err != nil {
+				RespondWithServiceError(w, r, err, "failed to reserve idempotency key")
+				return
+			}
+			if existing != nil {
+				replay(w, r, rec, existing)
+				return
+			}
+
+			// Ключ освобождается и при панике обработчика, иначе повторы получали бы 409 до истечения ttl
+			ctx := context.WithoutCancel(r.Context())
+			recorder := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					releaseIdempotencyKey(ctx, store, key)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+
+			status := recorder.statusCode()
+			if status >= http.StatusInternalServerError {
+				releaseIdempotencyKey(ctx, store, key)
+				return
+			}
+			rec.Status = status
+			rec.ContentType = recorder.Header().Get("Content-Type")
+			rec.Body = recorder.body.Bytes()
+			if err := store.CompleteIdempotencyKey(ctx, rec); err != nil {
+				slog.Error("Failed to save idempotent response", "error", err, "key", key)
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// PurgeIdempotencyKeys периодически удаляет ключи идемпотентности с истекшим сроком, пока ctx не отменен
+func PurgeIdempotencyKeys(ctx context.Context, store service.IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := store.PurgeIdempotencyKeys(ctx, time.Now())
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					slog.Error("Failed to purge idempotency keys", "error", err)
+				}
+				continue
+			}
+			if purged > 0 {
+				slog.Info("Expired idempotency keys purged", "count", purged)
+			}
+		}
+	}
+}
+
+// fingerprint хеш метода, пути, параметров и тела запроса, которым сравниваются повторы с одним ключом.
+// Параметры сортируются, поэтому их порядок в запросе не влияет на хеш.
+func fingerprint(r *http.Request, body []byte) string {
+	target := r.URL.Path
+	if query := r.URL.Query().Encode(); query != "" {
+		target += "?" + query
+	}
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + target + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay отвечает на повтор запроса по сохраненной записи
+func replay(w http.ResponseWriter, r *http.Request, rec, existing *entities.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != rec.Fingerprint:
+		RespondWithError(w, r, http.StatusUnprocessableEntity,
+			"Idempotency-Key has already been used with a different request")
+	case !existing.Completed():
+		w.Header().Set("Retry-After", "1")
+		RespondWithError(w, r, http.StatusConflict,
+			"a request with this Idempotency-Key is still being processed")
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		if _, err := w.Write(existing.Body); err != nil {
+			slog.Error("Failed to write idempotent response", "error", err)
+		}
+	}
+}
+
+func releaseIdempotencyKey(ctx context.Context, store service.IdempotencyStore, key string) {
+	if err := store.ReleaseIdempotencyKey(ctx, key); err != nil {
+		slog.Error("Failed to release idempotency key", "error", err, "key", key)
+	}
+}
+
+// responseRecorder передает ответ клиенту и сохраняет копию для повторов
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.status == 0 {
+		rr.status = code
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *responseRecorder) statusCode() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
@@ -0,0 +1,94 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/service"
+)
+
+func TestIdempotency(t *testing.T) {
+	storage := memory.New()
+	svc := service.NewService(storage, &config.Config{})
+
+	router := chi.NewRouter()
+	router.With(Idempotency(storage, time.Hour)).Post("/subscriptions", (&Server{Service: svc}).CreateSubscription)
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"service_name": "Netflix", "price": 999, "user_id": "60601fee-2bf1-4721-ae6f-7636e79a0cba", "start_date": "07-2025"}`
+
+	first := post("key-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first POST: got %d, want %d: %s", first.Code, http.StatusCreated, first.Body)
+	}
+	replayed := post("key-1", body)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() {
+		t.Fatalf("replayed POST: got %d %s, want %d %s", replayed.Code, replayed.Body, http.StatusCreated, first.Body)
+	}
+	if replayed.Header().Get(idempotentReplayedHeader) != "true" || replayed.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replayed headers = %v", replayed.Header())
+	}
+
+	if rec := post("key-1", strings.Replace(body, "999", "1099", 1)); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("POST with reused key: got %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	// Ответ с ошибкой валидации тоже сохраняется за ключом
+	if rec := post("key-2", `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid POST: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := post("key-2", `{}`); rec.Code != http.StatusBadRequest || rec.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("replayed invalid POST: got %d, replayed %q", rec.Code, rec.Header().Get(idempotentReplayedHeader))
+	}
+
+	if rec := post("", body); rec.Code != http.StatusCreated {
+		t.Fatalf("POST without key: got %d, want %d", rec.Code, http.StatusCreated)
+	}
+	var created map[string]int64
+	if err := json.NewDecoder(post("key-3", body).Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// key-1, запрос без ключа и key-3 создали по одной подписке
+	if created["id"] != 3 {
+		t.Errorf("id for key-3 = %d, want 3", created["id"])
+	}
+}
+
+func TestIdempotencyQuery(t *testing.T) {
+	storage := memory.New()
+	svc := service.NewService(storage, &config.Config{})
+
+	router := chi.NewRouter()
+	router.With(Idempotency(storage, time.Hour)).Post("/subscriptions/batch", (&Server{Service: svc}).ApplyBatch)
+	post := func(query string) *httptest.ResponseRecorder {
+		body := `{"operations": [{"op": "delete", "id": 1}]}`
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions/batch"+query, strings.NewReader(body))
+		req.Header.Set(idempotencyKeyHeader, "batch-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("?atomic=true"); rec.Header().Get(idempotentReplayedHeader) != "" {
+		t.Fatalf("first batch: got %d, replayed %q", rec.Code, rec.Header().Get(idempotentReplayedHeader))
+	}
+	if rec := post("?atomic=false"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("batch with other atomic: got %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if rec := post("?atomic=true"); rec.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("repeated batch: got %d, replayed %q", rec.Code, rec.Header().Get(idempotentReplayedHeader))
+	}
+}
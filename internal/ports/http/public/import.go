@@ -0,0 +1,230 @@
+package public
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/ports/http/public/utils"
+)
+
+const (
+	// defaultImportLimit предельное число строк импорта, если оно не задано в конфигурации
+	defaultImportLimit = 100000
+	// maxImportBodySize предельный размер CSV-файла импорта
+	maxImportBodySize = 64 << 20
+)
+
+// importColumns колонки CSV импорта; currency, end_date и колонки оплаты можно не передавать,
+// цена передается в price или amount_minor
+var importColumns = []string{"service_name", "price", "amount_minor", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_anchor"}
+
+// requiredImportColumns обязательные колонки CSV импорта
+var requiredImportColumns = []string{"service_name", "user_id", "start_date"}
+
+// ImportSubscriptions импортирует подписки из CSV
+// @Summary Импорт подписок из CSV
+// @Description Принимает CSV с заголовком и колонками service_name, user_id, start_date, ценой в price (в основных единицах)
+// @Description или amount_minor (в минимальных единицах) и необязательными currency (по умолчанию RUB), end_date, billing_period (по умолчанию month), billing_anchor.
+// @Description Каждая строка проверяется по тем же правилам, что и при создании подписки. Принятые строки сохраняются
+// @Description одной операцией, отклоненные перечисляются в отчете с номером строки файла и причинами.
+// @Description При dry_run=true ничего не сохраняется, отчет показывает, что произойдет при импорте.
+// @Tags subscriptions
+// @Accept text/csv
+// @Produce json
+// @Param dry_run query bool false "Только проверить файл, ничего не сохраняя" default(false)
+// @Param file body string true "CSV-файл с подписками"
+// @Success 200 {object} entities.ImportReport "Отчет об импорте"
+// @Failure 400 {object} Problem "Некорректный CSV или параметры запроса"
+// @Failure 413 {object} Problem "Файл слишком большой"
+// @Failure 415 {object} Problem "Неподдерживаемый тип содержимого"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/import [post]
+func (s *Server) ImportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/csv" {
+		RespondWithError(w, r, http.StatusUnsupportedMediaType, "expected text/csv")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			RespondWithError(w, r, http.StatusBadRequest, "invalid query parameters",
+				entities.FieldError{Field: "dry_run", Message: "must be true or false"})
+			return
+		}
+	}
+
+	limit := defaultImportLimit
+	if s.cfg != nil && s.cfg.HTTPServer.ImportLimit > 0 {
+		limit = s.cfg.HTTPServer.ImportLimit
+	}
+
+	rows, err := parseImportCSV(http.MaxBytesReader(w, r.Body, maxImportBodySize), limit)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		RespondWithError(w, r, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("CSV file must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid CSV")
+		return
+	}
+
+	report, err := s.Service.ImportSubscriptions(r.Context(), rows, dryRun)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to import subscriptions")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, report)
+}
+
+// parseImportCSV читает CSV импорта. Ошибки формата отдельных строк сохраняются в ImportRow.Errors,
+// а ошибка заголовка или синтаксиса CSV отклоняет весь файл.
+func parseImportCSV(body io.Reader, limit int) ([]entities.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "header", Message: "CSV must start with a header row"},
+		}}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns, err := importHeader(header, importColumns, requiredImportColumns)
+	if err != nil {
+		return nil, err
+	}
+	_, hasPrice := columns["price"]
+	if _, hasAmount := columns["amount_minor"]; !hasPrice && !hasAmount {
+		return nil, &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "header", Message: `missing column "price" or "amount_minor"`},
+		}}
+	}
+
+	var rows []entities.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, csvError(err)
+		}
+		if len(rows) == limit {
+			return nil, &entities.ValidationError{Fields: []entities.FieldError{
+				{Field: "body", Message: fmt.Sprintf("must contain at most %d rows", limit)},
+			}}
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := entities.ImportRow{Line: line}
+		if err != nil {
+			row.Errors = []entities.FieldError{
+				{Field: "row", Message: fmt.Sprintf("expected %d fields, got %d", len(header), len(record))},
+			}
+		} else {
+			row.Subscription, row.Errors = parseImportRecord(record, columns)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// importHeader возвращает номера колонок known по заголовку CSV и проверяет, что колонки required есть
+func importHeader(header, known, required []string) (map[string]int, error) {
+	verr := &entities.ValidationError{}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // BOM из Excel
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch _, seen := columns[name]; {
+		case !slices.Contains(known, name):
+			verr.Add("header", fmt.Sprintf("unknown column %q", name))
+		case seen:
+			verr.Add("header", fmt.Sprintf("duplicate column %q", name))
+		default:
+			columns[name] = i
+		}
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			verr.Add("header", fmt.Sprintf("missing column %q", name))
+		}
+	}
+
+	return columns, verr.Err()
+}
+
+// parseImportRecord разбирает строку CSV в подписку и проверяет формат полей, как при создании подписки
+func parseImportRecord(record []string, columns map[string]int) (entities.Subscriptions, []entities.FieldError) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var fields []entities.FieldError
+	sub := entities.Subscriptions{
+		ServiceName: value("service_name"),
+		Currency:    value("currency"),
+		UserID:      value("user_id"),
+		StartDate:   value("start_date"),
+	}
+	price, amountMinor := value("price"), value("amount_minor")
+	if price == "" && amountMinor == "" {
+		fields = append(fields, entities.FieldError{Field: "price", Message: "price or amount_minor is required"})
+	}
+	if price != "" {
+		v, err := strconv.ParseInt(price, 10, 64)
+		if err != nil {
+			fields = append(fields, entities.FieldError{Field: "price", Message: "must be an integer"})
+		}
+		sub.Price = v
+	}
+	if amountMinor != "" {
+		v, err := strconv.ParseInt(amountMinor, 10, 64)
+		if err != nil {
+			fields = append(fields, entities.FieldError{Field: "amount_minor", Message: "must be an integer"})
+		}
+		sub.AmountMinor = v
+	}
+	if endDate := value("end_date"); endDate != "" {
+		sub.EndDate = &endDate
+	}
+	sub.BillingPeriod = value("billing_period")
+	if billingAnchor := value("billing_anchor"); billingAnchor != "" {
+		sub.BillingAnchor = &billingAnchor
+	}
+
+	var verr *entities.ValidationError
+	if errors.As(utils.ValidateSubscription(&sub), &verr) {
+		fields = append(fields, verr.Fields...)
+	}
+	return sub, fields
+}
+
+// csvError описывает синтаксическую ошибку CSV как ошибку валидации с номером строки
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "body", Message: fmt.Sprintf("line %d: %s", parseErr.Line, parseErr.Err)},
+		}}
+	}
+	return err
+}
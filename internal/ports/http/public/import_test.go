@@ -0,0 +1,97 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestImportSubscriptions(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	router := chi.NewRouter()
+	router.Post("/subscriptions/import", (&Server{Service: svc}).ImportSubscriptions)
+	post := func(query, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions/import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	csv := "\ufeffservice_name,price,user_id,start_date,end_date\n" +
+		"Netflix,999,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,\n" +
+		"Spotify,abc,not-a-uuid,2025-07,\n" +
+		"YouTube,349,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025,01-2025\n" +
+		"Yandex Plus,299\n" +
+		"\"Kinopoisk, HD\",399,60601fee-2bf1-4721-ae6f-7636e79a0cba,08-2025,12-2025\n"
+
+	report := func(rec *httptest.ResponseRecorder) entities.ImportReport {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var report entities.ImportReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return report
+	}
+
+	dry := report(post("?dry_run=true", "text/csv; charset=utf-8", csv))
+	if !dry.DryRun || dry.Total != 5 || len(dry.Accepted) != 2 || dry.Accepted[0].ID != 0 {
+		t.Fatalf("dry run report = %+v", dry)
+	}
+	wantRejected := map[int]string{3: "price,user_id,start_date", 4: "end_date", 5: "row"}
+	for _, row := range dry.Rejected {
+		fields := make([]string, 0, len(row.Errors))
+		for _, e := range row.Errors {
+			fields = append(fields, e.Field)
+		}
+		if got := strings.Join(fields, ","); got != wantRejected[row.Line] {
+			t.Errorf("line %d rejected for %s, want %s", row.Line, got, wantRejected[row.Line])
+		}
+	}
+	if page, _ := svc.ListSubscriptions(context.Background(), &entities.ListFilter{}); len(page.Items) != 0 {
+		t.Fatalf("dry run stored %d subscriptions", len(page.Items))
+	}
+
+	imported := report(post("", "text/csv", csv))
+	if imported.DryRun || len(imported.Accepted) != 2 || imported.Accepted[1].Line != 6 || imported.Accepted[1].ID == 0 {
+		t.Fatalf("import report = %+v", imported)
+	}
+	sub, err := svc.GetSubscription(context.Background(), imported.Accepted[1].ID)
+	if err != nil || sub.ServiceName != "Kinopoisk, HD" || sub.EndDate == nil || *sub.EndDate != "12-2025" {
+		t.Fatalf("imported subscription = %+v, %v", sub, err)
+	}
+
+	if rec := post("", "application/json", csv); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("json content type: got %d, want %d", rec.Code, http.StatusUnsupportedMediaType)
+	}
+	if rec := post("", "text/csv", "service_name,price,user\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad header: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := post("", "text/csv", "service_name,price,user_id,start_date\n\"Netflix,1\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("broken quoting: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := post("", "text/csv", "service_name,user_id,start_date\n"); rec.Code != http.StatusBadRequest {
+		t.Errorf("no price column: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// цену можно передать в минимальных единицах
+	minor := report(post("", "text/csv", "service_name,amount_minor,currency,user_id,start_date\n"+
+		"Spotify,99950,USD,60601fee-2bf1-4721-ae6f-7636e79a0cba,07-2025\n"))
+	if len(minor.Accepted) != 1 {
+		t.Fatalf("amount_minor import report = %+v", minor)
+	}
+	sub, err = svc.GetSubscription(context.Background(), minor.Accepted[0].ID)
+	if err != nil || sub.AmountMinor != 99950 || sub.Price != 999 || sub.Currency != entities.CurrencyUSD {
+		t.Fatalf("imported subscription = %+v, %v", sub, err)
+	}
+}
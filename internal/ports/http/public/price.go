@@ -0,0 +1,136 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"tz_effective/internal/entities"
+)
+
+// ListPrices возвращает историю цен подписки
+// @Summary История цен подписки
+// @Description Возвращает цены подписки по месяцам, с которых они действуют: первая запись - цена с начала подписки,
+// @Description следующие - запланированные изменения. Стоимость каждого месяца считается по цене, действовавшей в нем.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Success 200 {object} entities.PriceListResponse "История цен"
+// @Header 200 {string} ETag "Версия подписки для If-Match"
+// @Failure 400 {object} Problem "Некорректный ID"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id}/prices [get]
+func (s *Server) ListPrices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+
+	sub, err := s.Service.GetSubscription(r.Context(), id)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to get subscription prices")
+		return
+	}
+	w.Header().Set("ETag", etag(sub.Version))
+	RespondWithJSON(w, http.StatusOK, entities.PriceListResponse{Items: entities.PriceHistory(sub)})
+}
+
+// SchedulePriceChange планирует изменение цены подписки
+// @Summary Изменение цены подписки
+// @Description Задает цену подписки начиная с месяца effective_from, не меняя стоимость предыдущих месяцев.
+// @Description Цена передается в price (в основных единицах) или amount_minor (в минимальных единицах валюты подписки).
+// @Description Месяц должен быть позже start_date и не позже end_date. Изменение с того же месяца заменяется.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag подписки из GET; изменение выполняется, только если версия не изменилась"
+// @Param change body entities.PriceChange true "Новая цена и месяц, с которого она действует"
+// @Success 200 {object} entities.Subscriptions "Подписка после изменения"
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 404 {object} Problem "Подписка не найдена"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id}/prices [post]
+func (s *Server) SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+	var change entities.PriceChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
+		return
+	}
+
+	var sub *entities.Subscriptions
+	err = matchVersion(versions, func(version int64) (err error) {
+		sub, err = s.Service.SchedulePriceChange(r.Context(), id, &change, version)
+		return err
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to change subscription price")
+		return
+	}
+	w.Header().Set("ETag", etag(sub.Version))
+	RespondWithJSON(w, http.StatusOK, sub)
+}
+
+// CancelPriceChange отменяет изменение цены подписки
+// @Summary Отмена изменения цены
+// @Description Удаляет изменение цены с указанного месяца: с него снова действует предыдущая цена
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param month path string true "Месяц изменения цены (MM-YYYY)"
+// @Param If-Match header string false "ETag подписки из GET; изменение выполняется, только если версия не изменилась"
+// @Success 200 {object} entities.Subscriptions "Подписка после изменения"
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 404 {object} Problem "Подписка или изменение цены не найдены"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id}/prices/{month} [delete]
+func (s *Server) CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
+		return
+	}
+
+	var sub *entities.Subscriptions
+	err = matchVersion(versions, func(version int64) (err error) {
+		sub, err = s.Service.CancelPriceChange(r.Context(), id, chi.URLParam(r, "month"), version)
+		return err
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to cancel subscription price change")
+		return
+	}
+	w.Header().Set("ETag", etag(sub.Version))
+	RespondWithJSON(w, http.StatusOK, sub)
+}
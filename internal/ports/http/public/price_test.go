@@ -0,0 +1,104 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestPrices(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	endDate := "12-2025"
+	sub := entities.Subscriptions{
+		ServiceName: "Netflix",
+		Price:       999,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+		EndDate:     &endDate,
+	}
+	if _, err := svc.CreateSubscription(context.Background(), &sub); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	server := &Server{Service: svc}
+	router := chi.NewRouter()
+	router.Get("/subscriptions/{id}/prices", server.ListPrices)
+	router.Post("/subscriptions/{id}/prices", server.SchedulePriceChange)
+	router.Delete("/subscriptions/{id}/prices/{month}", server.CancelPriceChange)
+	router.Get("/subscriptions/cost", server.CalculateTotalCost)
+	do := func(method, target, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/subscriptions/1/prices", `"1"`, `{"effective_from": "10-2025", "price": 1299}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("schedule: got %d, ETag %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/subscriptions/1/prices", "", "")
+	var prices entities.PriceListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&prices); err != nil {
+		t.Fatalf("decode prices: %v", err)
+	}
+	want := []entities.PriceChange{{EffectiveFrom: "07-2025", Price: 999, AmountMinor: 99900}, {EffectiveFrom: "10-2025", Price: 1299, AmountMinor: 129900}}
+	if rec.Code != http.StatusOK || !reflect.DeepEqual(prices.Items, want) {
+		t.Fatalf("list: got %d %+v, want %+v", rec.Code, prices.Items, want)
+	}
+
+	rec = do(http.MethodGet, "/subscriptions/cost?start_period=07-2025&end_period=12-2025", "", "")
+	var total entities.TotalCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&total); err != nil {
+		t.Fatalf("decode cost: %v", err)
+	}
+	if total.TotalCost != 3*999+3*1299 {
+		t.Errorf("cost = %d, want %d", total.TotalCost, 3*999+3*1299)
+	}
+
+	invalid := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "start month", body: `{"effective_from": "07-2025", "price": 1}`, field: "effective_from"},
+		{name: "after end", body: `{"effective_from": "01-2026", "price": 1}`, field: "effective_from"},
+		{name: "format", body: `{"effective_from": "2025-10", "price": 1}`, field: "effective_from"},
+		{name: "negative price", body: `{"effective_from": "11-2025", "price": -1}`, field: "price"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(http.MethodPost, "/subscriptions/1/prices", "", tt.body)
+			var problem Problem
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field {
+				t.Errorf("got %d %+v, want 400 for %s", rec.Code, problem, tt.field)
+			}
+		})
+	}
+
+	if rec := do(http.MethodDelete, "/subscriptions/1/prices/10-2025", `"1"`, ""); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("cancel with stale ETag: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	if rec := do(http.MethodDelete, "/subscriptions/1/prices/10-2025", `"2"`, ""); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("cancel: got %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := do(http.MethodDelete, "/subscriptions/1/prices/10-2025", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel again: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
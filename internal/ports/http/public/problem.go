@@ -0,0 +1,107 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"tz_effective/internal/entities"
+)
+
+// problemContentType тип содержимого ответов об ошибках (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// Problem описание ошибки в формате RFC 7807
+type Problem struct {
+	Type      string                `json:"type" example:"/problems/validation-error"`
+	Title     string                `json:"title" example:"Bad Request"`
+	Status    int                   `json:"status" example:"400"`
+	Detail    string                `json:"detail,omitempty" example:"invalid request body"`
+	Instance  string                `json:"instance,omitempty" example:"/subscriptions"`
+	RequestID string                `json:"request_id,omitempty" example:"host/abcdef-000001"`
+	Errors    []entities.FieldError `json:"errors,omitempty"`
+}
+
+// problemTypes URI типов проблем по HTTP-статусу
+var problemTypes = map[int]string{
+	http.StatusBadRequest:           "/problems/validation-error",
+	http.StatusUnauthorized:         "/problems/unauthorized",
+	http.StatusForbidden:            "/problems/forbidden",
+	http.StatusNotFound:             "/problems/not-found",
+	http.StatusNotAcceptable:        "/problems/not-acceptable",
+	http.StatusConflict:             "/problems/conflict",
+	http.StatusPreconditionFailed:   "/problems/precondition-failed",
+	http.StatusPreconditionRequired: "/problems/precondition-required",
+	http.StatusUnprocessableEntity:  "/problems/unprocessable-request",
+	http.StatusFailedDependency:     "/problems/batch-aborted",
+	http.StatusServiceUnavailable:   "/problems/unavailable",
+	http.StatusInternalServerError:  "/problems/internal-error",
+}
+
+// problemType возвращает URI типа проблемы для статуса; для прочих статусов about:blank (RFC 7807, 4.2)
+func problemType(code int) string {
+	if t, ok := problemTypes[code]; ok {
+		return t
+	}
+	return "about:blank"
+}
+
+// RespondWithError отвечает ошибкой в формате application/problem+json.
+// fields перечисляет невалидные поля запроса.
+func RespondWithError(w http.ResponseWriter, r *http.Request, code int, detail string, fields ...entities.FieldError) {
+	problem := Problem{
+		Type:      problemType(code),
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: middleware.GetReqID(r.Context()),
+		Errors:    fields,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Failed to write error response", "error", err)
+	}
+}
+
+// errorStatus возвращает HTTP-статус, соответствующий ошибке сервиса
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, entities.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entities.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, entities.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, entities.ErrBatchAborted):
+		return http.StatusFailedDependency
+	case errors.Is(err, entities.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, entities.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RespondWithServiceError отвечает статусом ошибки сервиса; серверные ошибки только логируются
+func RespondWithServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	code := errorStatus(err)
+	if code >= http.StatusInternalServerError {
+		slog.Error(message, "error", err, "request_id", middleware.GetReqID(r.Context()))
+		RespondWithError(w, r, code, message)
+		return
+	}
+
+	var verr *entities.ValidationError
+	if errors.As(err, &verr) {
+		RespondWithError(w, r, code, message, verr.Fields...)
+		return
+	}
+
+	RespondWithError(w, r, code, message+": "+err.Error())
+}
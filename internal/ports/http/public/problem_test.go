@@ -0,0 +1,49 @@
+package public
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/service"
+)
+
+func TestCreateSubscriptionProblem(t *testing.T) {
+	s := &Server{Service: service.NewService(memory.New(), &config.Config{})}
+	handler := middleware.RequestID(http.HandlerFunc(s.CreateSubscription))
+
+	body := `{"service_name":"Netflix","price":100,"user_id":"not-a-uuid","start_date":"2025-07"}`
+	req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("Content-Type: got %q, want %q", ct, problemContentType)
+	}
+
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Type != "/problems/validation-error" {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+	if problem.RequestID == "" {
+		t.Fatalf("request_id is empty")
+	}
+
+	fields := make([]string, 0, len(problem.Errors))
+	for _, e := range problem.Errors {
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "user_id,start_date" {
+		t.Fatalf("errors fields: got %s, want user_id,start_date", got)
+	}
+}
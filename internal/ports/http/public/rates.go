@@ -0,0 +1,218 @@
+package public
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"tz_effective/internal/entities"
+)
+
+// rateColumns колонки CSV с курсами валют
+var rateColumns = []string{"currency", "month", "rate"}
+
+// ExchangeRateRequest тело запроса на установку курса валюты
+type ExchangeRateRequest struct {
+	Rate string `json:"rate" example:"92.5"`
+}
+
+// ListExchangeRates возвращает курсы валют
+// @Summary Курсы валют
+// @Description Возвращает курсы валют к рублю по месяцам. Курс действует только в своем месяце, на другие месяцы он не переносится.
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Param currency query []string false "Валюты" collectionFormat(csv) Enums(USD, EUR, KZT)
+// @Success 200 {object} entities.ExchangeRateListResponse "Курсы по валюте и месяцу"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /exchange-rates [get]
+func (s *Server) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	filter := &entities.ExchangeRateFilter{}
+	verr := &entities.ValidationError{}
+	for _, raw := range r.URL.Query()["currency"] {
+		for _, currency := range strings.Split(raw, ",") {
+			currency = strings.TrimSpace(currency)
+			if !entities.ValidCurrency(currency) {
+				verr.Add("currency", "must be one of "+strings.Join(entities.Currencies, ", "))
+				continue
+			}
+			filter.Currencies = append(filter.Currencies, currency)
+		}
+	}
+	if err := verr.Err(); err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	rates, err := s.Service.ListExchangeRates(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to list exchange rates")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, entities.ExchangeRateListResponse{Items: rates})
+}
+
+// SetExchangeRate устанавливает курс валюты за месяц
+// @Summary Установка курса валюты
+// @Description Задает, сколько рублей стоит единица валюты в месяце month. Курс за тот же месяц заменяется.
+// @Description Требует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Param currency path string true "Валюта" Enums(USD, EUR, KZT)
+// @Param month path string true "Месяц (MM-YYYY)"
+// @Param rate body ExchangeRateRequest true "Курс: положительное десятичное число, не больше 8 знаков после точки"
+// @Success 200 {object} entities.ExchangeRate "Сохраненный курс"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 401 {object} Problem "Нет токена администратора или он неверный"
+// @Failure 403 {object} Problem "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Security AdminToken
+// @Router /exchange-rates/{currency}/{month} [put]
+func (s *Server) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var req ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	rate, err := s.Service.SetExchangeRate(r.Context(), &entities.ExchangeRate{
+		Currency: chi.URLParam(r, "currency"),
+		Month:    chi.URLParam(r, "month"),
+		Rate:     req.Rate,
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to set exchange rate")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, rate)
+}
+
+// DeleteExchangeRate удаляет курс валюты за месяц
+// @Summary Удаление курса валюты
+// @Description Удаляет курс за месяц: суммы этого месяца в валюте больше не пересчитываются.
+// @Description Требует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Param currency path string true "Валюта" Enums(USD, EUR, KZT)
+// @Param month path string true "Месяц (MM-YYYY)"
+// @Success 204 {object} map[string]string "Статус удаления"
+// @Failure 400 {object} Problem "Ошибка в запросе"
+// @Failure 401 {object} Problem "Нет токена администратора или он неверный"
+// @Failure 403 {object} Problem "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан"
+// @Failure 404 {object} Problem "Курс не найден"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Security AdminToken
+// @Router /exchange-rates/{currency}/{month} [delete]
+func (s *Server) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if err := s.Service.DeleteExchangeRate(r.Context(), chi.URLParam(r, "currency"), chi.URLParam(r, "month")); err != nil {
+		RespondWithServiceError(w, r, err, "failed to delete exchange rate")
+		return
+	}
+	RespondWithJSON(w, http.StatusNoContent, map[string]string{"status": "deleted"})
+}
+
+// ImportExchangeRates загружает курсы валют из CSV
+// @Summary Загрузка курсов валют из CSV
+// @Description Принимает CSV с заголовком и колонками currency, month (MM-YYYY), rate.
+// @Description Курсы сохраняются все вместе или ни одного; курсы тех же валют и месяцев заменяются.
+// @Description Ошибки перечисляются по полям с номером строки файла.
+// @Description Требует токен администратора HTTP_ADMIN_TOKEN в заголовке Authorization (схема Bearer).
+// @Tags exchange-rates
+// @Accept text/csv
+// @Produce json
+// @Param file body string true "CSV-файл с курсами"
+// @Success 200 {object} entities.ExchangeRateListResponse "Сохраненные курсы"
+// @Failure 400 {object} Problem "Некорректный CSV"
+// @Failure 401 {object} Problem "Нет токена администратора или он неверный"
+// @Failure 403 {object} Problem "Операции администратора выключены: HTTP_ADMIN_TOKEN не задан"
+// @Failure 413 {object} Problem "Файл слишком большой"
+// @Failure 415 {object} Problem "Неподдерживаемый тип содержимого"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Security AdminToken
+// @Router /exchange-rates/import [post]
+func (s *Server) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/csv" {
+		RespondWithError(w, r, http.StatusUnsupportedMediaType, "expected text/csv")
+		return
+	}
+
+	limit := defaultImportLimit
+	if s.cfg != nil && s.cfg.HTTPServer.ImportLimit > 0 {
+		limit = s.cfg.HTTPServer.ImportLimit
+	}
+
+	rows, err := parseRatesCSV(http.MaxBytesReader(w, r.Body, maxImportBodySize), limit)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		RespondWithError(w, r, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("CSV file must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid CSV")
+		return
+	}
+
+	rates, err := s.Service.ImportExchangeRates(r.Context(), rows)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to import exchange rates")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, entities.ExchangeRateListResponse{Items: rates})
+}
+
+// parseRatesCSV читает CSV с курсами валют. Проверку значений выполняет сервис.
+func parseRatesCSV(body io.Reader, limit int) ([]entities.ExchangeRateRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "header", Message: "CSV must start with a header row"},
+		}}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns, err := importHeader(header, rateColumns, rateColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []entities.ExchangeRateRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(rows) == limit {
+			return nil, &entities.ValidationError{Fields: []entities.FieldError{
+				{Field: "body", Message: fmt.Sprintf("must contain at most %d rows", limit)},
+			}}
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, entities.ExchangeRateRow{Line: line, Rate: entities.ExchangeRate{
+			Currency: strings.ToUpper(strings.TrimSpace(record[columns["currency"]])),
+			Month:    strings.TrimSpace(record[columns["month"]]),
+			Rate:     strings.TrimSpace(record[columns["rate"]]),
+		}})
+	}
+}
@@ -0,0 +1,120 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestExchangeRates(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	endDate := "03-2025"
+	if _, err := svc.CreateSubscription(context.Background(), &entities.Subscriptions{
+		ServiceName: "Spotify",
+		Price:       1099,
+		Currency:    entities.CurrencyUSD,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "01-2025",
+		EndDate:     &endDate,
+	}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	server := &Server{Service: svc}
+	router := chi.NewRouter()
+	router.Get("/exchange-rates", server.ListExchangeRates)
+	router.Post("/exchange-rates/import", server.ImportExchangeRates)
+	router.Put("/exchange-rates/{currency}/{month}", server.SetExchangeRate)
+	router.Delete("/exchange-rates/{currency}/{month}", server.DeleteExchangeRate)
+	router.Get("/subscriptions/cost", server.CalculateTotalCost)
+	do := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	problemFields := func(rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		var problem Problem
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatalf("decode problem: %v", err)
+		}
+		fields := make([]string, 0, len(problem.Errors))
+		for _, e := range problem.Errors {
+			fields = append(fields, e.Field)
+		}
+		return strings.Join(fields, ",")
+	}
+
+	rec := do(http.MethodPut, "/exchange-rates/USD/01-2025", "application/json", `{"rate": "90.50"}`)
+	var rate entities.ExchangeRate
+	if err := json.NewDecoder(rec.Body).Decode(&rate); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("PUT rate: got %d, %v", rec.Code, err)
+	}
+	if rate != (entities.ExchangeRate{Currency: "USD", Month: "01-2025", Rate: "90.5"}) {
+		t.Fatalf("stored rate = %+v", rate)
+	}
+	if rec = do(http.MethodPut, "/exchange-rates/RUB/01-2025", "application/json", `{"rate": "1"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT base currency: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec = do(http.MethodPut, "/exchange-rates/USD/2025-01", "application/json", `{"rate": "-1"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT invalid rate: got %d, want %d", rec.Code, http.StatusBadRequest)
+	} else if got := problemFields(rec); got != "month,rate" {
+		t.Fatalf("PUT invalid rate errors: got %s, want month,rate", got)
+	}
+
+	// ошибки всех строк возвращаются вместе, и ни один курс не сохраняется
+	rec = do(http.MethodPost, "/exchange-rates/import", "text/csv", "currency,month,rate\nEUR,01-2025,100\nGBP,02-2025,110\nEUR,01-2025,101\n")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("import with errors: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if got := problemFields(rec); got != "currency,month" {
+		t.Fatalf("import errors: got %s, want currency,month", got)
+	}
+	rec = do(http.MethodPost, "/exchange-rates/import", "text/csv", "month,currency,rate\n02-2025,usd,90.5\n03-2025,usd,100\n01-2025,EUR,100.25\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/exchange-rates?currency=USD", "", "")
+	var list entities.ExchangeRateListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("GET rates: got %d, %v", rec.Code, err)
+	}
+	if len(list.Items) != 3 || list.Items[2].Month != "03-2025" || list.Items[2].Rate != "100" {
+		t.Fatalf("USD rates = %+v", list.Items)
+	}
+
+	// 1099 USD по курсу 90,5 в январе и феврале и по курсу 100 в марте
+	rec = do(http.MethodGet, "/subscriptions/cost?start_period=01-2025&end_period=03-2025", "", "")
+	var total entities.TotalCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&total); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("cost: got %d, %v", rec.Code, err)
+	}
+	if total.TotalCostMinor != 2*9945950+10990000 || total.TotalCost != 308819 || total.Currency != "RUB" || len(total.Rates) != 3 {
+		t.Fatalf("cost = %+v", total)
+	}
+	if rec = do(http.MethodGet, "/subscriptions/cost?start_period=01-2025&end_period=03-2025&currency=GBP", "", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown currency: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if rec = do(http.MethodDelete, "/exchange-rates/USD/01-2025", "", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE rate: got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec = do(http.MethodDelete, "/exchange-rates/USD/01-2025", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("DELETE missing rate: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	// без январского курса стоимость в рублях не посчитать: февральский курс не подставляется
+	if rec = do(http.MethodGet, "/subscriptions/cost?start_period=01-2025&end_period=03-2025", "", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("cost without rate: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
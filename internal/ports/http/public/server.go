@@ -30,11 +30,12 @@ func NewServer(server *http.Server, cfg *config.Config, service2 *service.Servic
 	}
 }
 
-func StartServer(ctx context.Context, service *service.Service, cfg *config.Config) <-chan struct{} {
+func StartServer(ctx context.Context, service *service.Service, idempotency service.IdempotencyStore, cfg *config.Config) <-chan struct{} {
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
+	r.Use(AuditContext)
 	r.Use(middleware.RealIP)
 	r.Use(mwLogger.New())
 	r.Use(middleware.Recoverer)
@@ -58,14 +59,39 @@ func StartServer(ctx context.Context, service *service.Service, cfg *config.Conf
 	}()
 
 	r.Route("/subscriptions", func(r chi.Router) {
-		r.Post("/", server.CreateSubscription)
+		r.With(Idempotency(idempotency, cfg.HTTPServer.IdempotencyTTL)).Post("/", server.CreateSubscription)
+		r.With(Idempotency(idempotency, cfg.HTTPServer.IdempotencyTTL)).Post("/batch", server.ApplyBatch)
+		r.Post("/import", server.ImportSubscriptions)
+		r.Get("/trash", server.ListTrash)
 		r.Get("/{id}", server.GetSubscription)
 		r.Put("/{id}", server.UpdateSubscription)
+		r.Patch("/{id}", server.PatchSubscription)
 		r.Delete("/{id}", server.DeleteSubscription)
+		r.Post("/{id}/restore", server.RestoreSubscription)
+		r.Get("/{id}/history", server.SubscriptionHistory)
+		r.Get("/{id}/prices", server.ListPrices)
+		r.Post("/{id}/prices", server.SchedulePriceChange)
+		r.Delete("/{id}/prices/{month}", server.CancelPriceChange)
 		r.Get("/", server.ListSubscriptions)
+		r.Get("/export", server.ExportSubscriptions)
 		r.Get("/cost", server.CalculateTotalCost)
+		r.Get("/cost/breakdown", server.CalculateCostBreakdown)
 	})
 
+	r.Get("/audit", server.ListAudit)
+
+	r.Route("/exchange-rates", func(r chi.Router) {
+		r.Get("/", server.ListExchangeRates)
+		r.Group(func(r chi.Router) {
+			r.Use(AdminOnly(cfg.HTTPServer.AdminToken))
+			r.Post("/import", server.ImportExchangeRates)
+			r.Put("/{currency}/{month}", server.SetExchangeRate)
+			r.Delete("/{currency}/{month}", server.DeleteExchangeRate)
+		})
+	})
+
+	go PurgeIdempotencyKeys(ctx, idempotency, idempotencyPurgeInterval)
+
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("http://localhost:"+cfg.HTTPServer.Port+"/swagger/doc.json"), // The url pointing to API definition
 	))
@@ -96,18 +122,3 @@ func RespondWithJSON(w http.ResponseWriter, code int, data interface{}) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
-
-func RespondWithError(w http.ResponseWriter, code int, message string, details ...string) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.WriteHeader(code)
-
-	errorText := message
-	if len(details) > 0 {
-		errorText += "\nDetails: " + details[0]
-	}
-
-	if _, err := w.Write([]byte(errorText)); err != nil {
-		slog.Error("Failed to write error response", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-}
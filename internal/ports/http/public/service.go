@@ -8,8 +8,23 @@ import (
 type Service interface {
 	CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (id int64, err error)
 	GetSubscription(ctx context.Context, id int64) (sub *entities.Subscriptions, err error)
-	UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions) error
-	DeleteSubscription(ctx context.Context, id int64) error
-	ListSubscriptions(ctx context.Context, filter *entities.ListFilter) ([]entities.Subscriptions, error)
-	CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (int64, error)
+	UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (newVersion int64, err error)
+	PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64) (*entities.Subscriptions, error)
+	DeleteSubscription(ctx context.Context, id int64, version int64) error
+	RestoreSubscription(ctx context.Context, id int64, version int64) (*entities.Subscriptions, error)
+	SchedulePriceChange(ctx context.Context, id int64, change *entities.PriceChange, version int64) (*entities.Subscriptions, error)
+	CancelPriceChange(ctx context.Context, id int64, effectiveFrom string, version int64) (*entities.Subscriptions, error)
+	ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error)
+	ImportSubscriptions(ctx context.Context, rows []entities.ImportRow, dryRun bool) (*entities.ImportReport, error)
+	ListSubscriptions(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error)
+	ListTrash(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error)
+	ExportSubscriptions(ctx context.Context, filter *entities.ListFilter, fn func(*entities.Subscriptions) error) error
+	ListEvents(ctx context.Context, filter *entities.EventFilter) (*entities.EventPage, error)
+	ListExchangeRates(ctx context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error)
+	SetExchangeRate(ctx context.Context, rate *entities.ExchangeRate) (*entities.ExchangeRate, error)
+	ImportExchangeRates(ctx context.Context, rows []entities.ExchangeRateRow) ([]entities.ExchangeRate, error)
+	DeleteExchangeRate(ctx context.Context, currency, month string) error
+	CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error)
+	CalculateCostBreakdown(ctx context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error)
+	CalculateGroupedCost(ctx context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error)
 }
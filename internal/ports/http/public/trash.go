@@ -0,0 +1,95 @@
+package public
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"tz_effective/internal/entities"
+)
+
+// ListTrash возвращает подписки из корзины
+// @Summary Корзина подписок
+// @Description Получает удаленные подписки, которые еще можно восстановить. Фильтры, сортировка и пагинация как у списка подписок.
+// @Description Подписки удаляются из корзины окончательно по истечении срока хранения.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param user_id query []string false "ID пользователя (UUID), можно повторять" collectionFormat(multi)
+// @Param service_name query []string false "Название сервиса (точное совпадение), можно повторять" collectionFormat(multi)
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param start_date query string false "Дата начала подписки не раньше (MM-YYYY)"
+// @Param end_date query string false "Дата окончания подписки не позже (MM-YYYY)"
+// @Param active_on query string false "Подписка действует в этом месяце (MM-YYYY)"
+// @Param currency query []string false "Валюты цены" collectionFormat(csv) Enums(RUB, USD, EUR, KZT)
+// @Param min_price query int false "Минимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param max_price query int false "Максимальная цена в основных единицах, сравнивается в одной валюте currency (по умолчанию RUB)"
+// @Param has_end_date query bool false "true - только с датой окончания, false - только бессрочные"
+// @Param sort query string false "Поля сортировки через запятую, минус - по убыванию (id, service_name, price, start_date, created_at, updated_at)" default(start_date)
+// @Param limit query int false "Размер страницы (по умолчанию 50, не более 500)"
+// @Param cursor query string false "Курсор страницы из next_cursor предыдущего ответа"
+// @Param include_total query bool false "Посчитать общее количество подписок в корзине по фильтру"
+// @Success 200 {object} entities.SubscriptionListResponse "Страница корзины"
+// @Header 200 {string} Link "Ссылки на первую и следующую страницы (RFC 8288)"
+// @Failure 400 {object} Problem "Ошибка в параметрах запроса"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/trash [get]
+func (s *Server) ListTrash(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "invalid query parameters")
+		return
+	}
+
+	page, err := s.Service.ListTrash(r.Context(), filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to list deleted subscriptions")
+		return
+	}
+
+	respondWithPage(w, r, page)
+}
+
+// RestoreSubscription возвращает подписку из корзины
+// @Summary Восстановление подписки
+// @Description Возвращает удаленную подписку из корзины в список и расчет стоимости
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param If-Match header string false "ETag подписки из корзины; восстановление выполняется, только если версия не изменилась"
+// @Success 200 {object} entities.Subscriptions "Восстановленная подписка"
+// @Header 200 {string} ETag "Новая версия подписки"
+// @Failure 400 {object} Problem "Некорректный ID"
+// @Failure 404 {object} Problem "Подписки нет в корзине"
+// @Failure 412 {object} Problem "Подписка изменена после получения ETag"
+// @Failure 428 {object} Problem "Требуется заголовок If-Match"
+// @Failure 500 {object} Problem "Внутренняя ошибка сервера"
+// @Failure 503 {object} Problem "Хранилище недоступно"
+// @Router /subscriptions/{id}/restore [post]
+func (s *Server) RestoreSubscription(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		RespondWithError(w, r, http.StatusBadRequest, "invalid id",
+			entities.FieldError{Field: "id", Message: "must be an integer"})
+		return
+	}
+	versions, err := s.ifMatchVersions(r)
+	if err != nil {
+		respondPreconditionError(w, r, err)
+		return
+	}
+
+	var sub *entities.Subscriptions
+	err = matchVersion(versions, func(version int64) (err error) {
+		sub, err = s.Service.RestoreSubscription(r.Context(), id, version)
+		return err
+	})
+	if err != nil {
+		RespondWithServiceError(w, r, err, "failed to restore subscription")
+		return
+	}
+	w.Header().Set("ETag", etag(sub.Version))
+	RespondWithJSON(w, http.StatusOK, sub)
+}
@@ -0,0 +1,73 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestTrash(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	sub := entities.Subscriptions{
+		ServiceName: "Netflix",
+		Price:       999,
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:   "07-2025",
+	}
+	if _, err := svc.CreateSubscription(context.Background(), &sub); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	server := &Server{Service: svc}
+	router := chi.NewRouter()
+	router.Get("/subscriptions/trash", server.ListTrash)
+	router.Get("/subscriptions/{id}", server.GetSubscription)
+	router.Delete("/subscriptions/{id}", server.DeleteSubscription)
+	router.Post("/subscriptions/{id}/restore", server.RestoreSubscription)
+	do := func(method, target, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodDelete, "/subscriptions/1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := do(http.MethodGet, "/subscriptions/1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET deleted: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := do(http.MethodGet, "/subscriptions/trash", "")
+	var trash entities.SubscriptionListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&trash); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(trash.Items) != 1 || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("trash: got %d %+v", rec.Code, trash)
+	}
+
+	if rec := do(http.MethodPost, "/subscriptions/1/restore", `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("restore with stale ETag: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	rec = do(http.MethodPost, "/subscriptions/1/restore", etag(trash.Items[0].Version))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("restore: got %d, ETag %s: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+	if rec := do(http.MethodGet, "/subscriptions/1", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET restored: got %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := do(http.MethodPost, "/subscriptions/1/restore", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second restore: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
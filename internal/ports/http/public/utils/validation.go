@@ -3,9 +3,13 @@ package utils
 import (
 	"fmt"
 	"regexp"
+	"tz_effective/internal/entities"
 )
 
-var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+var (
+	uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	dateRegex = regexp.MustCompile(`^(0[1-9]|1[0-2])-\d{4}$`)
+)
 
 func ValidateUUID(uuid string) error {
 	if !uuidRegex.MatchString(uuid) {
@@ -15,9 +19,52 @@ func ValidateUUID(uuid string) error {
 }
 
 func ValidateDate(date string) error {
-	dateRegex := regexp.MustCompile(`^(0[1-9]|1[0-2])-\d{4}$`)
 	if !dateRegex.MatchString(date) {
 		return fmt.Errorf("invalid date format: %s, expected format MM-YYYY", date)
 	}
 	return nil
 }
+
+// CheckUUID добавляет в verr ошибку поля field, если value не является UUID
+func CheckUUID(verr *entities.ValidationError, field, value string) {
+	if !uuidRegex.MatchString(value) {
+		verr.Add(field, "invalid UUID format")
+	}
+}
+
+// CheckDate добавляет в verr ошибку поля field, если value не в формате MM-YYYY
+func CheckDate(verr *entities.ValidationError, field, value string) {
+	if !dateRegex.MatchString(value) {
+		verr.Add(field, "expected format MM-YYYY")
+	}
+}
+
+// ValidateSubscription проверяет формат полей подписки из тела запроса.
+// Возвращает *entities.ValidationError со всеми невалидными полями.
+func ValidateSubscription(sub *entities.Subscriptions) error {
+	verr := &entities.ValidationError{}
+
+	CheckUUID(verr, "user_id", sub.UserID)
+	CheckDate(verr, "start_date", sub.StartDate)
+	if sub.EndDate != nil {
+		CheckDate(verr, "end_date", *sub.EndDate)
+	}
+	if sub.BillingAnchor != nil {
+		CheckDate(verr, "billing_anchor", *sub.BillingAnchor)
+	}
+
+	return verr.Err()
+}
+
+// PeriodOrdered проверяет, что месяц start (MM-YYYY) не позже месяца end
+func PeriodOrdered(start, end string) bool {
+	from, err := entities.ParseMonth(start)
+	if err != nil {
+		return false
+	}
+	to, err := entities.ParseMonth(end)
+	if err != nil {
+		return false
+	}
+	return from <= to
+}
@@ -0,0 +1,523 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// Period период подписки в месяцах. Для бессрочной подписки End равен nil.
+type Period struct {
+	Start entities.Month
+	End   *entities.Month
+}
+
+// ParsePeriod разбирает даты начала и окончания подписки в формате MM-YYYY
+func ParsePeriod(sub *entities.Subscriptions) (Period, error) {
+	start, err := entities.ParseMonth(sub.StartDate)
+	if err != nil {
+		return Period{}, fmt.Errorf("start_date: %w", err)
+	}
+
+	p := Period{Start: start}
+	if sub.EndDate != nil {
+		end, err := entities.ParseMonth(*sub.EndDate)
+		if err != nil {
+			return Period{}, fmt.Errorf("end_date: %w", err)
+		}
+		p.End = &end
+	}
+
+	return p, nil
+}
+
+// Schedule цены подписки по месяцам: цена с начала подписки и изменения цены по возрастанию месяца
+type Schedule struct {
+	price   int64
+	changes []change
+}
+
+type change struct {
+	from  entities.Month
+	price int64
+}
+
+// ParseSchedule разбирает историю цен подписки
+func ParseSchedule(sub *entities.Subscriptions) (Schedule, error) {
+	s := Schedule{price: sub.AmountMinor, changes: make([]change, 0, len(sub.Prices))}
+	for i, p := range sub.Prices {
+		from, err := entities.ParseMonth(p.EffectiveFrom)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("prices[%d].effective_from: %w", i, err)
+		}
+		s.changes = append(s.changes, change{from: from, price: p.AmountMinor})
+	}
+	sort.SliceStable(s.changes, func(i, j int) bool { return s.changes[i].from < s.changes[j].from })
+	return s, nil
+}
+
+// At возвращает цену, действующую в месяце m
+func (s Schedule) At(m entities.Month) int64 {
+	price := s.price
+	for _, c := range s.changes {
+		if c.from > m {
+			break
+		}
+		price = c.price
+	}
+	return price
+}
+
+// Billing даты списаний подписки: месяцы, отсчитанные от опорного месяца с шагом периода оплаты.
+// Недельная подписка оплачивается каждые 7 дней начиная с первого числа опорного месяца.
+type Billing struct {
+	period string
+	anchor entities.Month
+}
+
+// ParseBilling разбирает период оплаты подписки и опорный месяц (по умолчанию start_date)
+func ParseBilling(sub *entities.Subscriptions) (Billing, error) {
+	b := Billing{period: sub.Billing()}
+	if !entities.ValidBillingPeriod(b.period) {
+		return Billing{}, fmt.Errorf("billing_period: %w: unknown billing period %q", entities.ErrValidation, b.period)
+	}
+
+	anchor := sub.StartDate
+	if sub.BillingAnchor != nil {
+		anchor = *sub.BillingAnchor
+	}
+	var err error
+	if b.anchor, err = entities.ParseMonth(anchor); err != nil {
+		return Billing{}, fmt.Errorf("billing_anchor: %w", err)
+	}
+	return b, nil
+}
+
+// Charges возвращает количество списаний в месяце m
+func (b Billing) Charges(m entities.Month) int64 {
+	return int64(len(b.covers(m)))
+}
+
+// share часть периода, оплаченного одним списанием, которая приходится на месяц.
+// weight - число месяцев (или дней для недельной оплаты) периода в этом месяце.
+type share struct {
+	month  entities.Month
+	weight int64
+}
+
+// covers возвращает для каждого списания в месяце m месяцы оплаченного им периода
+func (b Billing) covers(m entities.Month) [][]share {
+	switch b.period {
+	case entities.BillingQuarter:
+		return b.every(m, 3)
+	case entities.BillingYear:
+		return b.every(m, 12)
+	case entities.BillingWeek:
+		// списания каждые 7 дней от первого числа опорного месяца, попавшие в дни [first, last] месяца m
+		anchor := b.anchor.Time()
+		first := days(anchor, m.Time())
+		last := days(anchor, (m+1).Time()) - 1
+		var charges [][]share
+		for week := floorDiv(first-1, 7) + 1; week <= floorDiv(last, 7); week++ {
+			var shares []share
+			for day := week * 7; day < week*7+7; day++ {
+				month := entities.MonthOf(anchor.AddDate(0, 0, int(day)))
+				if n := len(shares); n > 0 && shares[n-1].month == month {
+					shares[n-1].weight++
+				} else {
+					shares = append(shares, share{month: month, weight: 1})
+				}
+			}
+			charges = append(charges, shares)
+		}
+		return charges
+	default:
+		return [][]share{{{month: m, weight: 1}}}
+	}
+}
+
+// every возвращает списание за months месяцев, если месяц m отстоит от опорного на целое число периодов
+func (b Billing) every(m entities.Month, months int64) [][]share {
+	if floorMod(int64(m-b.anchor), months) != 0 {
+		return nil
+	}
+	shares := make([]share, months)
+	for i := range shares {
+		shares[i] = share{month: m + entities.Month(i), weight: 1}
+	}
+	return [][]share{shares}
+}
+
+// maxCoverage наибольшее число месяцев, которые оплачивает одно списание
+const maxCoverage = 12
+
+func days(from, to time.Time) int64 {
+	return int64(to.Sub(from) / (24 * time.Hour))
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int64) int64 {
+	return a - floorDiv(a, b)*b
+}
+
+// plan подписка, разобранная для расчета стоимости
+type plan struct {
+	period   Period
+	prices   Schedule
+	billing  Billing
+	currency string
+}
+
+// active сообщает, что подписка действует в месяце m
+func (p plan) active(m entities.Month) bool {
+	return m >= p.period.Start && (p.period.End == nil || m <= *p.period.End)
+}
+
+// costs возвращает стоимость подписки по месяцам [first, last] периода подписки; charged - было ли списание или его часть
+func (p plan) costs(first, last entities.Month, basis string) (amounts []int64, charged []bool) {
+	amounts = make([]int64, int(last-first)+1)
+	charged = make([]bool, len(amounts))
+
+	if basis != entities.BasisAccrual {
+		for m := first; m <= last; m++ {
+			n := p.billing.Charges(m)
+			amounts[m-first] = p.prices.At(m) * n
+			charged[m-first] = n > 0
+		}
+		return amounts, charged
+	}
+
+	// на месяцы периода приходятся и списания, сделанные до first: самое раннее из них
+	// оплачивает maxCoverage месяцев и последним из них покрывает first
+	from := first - (maxCoverage - 1)
+	if from < p.period.Start {
+		from = p.period.Start
+	}
+	for c := from; c <= last; c++ {
+		price := p.prices.At(c)
+		for _, shares := range p.billing.covers(c) {
+			for _, part := range p.accrue(price, shares) {
+				if part.month >= first && part.month <= last {
+					amounts[part.month-first] += part.amount
+					charged[part.month-first] = true
+				}
+			}
+		}
+	}
+	return amounts, charged
+}
+
+// accrual часть списания, начисленная на месяц
+type accrual struct {
+	month  entities.Month
+	amount int64
+}
+
+// accrue распределяет списание price по месяцам оплаченного периода, в которых подписка действует,
+// пропорционально весам. Части округляются вниз по нарастающему итогу, поэтому их сумма равна price.
+func (p plan) accrue(price int64, shares []share) []accrual {
+	var total int64
+	for _, s := range shares {
+		if p.active(s.month) {
+			total += s.weight
+		}
+	}
+
+	parts := make([]accrual, 0, len(shares))
+	var cumulative, accrued int64
+	for _, s := range shares {
+		if !p.active(s.month) {
+			continue
+		}
+		cumulative += s.weight
+		amount := floorDiv(price*cumulative, total) - accrued
+		accrued += amount
+		parts = append(parts, accrual{month: s.month, amount: amount})
+	}
+	return parts
+}
+
+// parse разбирает период, историю цен и период оплаты подписки для расчета стоимости
+func parse(sub *entities.Subscriptions) (plan, error) {
+	p, err := ParsePeriod(sub)
+	if err != nil {
+		return plan{}, fmt.Errorf("subscription %q: %w", sub.ServiceName, err)
+	}
+	prices, err := ParseSchedule(sub)
+	if err != nil {
+		return plan{}, fmt.Errorf("subscription %q: %w", sub.ServiceName, err)
+	}
+	billing, err := ParseBilling(sub)
+	if err != nil {
+		return plan{}, fmt.Errorf("subscription %q: %w", sub.ServiceName, err)
+	}
+	return plan{period: p, prices: prices, billing: billing, currency: sub.CurrencyCode()}, nil
+}
+
+// Query параметры расчета стоимости: период [From, To], метод учета и валюта результата.
+// Rates заполняет хранилище курсами валют, перечисленными в Currencies.
+type Query struct {
+	From     entities.Month
+	To       entities.Month
+	Basis    string
+	Currency string
+	Rates    Rates
+}
+
+// ParseFilter разбирает фильтр стоимости. Пустой метод учета означает entities.BasisCash,
+// пустая валюта - entities.BaseCurrency.
+func ParseFilter(filter *entities.CostFilter) (Query, error) {
+	var q Query
+	var err error
+	if q.From, err = entities.ParseMonth(filter.StartPeriod); err != nil {
+		return Query{}, fmt.Errorf("start_period: %w", err)
+	}
+	if q.To, err = entities.ParseMonth(filter.EndPeriod); err != nil {
+		return Query{}, fmt.Errorf("end_period: %w", err)
+	}
+
+	switch q.Basis = filter.Basis; q.Basis {
+	case "":
+		q.Basis = entities.BasisCash
+	case entities.BasisCash, entities.BasisAccrual:
+	default:
+		return Query{}, fmt.Errorf("basis: %w: unknown cost basis %q", entities.ErrValidation, q.Basis)
+	}
+
+	if q.Currency = filter.Currency; q.Currency == "" {
+		q.Currency = entities.BaseCurrency
+	}
+	if !entities.ValidCurrency(q.Currency) {
+		return Query{}, fmt.Errorf("currency: %w: unsupported currency %q", entities.ErrValidation, q.Currency)
+	}
+	return q, nil
+}
+
+// Currencies возвращает валюты, курсы которых нужны для пересчета цен подписок в валюту запроса.
+// Курс BaseCurrency всегда равен 1 и не нужен.
+func (q Query) Currencies(subs []entities.Subscriptions) []string {
+	need := make(map[string]bool)
+	for i := range subs {
+		if currency := subs[i].CurrencyCode(); currency != q.Currency {
+			need[currency] = true
+		}
+	}
+	if len(need) > 0 {
+		need[q.Currency] = true
+	}
+	delete(need, entities.BaseCurrency)
+
+	currencies := make([]string, 0, len(need))
+	for currency := range need {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// costs возвращает стоимость подписки в каждом месяце [first, last] в валюте запроса (см. plan.costs)
+func (q Query) costs(p plan, conv *converter, first, last entities.Month) ([]int64, []bool, error) {
+	amounts, charged := p.costs(first, last, q.Basis)
+	for i := range amounts {
+		if !charged[i] {
+			continue
+		}
+		var err error
+		if amounts[i], err = conv.convert(amounts[i], p.currency, first+entities.Month(i)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return amounts, charged, nil
+}
+
+// total возвращает стоимость подписки за месяцы [first, last] в валюте запроса
+func (q Query) total(p plan, conv *converter, first, last entities.Month) (int64, error) {
+	amounts, _, err := q.costs(p, conv, first, last)
+	if err != nil {
+		return 0, err
+	}
+	var sum int64
+	for _, amount := range amounts {
+		sum += amount
+	}
+	return sum, nil
+}
+
+// Overlap возвращает первый и последний месяц подписки внутри [from, to].
+// ok равен false, если подписка не пересекается с периодом.
+func (p Period) Overlap(from, to entities.Month) (first, last entities.Month, ok bool) {
+	first, last = p.Start, to
+	if first < from {
+		first = from
+	}
+	if p.End != nil && *p.End < last {
+		last = *p.End
+	}
+	return first, last, first <= last
+}
+
+// Total считает стоимость подписок за период в минимальных единицах валюты запроса по курсу каждого месяца
+func Total(subs []entities.Subscriptions, q Query) (*entities.TotalCostResponse, error) {
+	res := &entities.TotalCostResponse{Currency: q.Currency}
+	conv := newConverter(q.Currency, q.Rates)
+
+	for i := range subs {
+		p, err := parse(&subs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		first, last, ok := p.period.Overlap(q.From, q.To)
+		if !ok {
+			continue
+		}
+
+		total, err := q.total(p, conv, first, last)
+		if err != nil {
+			return nil, err
+		}
+		res.TotalCostMinor += total
+		res.SubscriptionMonths += int64(last-first) + 1
+	}
+
+	res.TotalCost = entities.MajorUnits(res.TotalCostMinor)
+	res.Rates = conv.used()
+	return res, nil
+}
+
+// Breakdown раскладывает стоимость подписок за период запроса по календарным месяцам (см. Total).
+// В ответ попадает каждый месяц периода, даже если на него ничего не пришлось.
+func Breakdown(subs []entities.Subscriptions, q Query) (*entities.CostBreakdownResponse, error) {
+	res := &entities.CostBreakdownResponse{
+		Months:   make([]entities.MonthlyCost, 0, int(q.To-q.From)+1),
+		Currency: q.Currency,
+	}
+	for m := q.From; m <= q.To; m++ {
+		res.Months = append(res.Months, entities.MonthlyCost{
+			Month:         m.String(),
+			Subscriptions: []entities.SubscriptionCost{},
+		})
+	}
+	conv := newConverter(q.Currency, q.Rates)
+
+	for i := range subs {
+		p, err := parse(&subs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		first, last, ok := p.period.Overlap(q.From, q.To)
+		if !ok {
+			continue
+		}
+
+		amounts, charged, err := q.costs(p, conv, first, last)
+		if err != nil {
+			return nil, err
+		}
+		for m := first; m <= last; m++ {
+			if !charged[m-first] {
+				continue
+			}
+			price := amounts[m-first]
+			month := &res.Months[m-q.From]
+			month.TotalCostMinor += price
+			month.Subscriptions = append(month.Subscriptions, entities.SubscriptionCost{
+				ServiceName: subs[i].ServiceName,
+				UserID:      subs[i].UserID,
+				Cost:        entities.MajorUnits(price),
+				CostMinor:   price,
+			})
+			res.TotalCostMinor += price
+		}
+	}
+
+	for i := range res.Months {
+		res.Months[i].TotalCost = entities.MajorUnits(res.Months[i].TotalCostMinor)
+	}
+	res.TotalCost = entities.MajorUnits(res.TotalCostMinor)
+	res.Rates = conv.used()
+	return res, nil
+}
+
+// Grouped считает стоимость подписок (см. Total) по группам полей groupBy в порядке ключа
+func Grouped(subs []entities.Subscriptions, q Query, groupBy []string) ([]entities.GroupedCost, error) {
+	groups := make(map[string]*entities.GroupedCost)
+	converters := make(map[string]*converter)
+	var order []string
+
+	for i := range subs {
+		p, err := parse(&subs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		first, last, ok := p.period.Overlap(q.From, q.To)
+		if !ok {
+			continue
+		}
+
+		key, values, err := groupKey(&subs[i], groupBy)
+		if err != nil {
+			return nil, err
+		}
+
+		group, found := groups[key]
+		if !found {
+			group = &entities.GroupedCost{Key: values, Currency: q.Currency}
+			groups[key] = group
+			converters[key] = newConverter(q.Currency, q.Rates)
+			order = append(order, key)
+		}
+
+		total, err := q.total(p, converters[key], first, last)
+		if err != nil {
+			return nil, err
+		}
+		group.TotalCostMinor += total
+		group.SubscriptionMonths += int64(last-first) + 1
+	}
+
+	sort.Strings(order)
+
+	res := make([]entities.GroupedCost, 0, len(order))
+	for _, key := range order {
+		group := *groups[key]
+		group.TotalCost = entities.MajorUnits(group.TotalCostMinor)
+		group.Rates = converters[key].used()
+		res = append(res, group)
+	}
+
+	return res, nil
+}
+
+// groupKey возвращает строковый ключ группы подписки и значения полей группировки
+func groupKey(sub *entities.Subscriptions, groupBy []string) (string, map[string]string, error) {
+	values := make(map[string]string, len(groupBy))
+	parts := make([]string, 0, len(groupBy))
+
+	for _, field := range groupBy {
+		var value string
+		switch field {
+		case entities.GroupByServiceName:
+			value = sub.ServiceName
+		case entities.GroupByUserID:
+			value = sub.UserID
+		default:
+			return "", nil, fmt.Errorf("%w: unsupported group_by field %q", entities.ErrValidation, field)
+		}
+		values[field] = value
+		parts = append(parts, value)
+	}
+
+	return strings.Join(parts, "\x00"), values, nil
+}
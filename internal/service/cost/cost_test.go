@@ -0,0 +1,224 @@
+package cost
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"tz_effective/internal/entities"
+)
+
+func month(t *testing.T, s string) entities.Month {
+	t.Helper()
+	m, err := entities.ParseMonth(s)
+	if err != nil {
+		t.Fatalf("ParseMonth(%q): %v", s, err)
+	}
+	return m
+}
+
+func parsePlan(t *testing.T, sub entities.Subscriptions) plan {
+	t.Helper()
+	p, err := parse(&sub)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return p
+}
+
+func TestBillingCovers(t *testing.T) {
+	// format записывает части списаний в виде "MM-YYYY:вес"
+	format := func(charges [][]share) [][]string {
+		res := make([][]string, 0, len(charges))
+		for _, shares := range charges {
+			parts := make([]string, 0, len(shares))
+			for _, s := range shares {
+				parts = append(parts, fmt.Sprintf("%s:%d", s.month, s.weight))
+			}
+			res = append(res, parts)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name   string
+		period string
+		anchor string
+		month  string
+		want   [][]string
+	}{
+		{name: "month", period: entities.BillingMonth, anchor: "01-2025", month: "07-2025",
+			want: [][]string{{"07-2025:1"}}},
+		{name: "quarter on anchor", period: entities.BillingQuarter, anchor: "01-2025", month: "01-2025",
+			want: [][]string{{"01-2025:1", "02-2025:1", "03-2025:1"}}},
+		{name: "quarter after anchor", period: entities.BillingQuarter, anchor: "01-2025", month: "04-2025",
+			want: [][]string{{"04-2025:1", "05-2025:1", "06-2025:1"}}},
+		{name: "quarter between charges", period: entities.BillingQuarter, anchor: "01-2025", month: "05-2025",
+			want: [][]string{}},
+		{name: "quarter before anchor", period: entities.BillingQuarter, anchor: "01-2025", month: "10-2024",
+			want: [][]string{{"10-2024:1", "11-2024:1", "12-2024:1"}}},
+		{name: "quarter before anchor between charges", period: entities.BillingQuarter, anchor: "01-2025", month: "12-2024",
+			want: [][]string{}},
+		{name: "year on anchor", period: entities.BillingYear, anchor: "03-2025", month: "03-2026",
+			want: [][]string{{"03-2026:1", "04-2026:1", "05-2026:1", "06-2026:1", "07-2026:1", "08-2026:1",
+				"09-2026:1", "10-2026:1", "11-2026:1", "12-2026:1", "01-2027:1", "02-2027:1"}}},
+		{name: "year off anchor", period: entities.BillingYear, anchor: "03-2025", month: "02-2026",
+			want: [][]string{}},
+		{name: "year before anchor", period: entities.BillingYear, anchor: "03-2025", month: "03-2024",
+			want: [][]string{{"03-2024:1", "04-2024:1", "05-2024:1", "06-2024:1", "07-2024:1", "08-2024:1",
+				"09-2024:1", "10-2024:1", "11-2024:1", "12-2024:1", "01-2025:1", "02-2025:1"}}},
+		// 01.01, 08.01, 15.01, 22.01 и 29.01: последнее списание оплачивает 3 дня января и 4 дня февраля
+		{name: "week in anchor month", period: entities.BillingWeek, anchor: "01-2025", month: "01-2025",
+			want: [][]string{{"01-2025:7"}, {"01-2025:7"}, {"01-2025:7"}, {"01-2025:7"}, {"01-2025:3", "02-2025:4"}}},
+		// 05.02, 12.02, 19.02 и 26.02
+		{name: "week after anchor month", period: entities.BillingWeek, anchor: "01-2025", month: "02-2025",
+			want: [][]string{{"02-2025:7"}, {"02-2025:7"}, {"02-2025:7"}, {"02-2025:3", "03-2025:4"}}},
+		// 04.12, 11.12, 18.12 и 25.12.2024 - через 7 дней от 01.01.2025 назад
+		{name: "week before anchor", period: entities.BillingWeek, anchor: "01-2025", month: "12-2024",
+			want: [][]string{{"12-2024:7"}, {"12-2024:7"}, {"12-2024:7"}, {"12-2024:7"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Billing{period: tt.period, anchor: month(t, tt.anchor)}
+			m := month(t, tt.month)
+			if got := format(b.covers(m)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("covers(%s) = %v, want %v", tt.month, got, tt.want)
+			}
+			if got := b.Charges(m); got != int64(len(tt.want)) {
+				t.Errorf("Charges(%s) = %d, want %d", tt.month, got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestPlanCosts(t *testing.T) {
+	end := "05-2025"
+	yearly := entities.Subscriptions{ServiceName: "PlayStation Plus", AmountMinor: 1200, StartDate: "03-2025",
+		BillingPeriod: entities.BillingYear}
+	quarterly := entities.Subscriptions{ServiceName: "Amediateka", AmountMinor: 100, StartDate: "01-2025", EndDate: &end,
+		BillingPeriod: entities.BillingQuarter}
+	anchored := entities.Subscriptions{ServiceName: "Amediateka", AmountMinor: 90, StartDate: "02-2025",
+		BillingPeriod: entities.BillingQuarter, BillingAnchor: ptr("01-2025")}
+	weekly := entities.Subscriptions{ServiceName: "Gym", AmountMinor: 70, StartDate: "01-2025", BillingPeriod: entities.BillingWeek}
+	priced := entities.Subscriptions{ServiceName: "Netflix", AmountMinor: 100, StartDate: "01-2025",
+		Prices: []entities.PriceChange{{EffectiveFrom: "03-2025", AmountMinor: 200}}}
+
+	tests := []struct {
+		name        string
+		sub         entities.Subscriptions
+		first, last string
+		basis       string
+		amounts     []int64
+		charged     []bool
+	}{
+		{name: "cash charges only in charge months", sub: yearly, first: "01-2026", last: "03-2026", basis: entities.BasisCash,
+			amounts: []int64{0, 0, 1200}, charged: []bool{false, false, true}},
+		// списание 03-2025 находится за 10 месяцев до периода и все равно начисляется на него
+		{name: "accrual looks back to earlier charges", sub: yearly, first: "01-2026", last: "03-2026", basis: entities.BasisAccrual,
+			amounts: []int64{100, 100, 100}, charged: []bool{true, true, true}},
+		// списание 01-2025 по опорному месяцу сделано до начала подписки и не учитывается
+		{name: "accrual ignores charges before start", sub: anchored, first: "02-2025", last: "04-2025", basis: entities.BasisAccrual,
+			amounts: []int64{0, 0, 30}, charged: []bool{false, false, true}},
+		// части округляются вниз по нарастающему итогу: 33, 33 и 34
+		{name: "accrual spreads charge", sub: quarterly, first: "01-2025", last: "03-2025", basis: entities.BasisAccrual,
+			amounts: []int64{33, 33, 34}, charged: []bool{true, true, true}},
+		// списание 04-2025 оплачивает и 06-2025, когда подписка уже закончилась
+		{name: "accrual over partially active period", sub: quarterly, first: "04-2025", last: "05-2025", basis: entities.BasisAccrual,
+			amounts: []int64{50, 50}, charged: []bool{true, true}},
+		{name: "cash over partially active period", sub: quarterly, first: "04-2025", last: "05-2025", basis: entities.BasisCash,
+			amounts: []int64{100, 0}, charged: []bool{true, false}},
+		// 29.01 - 4 дня февраля из 7, 26.02 - 3 дня февраля из 7
+		{name: "weekly accrual by days", sub: weekly, first: "02-2025", last: "02-2025", basis: entities.BasisAccrual,
+			amounts: []int64{40 + 3*70 + 30}, charged: []bool{true}},
+		{name: "weekly cash", sub: weekly, first: "02-2025", last: "02-2025", basis: entities.BasisCash,
+			amounts: []int64{4 * 70}, charged: []bool{true}},
+		{name: "price history", sub: priced, first: "02-2025", last: "03-2025", basis: entities.BasisCash,
+			amounts: []int64{100, 200}, charged: []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parsePlan(t, tt.sub)
+			amounts, charged := p.costs(month(t, tt.first), month(t, tt.last), tt.basis)
+			if !reflect.DeepEqual(amounts, tt.amounts) || !reflect.DeepEqual(charged, tt.charged) {
+				t.Errorf("costs = %v %v, want %v %v", amounts, charged, tt.amounts, tt.charged)
+			}
+		})
+	}
+}
+
+func TestAccrue(t *testing.T) {
+	end := "01-2025"
+	active := parsePlan(t, entities.Subscriptions{ServiceName: "Gym", AmountMinor: 70, StartDate: "01-2025",
+		BillingPeriod: entities.BillingWeek})
+	ended := parsePlan(t, entities.Subscriptions{ServiceName: "Gym", AmountMinor: 70, StartDate: "01-2025", EndDate: &end,
+		BillingPeriod: entities.BillingWeek})
+	jan, feb := month(t, "01-2025"), month(t, "02-2025")
+	shares := []share{{month: jan, weight: 3}, {month: feb, weight: 4}}
+
+	tests := []struct {
+		name  string
+		p     plan
+		price int64
+		want  []accrual
+	}{
+		{name: "by weights", p: active, price: 70, want: []accrual{{month: jan, amount: 30}, {month: feb, amount: 40}}},
+		{name: "rounds down by running total", p: active, price: 10, want: []accrual{{month: jan, amount: 4}, {month: feb, amount: 6}}},
+		{name: "inactive month gets nothing", p: ended, price: 70, want: []accrual{{month: jan, amount: 70}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.accrue(tt.price, shares); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("accrue(%d) = %+v, want %+v", tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter entities.CostFilter
+		want   Query
+		field  string
+	}{
+		{name: "defaults", filter: entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"},
+			want: Query{From: 2025*12 + 0, To: 2025*12 + 11, Basis: entities.BasisCash, Currency: entities.BaseCurrency}},
+		{name: "widest period", filter: entities.CostFilter{StartPeriod: "01-0001", EndPeriod: "12-9999"},
+			want: Query{From: 12, To: 9999*12 + 11, Basis: entities.BasisCash, Currency: entities.BaseCurrency}},
+		{name: "accrual in USD", filter: entities.CostFilter{StartPeriod: "02-2025", EndPeriod: "02-2025", Basis: entities.BasisAccrual,
+			Currency: entities.CurrencyUSD},
+			want: Query{From: 2025*12 + 1, To: 2025*12 + 1, Basis: entities.BasisAccrual, Currency: entities.CurrencyUSD}},
+		{name: "month zero", filter: entities.CostFilter{StartPeriod: "00-2025", EndPeriod: "12-2025"}, field: "start_period"},
+		{name: "month thirteen", filter: entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "13-2025"}, field: "end_period"},
+		{name: "wrong format", filter: entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "2025-12"}, field: "end_period"},
+		{name: "unknown basis", filter: entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025", Basis: "daily"}, field: "basis"},
+		{name: "unknown currency", filter: entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025", Currency: "GBP"}, field: "currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			got, err := ParseFilter(&filter)
+			if tt.field != "" {
+				if !errors.Is(err, entities.ErrValidation) || !strings.HasPrefix(err.Error(), tt.field+": ") {
+					t.Fatalf("ParseFilter: got %v, want validation error for %s", err, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilter: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,126 @@
+package cost
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"tz_effective/internal/entities"
+)
+
+// Rates курсы валют к entities.BaseCurrency по месяцам. Курс действует только в своем месяце.
+type Rates map[string][]rate
+
+type rate struct {
+	month  entities.Month
+	value  *big.Rat
+	source entities.ExchangeRate
+}
+
+// NewRates разбирает курсы валют
+func NewRates(rates []entities.ExchangeRate) (Rates, error) {
+	res := make(Rates)
+	for _, r := range rates {
+		month, err := entities.ParseMonth(r.Month)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate %s: %w", r.Currency, err)
+		}
+		value, err := entities.ParseRate(r.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate %s for %s: %w", r.Currency, r.Month, err)
+		}
+		res[r.Currency] = append(res[r.Currency], rate{month: month, value: value, source: r})
+	}
+	for _, list := range res {
+		sort.Slice(list, func(i, j int) bool { return list[i].month < list[j].month })
+	}
+	return res, nil
+}
+
+// at возвращает курс валюты за месяц m
+func (r Rates) at(currency string, m entities.Month) (rate, bool) {
+	list := r[currency]
+	i := sort.Search(len(list), func(i int) bool { return list[i].month >= m })
+	if i == len(list) || list[i].month != m {
+		return rate{}, false
+	}
+	return list[i], true
+}
+
+// converter пересчитывает суммы в валюту запроса и запоминает использованные курсы
+type converter struct {
+	currency string
+	rates    Rates
+	applied  map[entities.ExchangeRate]entities.Month
+}
+
+func newConverter(currency string, rates Rates) *converter {
+	return &converter{currency: currency, rates: rates, applied: make(map[entities.ExchangeRate]entities.Month)}
+}
+
+// convert пересчитывает сумму amount в валюте from в валюту запроса по курсам месяца m.
+// Результат округляется до минимальной единицы валюты, половина - от нуля.
+func (c *converter) convert(amount int64, from string, m entities.Month) (int64, error) {
+	if from == c.currency {
+		return amount, nil
+	}
+	fromRate, err := c.rate(from, m)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := c.rate(c.currency, m)
+	if err != nil {
+		return 0, err
+	}
+
+	value := new(big.Rat).SetInt64(amount)
+	value.Mul(value, fromRate).Quo(value, toRate)
+	return round(value), nil
+}
+
+// rate возвращает курс валюты к entities.BaseCurrency в месяце m
+func (c *converter) rate(currency string, m entities.Month) (*big.Rat, error) {
+	if currency == entities.BaseCurrency {
+		return big.NewRat(1, 1), nil
+	}
+	r, ok := c.rates.at(currency, m)
+	if !ok {
+		return nil, NoRateError(currency, m)
+	}
+	c.applied[r.source] = r.month
+	return r.value, nil
+}
+
+// NoRateError ошибка пересчета суммы, для которого нет курса валюты за месяц m
+func NoRateError(currency string, m entities.Month) error {
+	return &entities.ValidationError{Fields: []entities.FieldError{{
+		Field:   "currency",
+		Message: fmt.Sprintf("no exchange rate for %s in %s", currency, m),
+	}}}
+}
+
+// used возвращает примененные курсы по валюте и месяцу; nil, если пересчет не понадобился
+func (c *converter) used() []entities.ExchangeRate {
+	if len(c.applied) == 0 {
+		return nil
+	}
+	rates := make([]entities.ExchangeRate, 0, len(c.applied))
+	for r := range c.applied {
+		rates = append(rates, r)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Currency != rates[j].Currency {
+			return rates[i].Currency < rates[j].Currency
+		}
+		return c.applied[rates[i]] < c.applied[rates[j]]
+	})
+	return rates
+}
+
+// round округляет число до целого, половину - от нуля
+func round(r *big.Rat) int64 {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Num().Sign())))
+	}
+	return q.Int64()
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// IdempotencyStore хранилище ключей идемпотентности
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey сохраняет ключ без ответа или возвращает неистекшую запись по нему
+	ReserveIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) (*entities.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, rec *entities.IdempotencyRecord) error
+	// ReleaseIdempotencyKey удаляет ключ без ответа, чтобы запрос можно было повторить
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+}
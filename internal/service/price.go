@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// SchedulePriceChange планирует изменение цены подписки с указанного месяца.
+// Изменение с того же месяца заменяется.
+func (s *Service) SchedulePriceChange(ctx context.Context, id int64, change *entities.PriceChange, version int64) (*entities.Subscriptions, error) {
+	if err := validatePriceChange(change); err != nil {
+		return nil, err
+	}
+	slog.Info("Scheduling price change", "id", id, "effective_from", change.EffectiveFrom, "amount_minor", change.AmountMinor)
+	return s.storage.SetPriceChange(ctx, id, change, version, checkPriceChange(change))
+}
+
+// CancelPriceChange отменяет изменение цены подписки с месяца effectiveFrom (MM-YYYY)
+func (s *Service) CancelPriceChange(ctx context.Context, id int64, effectiveFrom string, version int64) (*entities.Subscriptions, error) {
+	if _, err := entities.ParseMonth(effectiveFrom); err != nil {
+		verr := &entities.ValidationError{}
+		verr.Add("effective_from", "expected format MM-YYYY")
+		return nil, verr
+	}
+	slog.Info("Cancelling price change", "id", id, "effective_from", effectiveFrom)
+	return s.storage.DeletePriceChange(ctx, id, effectiveFrom, version)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"tz_effective/internal/entities"
+)
+
+// ListExchangeRates возвращает курсы валют по валюте и месяцу
+func (s *Service) ListExchangeRates(ctx context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error) {
+	return s.storage.ListExchangeRates(ctx, filter)
+}
+
+// SetExchangeRate сохраняет курс валюты за месяц, заменяя прежний. Возвращает курс в сохраненном виде.
+func (s *Service) SetExchangeRate(ctx context.Context, rate *entities.ExchangeRate) (*entities.ExchangeRate, error) {
+	if err := validateExchangeRate(rate); err != nil {
+		return nil, err
+	}
+	stored := normalizeExchangeRate(*rate)
+	slog.Info("Setting exchange rate", "currency", stored.Currency, "month", stored.Month, "rate", stored.Rate)
+	if err := s.storage.SetExchangeRates(ctx, []entities.ExchangeRate{stored}); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// ImportExchangeRates проверяет курсы из строк CSV и сохраняет их все вместе или ни одного.
+// Ошибки перечисляются по полям с номером строки в сообщении.
+func (s *Service) ImportExchangeRates(ctx context.Context, rows []entities.ExchangeRateRow) ([]entities.ExchangeRate, error) {
+	verr := &entities.ValidationError{}
+	rates := make([]entities.ExchangeRate, 0, len(rows))
+	seen := make(map[entities.ExchangeRate]int, len(rows))
+
+	for _, row := range rows {
+		var rowErr *entities.ValidationError
+		if errors.As(validateExchangeRate(&row.Rate), &rowErr) {
+			for _, f := range rowErr.Fields {
+				verr.Add(f.Field, fmt.Sprintf("line %d: %s", row.Line, f.Message))
+			}
+			continue
+		}
+
+		rate := normalizeExchangeRate(row.Rate)
+		key := entities.ExchangeRate{Currency: rate.Currency, Month: rate.Month}
+		if line, ok := seen[key]; ok {
+			verr.Add("month", fmt.Sprintf("line %d: duplicates the %s rate for %s from line %d", row.Line, rate.Currency, rate.Month, line))
+			continue
+		}
+		seen[key] = row.Line
+		rates = append(rates, rate)
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Importing exchange rates", "count", len(rates))
+	if err := s.storage.SetExchangeRates(ctx, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// DeleteExchangeRate удаляет курс валюты за месяц (MM-YYYY)
+func (s *Service) DeleteExchangeRate(ctx context.Context, currency, month string) error {
+	if _, err := entities.ParseMonth(month); err != nil {
+		verr := &entities.ValidationError{}
+		verr.Add("month", "expected format MM-YYYY")
+		return verr
+	}
+	slog.Info("Deleting exchange rate", "currency", currency, "month", month)
+	return s.storage.DeleteExchangeRate(ctx, currency, month)
+}
+
+// normalizeExchangeRate приводит месяц и курс проверенного курса валюты к виду, в котором их возвращает хранилище
+func normalizeExchangeRate(rate entities.ExchangeRate) entities.ExchangeRate {
+	if month, err := entities.ParseMonth(rate.Month); err == nil {
+		rate.Month = month.String()
+	}
+	if value, err := entities.NormalizeRate(rate.Rate); err == nil {
+		rate.Rate = value
+	}
+	return rate
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"tz_effective/deploy/config"
 	"tz_effective/internal/entities"
@@ -20,6 +21,9 @@ func NewService(storage Storage, cfg *config.Config) *Service {
 }
 
 func (s *Service) CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (int64, error) {
+	if err := validateSubscription(sub); err != nil {
+		return 0, err
+	}
 	return s.storage.CreateSubscription(ctx, sub)
 }
 
@@ -27,19 +31,134 @@ func (s *Service) GetSubscription(ctx context.Context, id int64) (*entities.Subs
 	return s.storage.GetSubscription(ctx, id)
 }
 
-func (s *Service) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions) error {
-	return s.storage.UpdateSubscription(ctx, id, sub)
+func (s *Service) UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (int64, error) {
+	if err := validateSubscription(sub); err != nil {
+		return 0, err
+	}
+	return s.storage.UpdateSubscription(ctx, id, sub, version)
+}
+
+// PatchSubscription частично обновляет подписку. Бизнес-правила проверяются для подписки
+// после применения патча, поэтому, например, end_date сверяется с сохраненной start_date.
+func (s *Service) PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64) (*entities.Subscriptions, error) {
+	if err := validatePatchAmount(patch); err != nil {
+		return nil, err
+	}
+	return s.storage.PatchSubscription(ctx, id, patch, version, validateSubscription)
 }
 
-func (s *Service) DeleteSubscription(ctx context.Context, id int64) error {
-	return s.storage.DeleteSubscription(ctx, id)
+func (s *Service) DeleteSubscription(ctx context.Context, id int64, version int64) error {
+	return s.storage.DeleteSubscription(ctx, id, version)
 }
 
-func (s *Service) ListSubscriptions(ctx context.Context, filter *entities.ListFilter) ([]entities.Subscriptions, error) {
+// ApplyBatch проверяет операции пакета и передает хранилищу только корректные
+func (s *Service) ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error) {
+	results := make([]entities.BatchResult, len(ops))
+	valid := make([]entities.BatchOperation, 0, len(ops))
+	index := make([]int, 0, len(ops))
+	for i := range ops {
+		if err := validateBatchOperation(&ops[i]); err != nil {
+			failed := entities.BatchResult{ID: ops[i].ID, Err: err}
+			if atomic {
+				return entities.AbortBatch(ops, failed, i), nil
+			}
+			results[i] = failed
+			continue
+		}
+		valid = append(valid, ops[i])
+		index = append(index, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	slog.Info("Applying batch", "operations", len(valid), "atomic", atomic)
+	applied, err := s.storage.ApplyBatch(ctx, valid, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, res := range applied {
+		results[index[j]] = res
+	}
+	return results, nil
+}
+
+// ImportSubscriptions проверяет строки импорта правилами подписки и сохраняет принятые одной операцией.
+// Отклоненные строки попадают в отчет с причинами. При dryRun ничего не сохраняется.
+func (s *Service) ImportSubscriptions(ctx context.Context, rows []entities.ImportRow, dryRun bool) (*entities.ImportReport, error) {
+	report := &entities.ImportReport{
+		DryRun:   dryRun,
+		Total:    len(rows),
+		Accepted: []entities.ImportedRow{},
+		Rejected: []entities.RejectedRow{},
+	}
+
+	var accepted []entities.Subscriptions
+	for _, row := range rows {
+		fields := row.Errors
+		if len(fields) == 0 {
+			var verr *entities.ValidationError
+			if errors.As(validateSubscription(&row.Subscription), &verr) {
+				fields = verr.Fields
+			}
+		}
+		if len(fields) > 0 {
+			report.Rejected = append(report.Rejected, entities.RejectedRow{Line: row.Line, Errors: fields})
+			continue
+		}
+		accepted = append(accepted, row.Subscription)
+		report.Accepted = append(report.Accepted, entities.ImportedRow{Line: row.Line})
+	}
+
+	slog.Info("Importing subscriptions", "total", len(rows), "accepted", len(accepted), "dry_run", dryRun)
+	if dryRun || len(accepted) == 0 {
+		return report, nil
+	}
+
+	ids, err := s.storage.ImportSubscriptions(ctx, accepted)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		report.Accepted[i].ID = id
+	}
+	return report, nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error) {
+	if err := scopePriceFilter(filter); err != nil {
+		return nil, err
+	}
 	return s.storage.ListSubscriptions(ctx, filter)
 }
 
-func (s *Service) CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (int64, error) {
+func (s *Service) ExportSubscriptions(ctx context.Context, filter *entities.ListFilter, fn func(*entities.Subscriptions) error) error {
+	if err := scopePriceFilter(filter); err != nil {
+		return err
+	}
+	slog.Info("Exporting subscriptions", "filter", filter)
+	return s.storage.ExportSubscriptions(ctx, filter, fn)
+}
+
+// ListEvents возвращает страницу журнала изменений подписок
+func (s *Service) ListEvents(ctx context.Context, filter *entities.EventFilter) (*entities.EventPage, error) {
+	return s.storage.ListEvents(ctx, filter)
+}
+
+func (s *Service) CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error) {
 	slog.Info("Calculating total cost", "filter", filter)
 	return s.storage.CalculateTotalCost(ctx, filter)
 }
+
+func (s *Service) CalculateCostBreakdown(ctx context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error) {
+	slog.Info("Calculating cost breakdown", "filter", filter)
+	if err := validateBreakdownPeriod(filter); err != nil {
+		return nil, err
+	}
+	return s.storage.CalculateCostBreakdown(ctx, filter)
+}
+
+func (s *Service) CalculateGroupedCost(ctx context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error) {
+	slog.Info("Calculating grouped cost", "filter", filter, "group_by", groupBy)
+	return s.storage.CalculateGroupedCost(ctx, filter, groupBy)
+}
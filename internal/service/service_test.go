@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"tz_effective/deploy/config"
+	"tz_effective/internal/adaper/storage/memory"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	endBeforeStart := "01-2025"
+
+	tests := []struct {
+		name  string
+		sub   entities.Subscriptions
+		field string
+	}{
+		{name: "empty service_name", sub: entities.Subscriptions{ServiceName: " ", Price: 100, StartDate: "02-2025"}, field: "service_name"},
+		{name: "negative price", sub: entities.Subscriptions{ServiceName: "Netflix", Price: -1, StartDate: "02-2025"}, field: "price"},
+		{name: "invalid start_date", sub: entities.Subscriptions{ServiceName: "Netflix", Price: 100, StartDate: "2025-02"}, field: "start_date"},
+		{name: "end_date before start_date", sub: entities.Subscriptions{ServiceName: "Netflix", Price: 100, StartDate: "02-2025", EndDate: &endBeforeStart}, field: "end_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewService(memory.New(), &config.Config{})
+
+			sub := tt.sub
+			_, err := svc.CreateSubscription(context.Background(), &sub)
+			if !errors.Is(err, entities.ErrValidation) {
+				t.Fatalf("CreateSubscription: got %v, want ErrValidation", err)
+			}
+
+			var verr *entities.ValidationError
+			if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != tt.field {
+				t.Fatalf("CreateSubscription: got %v, want single error for field %s", err, tt.field)
+			}
+			if _, err := svc.UpdateSubscription(context.Background(), 1, &sub, entities.AnyVersion); !errors.Is(err, entities.ErrValidation) {
+				t.Fatalf("UpdateSubscription: got %v, want ErrValidation", err)
+			}
+		})
+	}
+}
+
+func TestApplyBatchValidation(t *testing.T) {
+	valid := entities.Subscriptions{ServiceName: "Netflix", Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "02-2025"}
+	invalid := valid
+	invalid.Price = -1
+	ops := []entities.BatchOperation{
+		{Op: entities.BatchCreate, Subscription: &valid},
+		{Op: "upsert", Subscription: &valid},
+		{Op: entities.BatchUpdate, ID: 1, Subscription: &invalid},
+		{Op: entities.BatchDelete},
+	}
+	wantFields := []string{"", "op", "subscription.price", "id"}
+
+	svc := service.NewService(memory.New(), &config.Config{})
+	results, err := svc.ApplyBatch(context.Background(), ops, false)
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	for i, want := range wantFields {
+		var verr *entities.ValidationError
+		switch {
+		case want == "" && results[i].Err != nil:
+			t.Errorf("result %d: unexpected error %v", i, results[i].Err)
+		case want != "" && (!errors.As(results[i].Err, &verr) || verr.Fields[0].Field != want):
+			t.Errorf("result %d: got %v, want validation error for field %s", i, results[i].Err, want)
+		}
+	}
+
+	// В атомарном режиме невалидная операция отменяет пакет до обращения к хранилищу
+	results, err = svc.ApplyBatch(context.Background(), ops, true)
+	if err != nil {
+		t.Fatalf("ApplyBatch(atomic): %v", err)
+	}
+	if !errors.Is(results[0].Err, entities.ErrBatchAborted) || !errors.Is(results[1].Err, entities.ErrValidation) {
+		t.Fatalf("ApplyBatch(atomic) results = %+v", results)
+	}
+	page, err := svc.ListSubscriptions(context.Background(), &entities.ListFilter{})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("stored %d subscriptions, want 1 from the non-atomic batch", len(page.Items))
+	}
+}
+
+func TestSubscriptionAmount(t *testing.T) {
+	valid := entities.Subscriptions{ServiceName: "Netflix", UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: "02-2025"}
+	tests := []struct {
+		name        string
+		price       int64
+		amountMinor int64
+		want        int64
+		field       string
+	}{
+		{name: "price in major units", price: 999, want: 99900},
+		{name: "amount_minor", amountMinor: 99950, want: 99950},
+		{name: "both agree", price: 999, amountMinor: 99950, want: 99950},
+		{name: "both disagree", price: 1000, amountMinor: 99950, field: "price"},
+		{name: "negative amount_minor", amountMinor: -1, field: "amount_minor"},
+		{name: "price overflows minor units", price: math.MaxInt64 / 10, field: "price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewService(memory.New(), &config.Config{})
+
+			sub := valid
+			sub.Price, sub.AmountMinor = tt.price, tt.amountMinor
+			id, err := svc.CreateSubscription(context.Background(), &sub)
+			if tt.field != "" {
+				var verr *entities.ValidationError
+				if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != tt.field {
+					t.Fatalf("CreateSubscription: got %v, want single error for field %s", err, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateSubscription: %v", err)
+			}
+
+			stored, err := svc.GetSubscription(context.Background(), id)
+			if err != nil {
+				t.Fatalf("GetSubscription: %v", err)
+			}
+			if stored.AmountMinor != tt.want || stored.Price != tt.want/100 {
+				t.Errorf("stored price = %d/%d, want %d", stored.Price, stored.AmountMinor, tt.want)
+			}
+		})
+	}
+}
+
+func TestListPriceFilterCurrency(t *testing.T) {
+	svc := service.NewService(memory.New(), &config.Config{})
+	for _, sub := range []entities.Subscriptions{
+		{ServiceName: "Netflix", Price: 999, StartDate: "02-2025"},
+		{ServiceName: "Spotify", Price: 999, Currency: entities.CurrencyUSD, StartDate: "02-2025"},
+	} {
+		sub := sub
+		sub.UserID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+		if _, err := svc.CreateSubscription(context.Background(), &sub); err != nil {
+			t.Fatalf("CreateSubscription: %v", err)
+		}
+	}
+
+	// без фильтра по валюте цена сравнивается в рублях
+	minPrice := int64(999)
+	page, err := svc.ListSubscriptions(context.Background(), &entities.ListFilter{MinPrice: &minPrice})
+	if err != nil || len(page.Items) != 1 || page.Items[0].ServiceName != "Netflix" {
+		t.Fatalf("ListSubscriptions(min_price): got %+v, %v", page, err)
+	}
+
+	filter := &entities.ListFilter{MinPrice: &minPrice, Currencies: []string{entities.CurrencyRUB, entities.CurrencyUSD}}
+	if _, err := svc.ListSubscriptions(context.Background(), filter); !errors.Is(err, entities.ErrValidation) {
+		t.Fatalf("ListSubscriptions(min_price, two currencies): got %v, want ErrValidation", err)
+	}
+
+	filter = &entities.ListFilter{Currencies: []string{entities.CurrencyRUB, entities.CurrencyUSD}}
+	if page, err := svc.ListSubscriptions(context.Background(), filter); err != nil || len(page.Items) != 2 {
+		t.Fatalf("ListSubscriptions(two currencies): got %+v, %v", page, err)
+	}
+}
@@ -2,14 +2,43 @@ package service
 
 import (
 	"context"
+	"time"
 	"tz_effective/internal/entities"
 )
 
+// Storage хранилище подписок. Изменения с version проверяют версию (entities.AnyVersion - без проверки)
+// и в той же транзакции записываются в журнал.
 type Storage interface {
 	CreateSubscription(ctx context.Context, sub *entities.Subscriptions) (int64, error)
+	// GetSubscription возвращает подписку с историей цен; подписки в корзине не находятся
 	GetSubscription(ctx context.Context, id int64) (*entities.Subscriptions, error)
-	UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions) error
-	DeleteSubscription(ctx context.Context, id int64) error
-	ListSubscriptions(ctx context.Context, filter *entities.ListFilter) ([]entities.Subscriptions, error)
-	CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (int64, error)
+	// UpdateSubscription возвращает новую версию подписки
+	UpdateSubscription(ctx context.Context, id int64, sub *entities.Subscriptions, version int64) (int64, error)
+	// PatchSubscription сохраняет поля патча, только если check не вернул ошибку для результата
+	PatchSubscription(ctx context.Context, id int64, patch *entities.SubscriptionPatch, version int64, check func(*entities.Subscriptions) error) (*entities.Subscriptions, error)
+	// DeleteSubscription переносит подписку в корзину
+	DeleteSubscription(ctx context.Context, id int64, version int64) error
+	RestoreSubscription(ctx context.Context, id int64, version int64) (*entities.Subscriptions, error)
+	// PurgeDeletedSubscriptions удаляет подписки, перенесенные в корзину не позже before, не трогая журнал
+	PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int64, error)
+	// SetPriceChange добавляет или заменяет изменение цены с того же месяца, если check не вернул ошибку
+	SetPriceChange(ctx context.Context, id int64, change *entities.PriceChange, version int64, check func(*entities.Subscriptions) error) (*entities.Subscriptions, error)
+	DeletePriceChange(ctx context.Context, id int64, effectiveFrom string, version int64) (*entities.Subscriptions, error)
+	// ApplyBatch возвращает результат каждой операции и ошибку, только если пакет не выполнен целиком
+	ApplyBatch(ctx context.Context, ops []entities.BatchOperation, atomic bool) ([]entities.BatchResult, error)
+	// ImportSubscriptions сохраняет все подписки или ни одной и возвращает их ID по порядку
+	ImportSubscriptions(ctx context.Context, subs []entities.Subscriptions) ([]int64, error)
+	// ListSubscriptions выбирает подписки из корзины только при filter.Deleted
+	ListSubscriptions(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error)
+	// ExportSubscriptions передает fn подписки по фильтру без разбивки на страницы и истории цен
+	ExportSubscriptions(ctx context.Context, filter *entities.ListFilter, fn func(*entities.Subscriptions) error) error
+	ListEvents(ctx context.Context, filter *entities.EventFilter) (*entities.EventPage, error)
+	ListExchangeRates(ctx context.Context, filter *entities.ExchangeRateFilter) ([]entities.ExchangeRate, error)
+	// SetExchangeRates сохраняет все курсы или ни одного, заменяя курсы тех же валют и месяцев
+	SetExchangeRates(ctx context.Context, rates []entities.ExchangeRate) error
+	DeleteExchangeRate(ctx context.Context, currency, month string) error
+	// CalculateTotalCost сам загружает курсы для пересчета в filter.Currency
+	CalculateTotalCost(ctx context.Context, filter *entities.CostFilter) (*entities.TotalCostResponse, error)
+	CalculateCostBreakdown(ctx context.Context, filter *entities.CostFilter) (*entities.CostBreakdownResponse, error)
+	CalculateGroupedCost(ctx context.Context, filter *entities.CostFilter, groupBy []string) ([]entities.GroupedCost, error)
 }
@@ -0,0 +1,90 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+// CurrencyMigration доступ к схеме SQL-хранилища для проверки миграции валют
+type CurrencyMigration struct {
+	Storage  service.Storage                        // Хранилище на последней версии схемы
+	Down     func(t *testing.T) error               // Откатывает миграцию валют
+	Up       func(t *testing.T) service.Storage     // Применяет миграцию снова и возвращает хранилище
+	Exec     func(t *testing.T, query string)       // Выполняет SQL на текущей схеме
+	QueryInt func(t *testing.T, query string) int64 // Возвращает число, выбранное SQL на текущей схеме
+}
+
+// RunCurrencyMigration проверяет, что откат миграции валют отказывается терять валюты и копейки,
+// а миграция не меняет журнал: снимки, записанные до нее, читаются с ценами в минимальных единицах
+func RunCurrencyMigration(t *testing.T, m CurrencyMigration) {
+	ctx := context.Background()
+	s := m.Storage
+	accept := func(*entities.Subscriptions) error { return nil }
+
+	sub := entities.Subscriptions{ServiceName: "Netflix", AmountMinor: 129900, Currency: entities.CurrencyUSD,
+		UserID: userA, StartDate: "01-2025"}
+	id := create(t, s, sub)
+	if _, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "06-2025", AmountMinor: 139900}, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("SetPriceChange: %v", err)
+	}
+	if err := m.Down(t); err == nil {
+		t.Fatal("Down with USD subscription succeeded, want error")
+	}
+
+	sub.Currency, sub.AmountMinor = entities.CurrencyRUB, 129950
+	if _, err := s.UpdateSubscription(ctx, id, &sub, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	if err := m.Down(t); err == nil {
+		t.Fatal("Down with fractional amount succeeded, want error")
+	}
+
+	sub.AmountMinor = 129900
+	if _, err := s.UpdateSubscription(ctx, id, &sub, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	want := listEvents(t, s, entities.EventFilter{})
+	if err := m.Down(t); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if price := m.QueryInt(t, `SELECT price FROM subscriptions`); price != 1299 {
+		t.Errorf("price after down = %d, want 1299", price)
+	}
+	if price := m.QueryInt(t, `SELECT price FROM subscription_prices`); price != 1399 {
+		t.Errorf("price change after down = %d, want 1399", price)
+	}
+
+	// так выглядят снимки журнала до миграции валют: цены в рублях, без amount_minor и currency
+	m.Exec(t, fmt.Sprintf(`INSERT INTO subscription_events (subscription_id, action, before_data, after_data, created_at) VALUES (%[1]d, 'update',
+		'{"id": %[1]d, "service_name": "Netflix", "price": 1299, "user_id": "%[2]s", "start_date": "01-2025", "billing_period": "quarter", "version": 4}',
+		'{"id": %[1]d, "service_name": "Netflix", "price": 1500, "user_id": "%[2]s", "start_date": "01-2025", "billing_period": "quarter", "version": 5,
+			"prices": [{"effective_from": "06-2025", "price": 1600}]}',
+		'2025-01-01T00:00:00.000000Z')`, id, userA))
+
+	s = m.Up(t)
+	got := listEvents(t, s, entities.EventFilter{})
+	if len(got) != len(want)+1 || !reflect.DeepEqual(got[:len(want)], want) {
+		t.Fatalf("events after migration = %+v, want %+v and a legacy event", got, want)
+	}
+	legacy := got[len(want)]
+	if legacy.Before.AmountMinor != 129900 || legacy.Before.Price != 1299 || legacy.Before.MonthlyAmountMinor != 43300 ||
+		legacy.Before.Currency != entities.CurrencyRUB {
+		t.Errorf("legacy snapshot before = %+v, want amounts in kopecks", legacy.Before)
+	}
+	if legacy.After.AmountMinor != 150000 || len(legacy.After.Prices) != 1 || legacy.After.Prices[0].AmountMinor != 160000 ||
+		legacy.After.Prices[0].Price != 1600 {
+		t.Errorf("legacy snapshot after = %+v, want amounts in kopecks", legacy.After)
+	}
+
+	stored, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if stored.AmountMinor != 129900 || stored.Price != 1299 || len(stored.Prices) != 1 || stored.Prices[0].AmountMinor != 139900 {
+		t.Errorf("subscription after migration = %+v", stored)
+	}
+}
@@ -0,0 +1,1557 @@
+// Package storagetest содержит набор тестов, проверяющих соответствие хранилища
+// контракту service.Storage. Любой адаптер хранилища запускает его из своих тестов:
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) service.Storage { return memory.New() })
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"tz_effective/internal/entities"
+	"tz_effective/internal/service"
+)
+
+// Factory создает пустое хранилище для одного теста
+type Factory func(t *testing.T) service.Storage
+
+const (
+	userA = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	userB = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+)
+
+// fixtures набор подписок, периоды которых пересекают границу года
+func fixtures() []entities.Subscriptions {
+	return []entities.Subscriptions{
+		{ServiceName: "Yandex Plus", AmountMinor: 299, UserID: userA, StartDate: "01-2025", EndDate: ptr("07-2025")},
+		{ServiceName: "Netflix", AmountMinor: 999, UserID: userA, StartDate: "02-2025"},
+		{ServiceName: "Spotify", AmountMinor: 199, UserID: userB, StartDate: "11-2024", EndDate: ptr("02-2025")},
+		{ServiceName: "Netflix", AmountMinor: 599, UserID: userB, StartDate: "06-2025", EndDate: ptr("01-2026")},
+		{ServiceName: "YouTube", AmountMinor: 349, UserID: userB, StartDate: "12-2025"},
+	}
+}
+
+// Run проверяет, что хранилище соблюдает контракт service.Storage
+func Run(t *testing.T, newStorage Factory) {
+	t.Run("CreateGetRoundTrip", func(t *testing.T) { testCreateGet(t, newStorage(t)) })
+	t.Run("UpdateRoundTrip", func(t *testing.T) { testUpdate(t, newStorage(t)) })
+	t.Run("Metadata", func(t *testing.T) { testMetadata(t, newStorage(t)) })
+	t.Run("Patch", func(t *testing.T) { testPatch(t, newStorage(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newStorage(t)) })
+	t.Run("Trash", func(t *testing.T) { testTrash(t, newStorage(t)) })
+	t.Run("Version", func(t *testing.T) { testVersion(t, newStorage(t)) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, newStorage(t)) })
+	t.Run("Prices", func(t *testing.T) { testPrices(t, newStorage(t)) })
+	t.Run("BatchAtomic", func(t *testing.T) { testBatchAtomic(t, newStorage(t)) })
+	t.Run("BatchPartial", func(t *testing.T) { testBatchPartial(t, newStorage(t)) })
+	t.Run("Import", func(t *testing.T) { testImport(t, newStorage(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newStorage(t)) })
+	t.Run("InvalidDates", func(t *testing.T) { testInvalidDates(t, newStorage(t)) })
+	t.Run("ListFilter", func(t *testing.T) { testListFilter(t, newStorage(t)) })
+	t.Run("Pagination", func(t *testing.T) { testPagination(t, newStorage(t)) })
+	t.Run("Export", func(t *testing.T) { testExport(t, newStorage(t)) })
+	t.Run("SearchUnicode", func(t *testing.T) { testSearchUnicode(t, newStorage(t)) })
+	t.Run("TotalCost", func(t *testing.T) { testTotalCost(t, newStorage(t)) })
+	t.Run("CostBreakdown", func(t *testing.T) { testCostBreakdown(t, newStorage(t)) })
+	t.Run("GroupedCost", func(t *testing.T) { testGroupedCost(t, newStorage(t)) })
+	t.Run("Billing", func(t *testing.T) { testBilling(t, newStorage(t)) })
+	t.Run("Basis", func(t *testing.T) { testBasis(t, newStorage(t)) })
+	t.Run("Currency", func(t *testing.T) { testCurrency(t, newStorage(t)) })
+	t.Run("Idempotency", func(t *testing.T) {
+		store, ok := newStorage(t).(service.IdempotencyStore)
+		if !ok {
+			t.Skip("storage does not implement service.IdempotencyStore")
+		}
+		testIdempotency(t, store)
+	})
+}
+
+func testCreateGet(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+
+	for _, want := range fixtures() {
+		want := want
+		id, err := s.CreateSubscription(ctx, &want)
+		if err != nil {
+			t.Fatalf("CreateSubscription(%s): %v", want.ServiceName, err)
+		}
+		if id <= 0 {
+			t.Fatalf("CreateSubscription(%s) returned id %d, want positive", want.ServiceName, id)
+		}
+
+		got, err := s.GetSubscription(ctx, id)
+		if err != nil {
+			t.Fatalf("GetSubscription(%d): %v", id, err)
+		}
+		assertSubscription(t, got, &want)
+	}
+}
+
+func testUpdate(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[1])
+
+	closed := entities.Subscriptions{
+		ServiceName: "Netflix Premium",
+		AmountMinor: 1299,
+		UserID:      userB,
+		StartDate:   "03-2025",
+		EndDate:     ptr("02-2026"),
+	}
+	if _, err := s.UpdateSubscription(ctx, id, &closed, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	assertStored(t, s, id, &closed)
+
+	reopened := closed
+	reopened.EndDate = nil
+	if _, err := s.UpdateSubscription(ctx, id, &reopened, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription without end_date: %v", err)
+	}
+	assertStored(t, s, id, &reopened)
+}
+
+// testMetadata проверяет, что хранилище возвращает ID и отметки времени создания и изменения
+func testMetadata(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	ids := seed(t, s)
+
+	created, err := s.GetSubscription(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if created.ID != ids[1] {
+		t.Fatalf("ID = %d, want %d", created.ID, ids[1])
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.Before(created.CreatedAt) {
+		t.Fatalf("created_at = %v, updated_at = %v, want set and not before created_at", created.CreatedAt, created.UpdatedAt)
+	}
+
+	page, err := s.ListSubscriptions(ctx, &entities.ListFilter{UserIDs: []string{userA}})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	for i, sub := range page.Items {
+		if sub.ID != ids[i] || sub.CreatedAt.IsZero() {
+			t.Fatalf("list item %d: id = %d, created_at = %v, want id %d and created_at set", i, sub.ID, sub.CreatedAt, ids[i])
+		}
+	}
+
+	// разрешение отметок времени в хранилищах не выше микросекунды
+	time.Sleep(10 * time.Millisecond)
+
+	update := fixtures()[1]
+	update.AmountMinor = 1099
+	if _, err := s.UpdateSubscription(ctx, ids[1], &update, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+
+	updated, err := s.GetSubscription(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("GetSubscription after update: %v", err)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("created_at changed on update: %v -> %v", created.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Fatalf("updated_at = %v, want after %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+}
+
+func testPatch(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[0])
+	accept := func(*entities.Subscriptions) error { return nil }
+
+	want := fixtures()[0]
+	want.AmountMinor = 399
+	got, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{AmountMinor: ptr(int64(399))}, entities.AnyVersion, accept)
+	if err != nil {
+		t.Fatalf("PatchSubscription(price): %v", err)
+	}
+	assertSubscription(t, got, &want)
+	assertStored(t, s, id, &want)
+
+	want.EndDate = nil
+	if _, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{SetEndDate: true}, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("PatchSubscription(clear end_date): %v", err)
+	}
+	assertStored(t, s, id, &want)
+
+	want.EndDate = ptr("03-2026")
+	want.ServiceName = "Yandex Plus Multi"
+	patch := &entities.SubscriptionPatch{ServiceName: ptr("Yandex Plus Multi"), EndDate: ptr("03-2026"), SetEndDate: true}
+	if _, err := s.PatchSubscription(ctx, id, patch, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("PatchSubscription(end_date): %v", err)
+	}
+	assertStored(t, s, id, &want)
+
+	// check получает подписку после применения патча; при ошибке изменения не сохраняются
+	reject := func(sub *entities.Subscriptions) error {
+		if sub.StartDate != "05-2026" || sub.ServiceName != want.ServiceName {
+			t.Errorf("check got %s, want patched subscription", format(*sub))
+		}
+		return entities.ErrValidation
+	}
+	if _, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{StartDate: ptr("05-2026")}, entities.AnyVersion, reject); !errors.Is(err, entities.ErrValidation) {
+		t.Fatalf("PatchSubscription rejected by check: got %v, want ErrValidation", err)
+	}
+	assertStored(t, s, id, &want)
+
+	if _, err := s.PatchSubscription(ctx, id+1000, &entities.SubscriptionPatch{AmountMinor: ptr(int64(1))}, entities.AnyVersion, accept); !errors.Is(err, entities.ErrNotFound) {
+		t.Fatalf("PatchSubscription(missing): got %v, want ErrNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[0])
+	other := create(t, s, fixtures()[1])
+
+	if err := s.DeleteSubscription(ctx, id, entities.AnyVersion); err != nil {
+		t.Fatalf("DeleteSubscription: %v", err)
+	}
+	if _, err := s.GetSubscription(ctx, id); !errors.Is(err, entities.ErrNotFound) {
+		t.Fatalf("GetSubscription after delete: got %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteSubscription(ctx, id, entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Fatalf("second DeleteSubscription: got %v, want ErrNotFound", err)
+	}
+
+	want := fixtures()[1]
+	assertStored(t, s, other, &want)
+}
+
+// testTrash проверяет мягкое удаление: подписка из корзины не видна остальным методам
+// и не входит в стоимость, пока ее не восстановят, а очистка удаляет ее окончательно.
+func testTrash(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	ids := seed(t, s)
+	period := &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"}
+	accept := func(*entities.Subscriptions) error { return nil }
+
+	before, err := s.CalculateTotalCost(ctx, period)
+	if err != nil {
+		t.Fatalf("CalculateTotalCost: %v", err)
+	}
+
+	// Netflix пользователя A: 999 за 02-2025..12-2025
+	deleted := ids[1]
+	if err := s.DeleteSubscription(ctx, deleted, 1); err != nil {
+		t.Fatalf("DeleteSubscription: %v", err)
+	}
+
+	update := fixtures()[1]
+	if _, err := s.UpdateSubscription(ctx, deleted, &update, entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("UpdateSubscription in trash: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.PatchSubscription(ctx, deleted, &entities.SubscriptionPatch{}, entities.AnyVersion, accept); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("PatchSubscription in trash: got %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteSubscription(ctx, deleted, 2); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("DeleteSubscription in trash: got %v, want ErrNotFound", err)
+	}
+
+	active := listPages(t, s, entities.ListFilter{Limit: 10, WithTotal: true}, len(ids)-1)
+	for _, sub := range active {
+		if sub.ID == deleted {
+			t.Fatalf("deleted subscription %d is listed", deleted)
+		}
+	}
+
+	after, err := s.CalculateTotalCost(ctx, period)
+	if err != nil {
+		t.Fatalf("CalculateTotalCost: %v", err)
+	}
+	if after.TotalCostMinor != before.TotalCostMinor-999*11 {
+		t.Errorf("TotalCost after delete = %d, want %d", after.TotalCostMinor, before.TotalCostMinor-999*11)
+	}
+
+	trash := listPages(t, s, entities.ListFilter{Deleted: true, Limit: 10, WithTotal: true}, 1)
+	assertSubscriptions(t, trash, fixtures()[1:2])
+	if trash[0].ID != deleted || trash[0].Version != 2 || trash[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v, want subscription %d with version 2 and deleted_at", trash[0], deleted)
+	}
+
+	if _, err := s.RestoreSubscription(ctx, deleted, 1); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Errorf("RestoreSubscription(stale version): got %v, want ErrVersionMismatch", err)
+	}
+	if _, err := s.RestoreSubscription(ctx, ids[0], entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("RestoreSubscription(active): got %v, want ErrNotFound", err)
+	}
+	restored, err := s.RestoreSubscription(ctx, deleted, 2)
+	if err != nil {
+		t.Fatalf("RestoreSubscription: %v", err)
+	}
+	if restored.Version != 3 || restored.DeletedAt != nil {
+		t.Fatalf("restored = %+v, want version 3 without deleted_at", restored)
+	}
+	want := fixtures()[1]
+	assertStored(t, s, deleted, &want)
+	if total, err := s.CalculateTotalCost(ctx, period); err != nil || total.TotalCostMinor != before.TotalCostMinor {
+		t.Errorf("TotalCost after restore = %v, %v; want %d", total, err, before.TotalCostMinor)
+	}
+
+	if err := s.DeleteSubscription(ctx, ids[0], entities.AnyVersion); err != nil {
+		t.Fatalf("DeleteSubscription: %v", err)
+	}
+	if purged, err := s.PurgeDeletedSubscriptions(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedSubscriptions(before deletion) = %d, %v; want 0", purged, err)
+	}
+	if purged, err := s.PurgeDeletedSubscriptions(ctx, time.Now().Add(time.Minute)); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedSubscriptions = %d, %v; want 1", purged, err)
+	}
+	if _, err := s.RestoreSubscription(ctx, ids[0], entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("RestoreSubscription after purge: got %v, want ErrNotFound", err)
+	}
+	listPages(t, s, entities.ListFilter{Deleted: true, Limit: 10, WithTotal: true}, 0)
+	assertStored(t, s, deleted, &want)
+}
+
+// testVersion проверяет оптимистичную блокировку: каждое изменение увеличивает версию,
+// а изменение с устаревшей версией отклоняется.
+func testVersion(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[0])
+	accept := func(*entities.Subscriptions) error { return nil }
+
+	got, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if got.Version != 1 {
+		t.Fatalf("version after create = %d, want 1", got.Version)
+	}
+
+	update := fixtures()[0]
+	update.AmountMinor = 349
+	version, err := s.UpdateSubscription(ctx, id, &update, 1)
+	if err != nil {
+		t.Fatalf("UpdateSubscription(version 1): %v", err)
+	}
+	if version != 2 {
+		t.Fatalf("version after update = %d, want 2", version)
+	}
+
+	if _, err := s.UpdateSubscription(ctx, id, &update, 1); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Errorf("UpdateSubscription(stale): got %v, want ErrVersionMismatch", err)
+	}
+	if _, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{AmountMinor: ptr(int64(1))}, 1, accept); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Errorf("PatchSubscription(stale): got %v, want ErrVersionMismatch", err)
+	}
+	if err := s.DeleteSubscription(ctx, id, 1); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Errorf("DeleteSubscription(stale): got %v, want ErrVersionMismatch", err)
+	}
+	assertStored(t, s, id, &update)
+
+	patched, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{AmountMinor: ptr(int64(399))}, 2, accept)
+	if err != nil {
+		t.Fatalf("PatchSubscription(version 2): %v", err)
+	}
+	if patched.Version != 3 {
+		t.Errorf("version after patch = %d, want 3", patched.Version)
+	}
+
+	if err := s.DeleteSubscription(ctx, id+1000, 3); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("DeleteSubscription(missing, version 3): got %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteSubscription(ctx, id, 3); err != nil {
+		t.Fatalf("DeleteSubscription(version 3): %v", err)
+	}
+}
+
+// testEvents проверяет журнал: каждое успешное изменение записывает событие с автором, ID запроса
+// и снимками, а отклоненные и откатившиеся изменения событий не оставляют.
+func testEvents(t *testing.T, s service.Storage) {
+	ctx := entities.WithAuditInfo(context.Background(), entities.AuditInfo{Actor: "alice", RequestID: "req-1"})
+	accept := func(*entities.Subscriptions) error { return nil }
+
+	sub := fixtures()[0]
+	id, err := s.CreateSubscription(ctx, &sub)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	update := fixtures()[0]
+	update.AmountMinor = 349
+	if _, err := s.UpdateSubscription(ctx, id, &update, 1); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	if _, err := s.UpdateSubscription(ctx, id, &update, 1); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Fatalf("UpdateSubscription(stale version): got %v, want ErrVersionMismatch", err)
+	}
+	if _, err := s.PatchSubscription(ctx, id, &entities.SubscriptionPatch{SetEndDate: true}, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("PatchSubscription: %v", err)
+	}
+	if err := s.DeleteSubscription(ctx, id, entities.AnyVersion); err != nil {
+		t.Fatalf("DeleteSubscription: %v", err)
+	}
+	if _, err := s.RestoreSubscription(ctx, id, entities.AnyVersion); err != nil {
+		t.Fatalf("RestoreSubscription: %v", err)
+	}
+
+	other := fixtures()[1]
+	if _, err := s.ApplyBatch(ctx, []entities.BatchOperation{
+		{Op: entities.BatchCreate, Subscription: &other},
+		{Op: entities.BatchDelete, ID: 999},
+	}, true); err != nil {
+		t.Fatalf("ApplyBatch(atomic): %v", err)
+	}
+	if _, err := s.ImportSubscriptions(context.Background(), fixtures()[1:3]); err != nil {
+		t.Fatalf("ImportSubscriptions: %v", err)
+	}
+
+	history, err := s.ListEvents(ctx, &entities.EventFilter{SubscriptionID: &id})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	wantActions := []string{entities.EventCreate, entities.EventUpdate, entities.EventUpdate, entities.EventDelete, entities.EventRestore}
+	if len(history.Items) != len(wantActions) {
+		t.Fatalf("history has %d events, want %d: %+v", len(history.Items), len(wantActions), history.Items)
+	}
+	for i, event := range history.Items {
+		if event.Action != wantActions[i] || event.SubscriptionID != id || event.Actor != "alice" || event.RequestID != "req-1" {
+			t.Errorf("event %d = %+v, want %s by alice in req-1", i, event, wantActions[i])
+		}
+		if i > 0 && event.ID <= history.Items[i-1].ID {
+			t.Errorf("event %d ID %d is not after %d", i, event.ID, history.Items[i-1].ID)
+		}
+	}
+	created, updated, patched, deleted := history.Items[0], history.Items[1], history.Items[2], history.Items[3]
+	if created.Before != nil || created.After == nil || created.After.AmountMinor != 299 || created.After.Version != 1 {
+		t.Errorf("create event snapshots = %v, %v", created.Before, created.After)
+	}
+	if updated.Before == nil || updated.After == nil || updated.Before.AmountMinor != 299 || updated.After.AmountMinor != 349 {
+		t.Errorf("update event snapshots = %v, %v", updated.Before, updated.After)
+	}
+	if patched.Before == nil || patched.Before.EndDate == nil || patched.After == nil || patched.After.EndDate != nil {
+		t.Errorf("patch event snapshots = %v, %v", patched.Before, patched.After)
+	}
+	if deleted.Before == nil || deleted.Before.DeletedAt != nil || deleted.After == nil || deleted.After.DeletedAt == nil {
+		t.Errorf("delete event snapshots = %v, %v", deleted.Before, deleted.After)
+	}
+
+	all := listEvents(t, s, entities.EventFilter{Limit: 2})
+	if len(all) != len(wantActions)+2 {
+		t.Fatalf("journal has %d events, want %d (rolled back batch must not be recorded)", len(all), len(wantActions)+2)
+	}
+	for _, event := range all[len(wantActions):] {
+		if event.Action != entities.EventCreate || event.Actor != "" || event.After == nil {
+			t.Errorf("import event = %+v, want anonymous create", event)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter entities.EventFilter
+		want   int
+	}{
+		{name: "action", filter: entities.EventFilter{Actions: []string{entities.EventDelete, entities.EventRestore}}, want: 2},
+		{name: "actor", filter: entities.EventFilter{Actor: ptr("alice")}, want: len(wantActions)},
+		{name: "unknown actor", filter: entities.EventFilter{Actor: ptr("bob")}, want: 0},
+		{name: "from future", filter: entities.EventFilter{From: ptr(time.Now().Add(time.Hour))}, want: 0},
+		{name: "to future", filter: entities.EventFilter{To: ptr(time.Now().Add(time.Hour))}, want: len(all)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listEvents(t, s, tt.filter); len(got) != tt.want {
+				t.Errorf("got %d events, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+// testPrices проверяет историю цен: изменения сохраняются по порядку месяцев, меняют версию,
+// переживают обновление подписки и учитываются в расчете стоимости помесячно.
+func testPrices(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	accept := func(*entities.Subscriptions) error { return nil }
+	id := create(t, s, fixtures()[1])
+
+	changed, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "06-2025", AmountMinor: 1199}, 1, accept)
+	if err != nil {
+		t.Fatalf("SetPriceChange(06-2025): %v", err)
+	}
+	if changed.Version != 2 || !reflect.DeepEqual(changed.Prices, []entities.PriceChange{{EffectiveFrom: "06-2025", Price: 11, AmountMinor: 1199}}) {
+		t.Fatalf("SetPriceChange(06-2025) = version %d, prices %+v", changed.Version, changed.Prices)
+	}
+	if _, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "01-2026", AmountMinor: 1399}, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("SetPriceChange(01-2026): %v", err)
+	}
+	if _, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "06-2025", AmountMinor: 1099}, entities.AnyVersion, accept); err != nil {
+		t.Fatalf("SetPriceChange(replace 06-2025): %v", err)
+	}
+
+	if _, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "03-2025", AmountMinor: 1}, 1, accept); !errors.Is(err, entities.ErrVersionMismatch) {
+		t.Errorf("SetPriceChange(stale): got %v, want ErrVersionMismatch", err)
+	}
+	rejected := errors.New("rejected")
+	if _, err := s.SetPriceChange(ctx, id, &entities.PriceChange{EffectiveFrom: "03-2025", AmountMinor: 1}, entities.AnyVersion,
+		func(*entities.Subscriptions) error { return rejected }); !errors.Is(err, rejected) {
+		t.Errorf("SetPriceChange(rejected by check): got %v, want check error", err)
+	}
+	if _, err := s.SetPriceChange(ctx, id+1000, &entities.PriceChange{EffectiveFrom: "03-2025", AmountMinor: 1}, entities.AnyVersion, accept); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("SetPriceChange(missing): got %v, want ErrNotFound", err)
+	}
+
+	want := []entities.PriceChange{{EffectiveFrom: "06-2025", Price: 10, AmountMinor: 1099}, {EffectiveFrom: "01-2026", Price: 13, AmountMinor: 1399}}
+	update := fixtures()[1]
+	update.ServiceName = "Netflix Premium"
+	if _, err := s.UpdateSubscription(ctx, id, &update, entities.AnyVersion); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	got, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if got.Version != 5 || got.AmountMinor != 999 || !reflect.DeepEqual(got.Prices, want) {
+		t.Fatalf("GetSubscription = version %d, price %d, prices %+v; want version 5, price 999, prices %+v", got.Version, got.AmountMinor, got.Prices, want)
+	}
+	if listed := listPages(t, s, entities.ListFilter{Limit: 10}, 1); !reflect.DeepEqual(listed[0].Prices, want) {
+		t.Errorf("ListSubscriptions prices = %+v, want %+v", listed[0].Prices, want)
+	}
+
+	total, err := s.CalculateTotalCost(ctx, &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "03-2026"})
+	if err != nil {
+		t.Fatalf("CalculateTotalCost: %v", err)
+	}
+	if wantCost := int64(4*999 + 7*1099 + 3*1399); total.TotalCostMinor != wantCost || total.SubscriptionMonths != 14 {
+		t.Errorf("CalculateTotalCost = %+v, want %d for 14 months", *total, wantCost)
+	}
+
+	breakdown, err := s.CalculateCostBreakdown(ctx, &entities.CostFilter{StartPeriod: "05-2025", EndPeriod: "06-2025"})
+	if err != nil {
+		t.Fatalf("CalculateCostBreakdown: %v", err)
+	}
+	if breakdown.TotalCostMinor != 999+1099 || breakdown.Months[0].TotalCostMinor != 999 || breakdown.Months[1].Subscriptions[0].CostMinor != 1099 {
+		t.Errorf("CalculateCostBreakdown = %+v", *breakdown)
+	}
+
+	groups, err := s.CalculateGroupedCost(ctx, &entities.CostFilter{StartPeriod: "12-2025", EndPeriod: "01-2026"}, []string{entities.GroupByServiceName})
+	if err != nil {
+		t.Fatalf("CalculateGroupedCost: %v", err)
+	}
+	assertGroups(t, groups, []entities.GroupedCost{
+		{Key: map[string]string{"service_name": "Netflix Premium"}, TotalCostMinor: 1099 + 1399, SubscriptionMonths: 2, Currency: entities.BaseCurrency},
+	})
+
+	removed, err := s.DeletePriceChange(ctx, id, "01-2026", 5)
+	if err != nil {
+		t.Fatalf("DeletePriceChange: %v", err)
+	}
+	if removed.Version != 6 || !reflect.DeepEqual(removed.Prices, want[:1]) {
+		t.Errorf("DeletePriceChange = version %d, prices %+v", removed.Version, removed.Prices)
+	}
+	if _, err := s.DeletePriceChange(ctx, id, "01-2026", entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("DeletePriceChange(again): got %v, want ErrNotFound", err)
+	}
+
+	history := listEvents(t, s, entities.EventFilter{SubscriptionID: &id})
+	last := history[len(history)-1]
+	if last.Action != entities.EventUpdate || len(last.Before.Prices) != 2 || len(last.After.Prices) != 1 {
+		t.Errorf("price change event = %+v", last)
+	}
+}
+
+func testBatchAtomic(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[0])
+	update := fixtures()[0]
+	update.AmountMinor = 1
+
+	results, err := s.ApplyBatch(ctx, []entities.BatchOperation{
+		{Op: entities.BatchCreate, Subscription: ptr(fixtures()[1])},
+		{Op: entities.BatchUpdate, ID: id, Subscription: &update},
+		{Op: entities.BatchDelete, ID: id + 1000},
+	}, true)
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	for i, want := range []error{entities.ErrBatchAborted, entities.ErrBatchAborted, entities.ErrNotFound} {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("result %d: got %v, want %v", i, results[i].Err, want)
+		}
+	}
+
+	// Откат пакета не оставляет ни созданной подписки, ни изменений
+	assertStored(t, s, id, ptr(fixtures()[0]))
+	page, err := s.ListSubscriptions(ctx, &entities.ListFilter{})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("stored %d subscriptions after aborted batch, want 1", len(page.Items))
+	}
+
+	results, err = s.ApplyBatch(ctx, []entities.BatchOperation{
+		{Op: entities.BatchCreate, Subscription: ptr(fixtures()[1])},
+		{Op: entities.BatchUpdate, ID: id, Version: 1, Subscription: &update},
+	}, true)
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if results[0].Err != nil || results[1].Err != nil || results[0].ID <= 0 || results[1].Version != 2 {
+		t.Fatalf("ApplyBatch results = %+v", results)
+	}
+	assertStored(t, s, results[0].ID, ptr(fixtures()[1]))
+	assertStored(t, s, id, &update)
+}
+
+func testBatchPartial(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	ids := seed(t, s)
+	update := fixtures()[1]
+	update.ServiceName = "Netflix Premium"
+
+	results, err := s.ApplyBatch(ctx, []entities.BatchOperation{
+		{Op: entities.BatchCreate, Subscription: ptr(fixtures()[2])},
+		{Op: entities.BatchDelete, ID: ids[0], Version: 7},
+		{Op: entities.BatchUpdate, ID: ids[1], Subscription: &update},
+		{Op: entities.BatchDelete, ID: ids[0]},
+	}, false)
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if !errors.Is(results[1].Err, entities.ErrVersionMismatch) {
+		t.Errorf("stale delete: got %v, want ErrVersionMismatch", results[1].Err)
+	}
+	for _, i := range []int{0, 2, 3} {
+		if results[i].Err != nil {
+			t.Errorf("result %d: unexpected error %v", i, results[i].Err)
+		}
+	}
+
+	assertStored(t, s, results[0].ID, ptr(fixtures()[2]))
+	assertStored(t, s, ids[1], &update)
+	if _, err := s.GetSubscription(ctx, ids[0]); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("GetSubscription(deleted): got %v, want ErrNotFound", err)
+	}
+}
+
+func testImport(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	existing := create(t, s, fixtures()[0])
+
+	subs := fixtures()
+	ids, err := s.ImportSubscriptions(ctx, subs)
+	if err != nil {
+		t.Fatalf("ImportSubscriptions: %v", err)
+	}
+	if len(ids) != len(subs) {
+		t.Fatalf("ImportSubscriptions returned %d ids, want %d", len(ids), len(subs))
+	}
+	for i, id := range ids {
+		if id == existing {
+			t.Fatalf("imported row %d got existing id %d", i, id)
+		}
+		assertStored(t, s, id, &subs[i])
+	}
+
+	// Новые подписки после импорта не получают ID импортированных
+	if id := create(t, s, fixtures()[1]); id <= ids[len(ids)-1] {
+		t.Errorf("id after import = %d, want greater than %d", id, ids[len(ids)-1])
+	}
+}
+
+func testNotFound(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	id := create(t, s, fixtures()[0])
+	missing := id + 1000
+
+	if _, err := s.GetSubscription(ctx, missing); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("GetSubscription(missing): got %v, want ErrNotFound", err)
+	}
+
+	sub := fixtures()[1]
+	if _, err := s.UpdateSubscription(ctx, missing, &sub, entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("UpdateSubscription(missing): got %v, want ErrNotFound", err)
+	}
+
+	if err := s.DeleteSubscription(ctx, missing, entities.AnyVersion); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("DeleteSubscription(missing): got %v, want ErrNotFound", err)
+	}
+
+	want := fixtures()[0]
+	assertStored(t, s, id, &want)
+}
+
+func testInvalidDates(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+
+	bad := fixtures()[0]
+	bad.StartDate = "13-2025"
+	if _, err := s.CreateSubscription(ctx, &bad); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CreateSubscription with invalid start_date: got %v, want ErrValidation", err)
+	}
+
+	id := create(t, s, fixtures()[0])
+	bad = fixtures()[0]
+	bad.EndDate = ptr("2025-07")
+	if _, err := s.UpdateSubscription(ctx, id, &bad, entities.AnyVersion); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("UpdateSubscription with invalid end_date: got %v, want ErrValidation", err)
+	}
+
+	_, err := s.CalculateTotalCost(ctx, &entities.CostFilter{StartPeriod: "00-2025", EndPeriod: "12-2025"})
+	if !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CalculateTotalCost with invalid start_period: got %v, want ErrValidation", err)
+	}
+}
+
+func testListFilter(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	all := fixtures()
+	tests := []struct {
+		name   string
+		filter entities.ListFilter
+		want   []entities.Subscriptions
+	}{
+		{name: "no filter", want: pick(all, 2, 0, 1, 3, 4)},
+		{name: "user_id", filter: entities.ListFilter{UserIDs: []string{userA}}, want: pick(all, 0, 1)},
+		{name: "service_name", filter: entities.ListFilter{ServiceNames: []string{"Netflix"}}, want: pick(all, 1, 3)},
+		{name: "start_date across year", filter: entities.ListFilter{StartDate: ptr("02-2025")}, want: pick(all, 1, 3, 4)},
+		{name: "end_date across year excludes open-ended", filter: entities.ListFilter{EndDate: ptr("12-2025")}, want: pick(all, 2, 0)},
+		{name: "end_date in far future still excludes open-ended", filter: entities.ListFilter{EndDate: ptr("12-2099")}, want: pick(all, 2, 0, 3)},
+		{name: "user_id and service_name", filter: entities.ListFilter{UserIDs: []string{userB}, ServiceNames: []string{"Netflix"}}, want: pick(all, 3)},
+		{name: "start_date and end_date", filter: entities.ListFilter{StartDate: ptr("01-2025"), EndDate: ptr("07-2025")}, want: pick(all, 0)},
+		{
+			name: "all filters",
+			filter: entities.ListFilter{
+				UserIDs:      []string{userA},
+				ServiceNames: []string{"Yandex Plus"},
+				StartDate:    ptr("01-2025"),
+				EndDate:      ptr("07-2025"),
+			},
+			want: pick(all, 0),
+		},
+		{name: "no match", filter: entities.ListFilter{ServiceNames: []string{"Unknown"}}},
+		{name: "user_id list", filter: entities.ListFilter{UserIDs: []string{userA, userB}}, want: pick(all, 2, 0, 1, 3, 4)},
+		{name: "service_name list", filter: entities.ListFilter{ServiceNames: []string{"Spotify", "YouTube"}}, want: pick(all, 2, 4)},
+		{name: "search ignores case", filter: entities.ListFilter{Search: ptr("netFL")}, want: pick(all, 1, 3)},
+		{name: "search is literal", filter: entities.ListFilter{Search: ptr("%")}},
+		{name: "active_on across year", filter: entities.ListFilter{ActiveOn: ptr("02-2025")}, want: pick(all, 2, 0, 1)},
+		{name: "active_on includes end month", filter: entities.ListFilter{ActiveOn: ptr("01-2026")}, want: pick(all, 1, 3, 4)},
+		{name: "min_price", filter: entities.ListFilter{MinPrice: ptr(int64(3))}, want: pick(all, 1, 3, 4)},
+		{name: "price range", filter: entities.ListFilter{MinPrice: ptr(int64(3)), MaxPrice: ptr(int64(5))}, want: pick(all, 3, 4)},
+		{name: "has_end_date", filter: entities.ListFilter{HasEndDate: ptr(true)}, want: pick(all, 2, 0, 3)},
+		{name: "open-ended only", filter: entities.ListFilter{HasEndDate: ptr(false)}, want: pick(all, 1, 4)},
+		{name: "sort by price", filter: entities.ListFilter{Sort: []entities.SortField{{Field: entities.SortPrice}}}, want: pick(all, 2, 0, 4, 3, 1)},
+		{name: "sort by price desc", filter: entities.ListFilter{Sort: []entities.SortField{{Field: entities.SortPrice, Desc: true}}}, want: pick(all, 1, 3, 4, 0, 2)},
+		{
+			name: "sort by service_name then start_date desc",
+			filter: entities.ListFilter{Sort: []entities.SortField{
+				{Field: entities.SortServiceName},
+				{Field: entities.SortStartDate, Desc: true},
+			}},
+			want: pick(all, 3, 1, 2, 0, 4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			got, err := s.ListSubscriptions(ctx, &filter)
+			if err != nil {
+				t.Fatalf("ListSubscriptions: %v", err)
+			}
+			if got.Next != nil {
+				t.Fatalf("Next = %+v, want nil for a single page", got.Next)
+			}
+			assertSubscriptions(t, got.Items, tt.want)
+		})
+	}
+}
+
+func testPagination(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	// еще одна подписка с той же датой начала, что и fixtures()[1]: порядок внутри даты задает id
+	sameStart := fixtures()[1]
+	sameStart.ServiceName = "Kinopoisk"
+	create(t, s, sameStart)
+
+	all := append(pick(fixtures(), 2, 0, 1), sameStart)
+	all = append(all, pick(fixtures(), 3, 4)...)
+
+	assertSubscriptions(t, listPages(t, s, entities.ListFilter{Limit: 2, WithTotal: true}, len(all)), all)
+
+	// при равной цене порядок задает id: Netflix создан раньше Kinopoisk
+	byPrice := append(pick(fixtures(), 1), sameStart)
+	byPrice = append(byPrice, pick(fixtures(), 3, 4, 0, 2)...)
+	priceDesc := entities.ListFilter{Sort: []entities.SortField{{Field: entities.SortPrice, Desc: true}}, Limit: 2}
+	assertSubscriptions(t, listPages(t, s, priceDesc, len(all)), byPrice)
+
+	first, err := s.ListSubscriptions(ctx, &entities.ListFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	priceDesc.After = first.Next
+	if _, err := s.ListSubscriptions(ctx, &priceDesc); !errors.Is(err, entities.ErrValidation) {
+		t.Fatalf("ListSubscriptions with cursor of another sort: got %v, want ErrValidation", err)
+	}
+
+	filtered, err := s.ListSubscriptions(ctx, &entities.ListFilter{UserIDs: []string{userB}, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListSubscriptions(user_id): %v", err)
+	}
+	if filtered.Total != nil {
+		t.Fatalf("Total = %d, want nil when not requested", *filtered.Total)
+	}
+	assertSubscriptions(t, filtered.Items, pick(fixtures(), 2, 3))
+	if filtered.Next == nil {
+		t.Fatalf("Next = nil, want cursor to the third subscription of userB")
+	}
+
+	unbounded, err := s.ListSubscriptions(ctx, &entities.ListFilter{Limit: entities.MaxPageSize + 1})
+	if err != nil {
+		t.Fatalf("ListSubscriptions(limit above max): %v", err)
+	}
+	assertSubscriptions(t, unbounded.Items, all)
+}
+
+// testExport проверяет, что выгрузка возвращает те же подписки в том же порядке, что и постраничный список
+func testExport(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	filters := []entities.ListFilter{
+		{},
+		{UserIDs: []string{userB}, Sort: []entities.SortField{{Field: entities.SortPrice, Desc: true}}},
+		{ActiveOn: ptr("06-2025"), Sort: []entities.SortField{{Field: entities.SortServiceName}}},
+		{ServiceNames: []string{"Missing"}},
+	}
+	for _, filter := range filters {
+		paged := filter
+		paged.Limit = 2
+		want := listPages(t, s, paged, len(fixtures()))
+
+		var got []entities.Subscriptions
+		if err := s.ExportSubscriptions(ctx, &filter, func(sub *entities.Subscriptions) error {
+			got = append(got, *sub)
+			return nil
+		}); err != nil {
+			t.Fatalf("ExportSubscriptions(%+v): %v", filter, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ExportSubscriptions(%+v) = %v, want %v", filter, formatAll(got), formatAll(want))
+		}
+	}
+
+	// Ошибка получателя прекращает выгрузку и возвращается без изменений
+	stop := errors.New("stop")
+	calls := 0
+	err := s.ExportSubscriptions(ctx, &entities.ListFilter{}, func(*entities.Subscriptions) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ExportSubscriptions with failing callback: got %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
+func testSearchUnicode(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	sub := entities.Subscriptions{ServiceName: "Яндекс Плюс", AmountMinor: 299, UserID: userA, StartDate: "03-2025"}
+	create(t, s, sub)
+
+	page, err := s.ListSubscriptions(ctx, &entities.ListFilter{Search: ptr("яНДЕКС")})
+	if err != nil {
+		t.Fatalf("ListSubscriptions: %v", err)
+	}
+	assertSubscriptions(t, page.Items, []entities.Subscriptions{sub})
+}
+
+func testTotalCost(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+
+	empty, err := s.CalculateTotalCost(ctx, &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"})
+	if err != nil {
+		t.Fatalf("CalculateTotalCost on empty storage: %v", err)
+	}
+	if empty.TotalCostMinor != 0 || empty.SubscriptionMonths != 0 {
+		t.Fatalf("CalculateTotalCost on empty storage = %+v, want zero", *empty)
+	}
+
+	seed(t, s)
+
+	tests := []struct {
+		name       string
+		filter     entities.CostFilter
+		wantCost   int64
+		wantMonths int64
+	}{
+		{
+			name:     "full year",
+			filter:   entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"},
+			wantCost: 7*299 + 11*999 + 2*199 + 7*599 + 1*349, wantMonths: 7 + 11 + 2 + 7 + 1,
+		},
+		{
+			name:     "single month",
+			filter:   entities.CostFilter{StartPeriod: "02-2025", EndPeriod: "02-2025"},
+			wantCost: 299 + 999 + 199, wantMonths: 3,
+		},
+		{
+			name:     "period starts in end month",
+			filter:   entities.CostFilter{StartPeriod: "07-2025", EndPeriod: "07-2025"},
+			wantCost: 299 + 999 + 599, wantMonths: 3,
+		},
+		{
+			name:     "period ends in start month",
+			filter:   entities.CostFilter{StartPeriod: "01-2024", EndPeriod: "11-2024"},
+			wantCost: 199, wantMonths: 1,
+		},
+		{
+			name:   "period before all subscriptions",
+			filter: entities.CostFilter{StartPeriod: "01-2020", EndPeriod: "12-2020"},
+		},
+		{
+			name:     "across year boundary",
+			filter:   entities.CostFilter{StartPeriod: "01-2026", EndPeriod: "03-2026"},
+			wantCost: 3*999 + 599 + 3*349, wantMonths: 7,
+		},
+		{
+			name:     "open-ended in the future",
+			filter:   entities.CostFilter{StartPeriod: "01-2030", EndPeriod: "12-2030"},
+			wantCost: 12*999 + 12*349, wantMonths: 24,
+		},
+		{
+			name:     "user_id",
+			filter:   entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025", UserID: ptr(userB)},
+			wantCost: 2*199 + 7*599 + 349, wantMonths: 10,
+		},
+		{
+			name:     "service_name",
+			filter:   entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025", ServiceName: ptr("Netflix")},
+			wantCost: 11*999 + 7*599, wantMonths: 18,
+		},
+		{
+			name:     "user_id and service_name",
+			filter:   entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025", UserID: ptr(userA), ServiceName: ptr("Netflix")},
+			wantCost: 11 * 999, wantMonths: 11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			got, err := s.CalculateTotalCost(ctx, &filter)
+			if err != nil {
+				t.Fatalf("CalculateTotalCost: %v", err)
+			}
+			if got.TotalCostMinor != tt.wantCost || got.SubscriptionMonths != tt.wantMonths {
+				t.Errorf("CalculateTotalCost = {cost: %d, months: %d}, want {cost: %d, months: %d}",
+					got.TotalCostMinor, got.SubscriptionMonths, tt.wantCost, tt.wantMonths)
+			}
+		})
+	}
+}
+
+func testCostBreakdown(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	got, err := s.CalculateCostBreakdown(ctx, &entities.CostFilter{StartPeriod: "10-2025", EndPeriod: "01-2026"})
+	if err != nil {
+		t.Fatalf("CalculateCostBreakdown: %v", err)
+	}
+
+	want := []struct {
+		month    string
+		cost     int64
+		services []string
+	}{
+		{month: "10-2025", cost: 999 + 599, services: []string{"Netflix", "Netflix"}},
+		{month: "11-2025", cost: 999 + 599, services: []string{"Netflix", "Netflix"}},
+		{month: "12-2025", cost: 999 + 599 + 349, services: []string{"Netflix", "Netflix", "YouTube"}},
+		{month: "01-2026", cost: 999 + 599 + 349, services: []string{"Netflix", "Netflix", "YouTube"}},
+	}
+
+	if len(got.Months) != len(want) {
+		t.Fatalf("CalculateCostBreakdown returned %d months, want %d", len(got.Months), len(want))
+	}
+
+	var total int64
+	for i, w := range want {
+		m := got.Months[i]
+		if m.Month != w.month || m.TotalCostMinor != w.cost {
+			t.Errorf("month %d = {%s, %d}, want {%s, %d}", i, m.Month, m.TotalCostMinor, w.month, w.cost)
+		}
+
+		services := make([]string, 0, len(m.Subscriptions))
+		var sum int64
+		for _, sub := range m.Subscriptions {
+			services = append(services, sub.ServiceName)
+			sum += sub.CostMinor
+		}
+		if !reflect.DeepEqual(services, w.services) {
+			t.Errorf("month %s subscriptions = %v, want %v", w.month, services, w.services)
+		}
+		if sum != m.TotalCostMinor {
+			t.Errorf("month %s subscriptions sum to %d, total is %d", w.month, sum, m.TotalCostMinor)
+		}
+		total += w.cost
+	}
+
+	if got.TotalCostMinor != total {
+		t.Errorf("CalculateCostBreakdown total = %d, want %d", got.TotalCostMinor, total)
+	}
+}
+
+func testGroupedCost(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	filter := &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"}
+
+	byService, err := s.CalculateGroupedCost(ctx, filter, []string{entities.GroupByServiceName})
+	if err != nil {
+		t.Fatalf("CalculateGroupedCost(service_name): %v", err)
+	}
+	assertGroups(t, byService, []entities.GroupedCost{
+		{Key: map[string]string{"service_name": "Netflix"}, TotalCostMinor: 11*999 + 7*599, SubscriptionMonths: 18, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"service_name": "Spotify"}, TotalCostMinor: 2 * 199, SubscriptionMonths: 2, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"service_name": "Yandex Plus"}, TotalCostMinor: 7 * 299, SubscriptionMonths: 7, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"service_name": "YouTube"}, TotalCostMinor: 349, SubscriptionMonths: 1, Currency: entities.BaseCurrency},
+	})
+
+	byUserService, err := s.CalculateGroupedCost(ctx, filter, []string{entities.GroupByUserID, entities.GroupByServiceName})
+	if err != nil {
+		t.Fatalf("CalculateGroupedCost(user_id, service_name): %v", err)
+	}
+	assertGroups(t, byUserService, []entities.GroupedCost{
+		{Key: map[string]string{"user_id": userA, "service_name": "Netflix"}, TotalCostMinor: 11 * 999, SubscriptionMonths: 11, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"user_id": userA, "service_name": "Yandex Plus"}, TotalCostMinor: 7 * 299, SubscriptionMonths: 7, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"user_id": userB, "service_name": "Netflix"}, TotalCostMinor: 7 * 599, SubscriptionMonths: 7, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"user_id": userB, "service_name": "Spotify"}, TotalCostMinor: 2 * 199, SubscriptionMonths: 2, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"user_id": userB, "service_name": "YouTube"}, TotalCostMinor: 349, SubscriptionMonths: 1, Currency: entities.BaseCurrency},
+	})
+}
+
+// testBilling проверяет периоды оплаты: месячный эквивалент цены и списания только в месяцы оплаты,
+// отсчитанные от опорного месяца.
+func testBilling(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	yearly := create(t, s, entities.Subscriptions{ServiceName: "PlayStation Plus", AmountMinor: 5990, UserID: userA,
+		StartDate: "03-2025", BillingPeriod: entities.BillingYear})
+	quarterly := create(t, s, entities.Subscriptions{ServiceName: "Amediateka", AmountMinor: 900, UserID: userA,
+		StartDate: "02-2025", BillingPeriod: entities.BillingQuarter, BillingAnchor: ptr("01-2025")})
+	create(t, s, entities.Subscriptions{ServiceName: "Gym", AmountMinor: 100, UserID: userB,
+		StartDate: "01-2025", EndDate: ptr("02-2025"), BillingPeriod: entities.BillingWeek})
+
+	listed := listPages(t, s, entities.ListFilter{Limit: 10, Sort: []entities.SortField{{Field: entities.SortID}}}, 3)
+	for i, want := range []int64{499, 300, 435} {
+		if listed[i].MonthlyAmountMinor != want || listed[i].MonthlyPrice != entities.MajorUnits(want) {
+			t.Errorf("%s monthly price = %d/%d, want %d", listed[i].ServiceName, listed[i].MonthlyPrice, listed[i].MonthlyAmountMinor, want)
+		}
+	}
+	if listed[0].BillingPeriod != entities.BillingYear || listed[0].BillingAnchor != nil || *listed[1].BillingAnchor != "01-2025" {
+		t.Errorf("billing = %s/%v, %s/%v", listed[0].BillingPeriod, listed[0].BillingAnchor, listed[1].BillingPeriod, listed[1].BillingAnchor)
+	}
+
+	total, err := s.CalculateTotalCost(ctx, &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "12-2025"})
+	if err != nil {
+		t.Fatalf("CalculateTotalCost: %v", err)
+	}
+	// годовая в 03-2025, квартальная в 04, 07 и 10-2025, недельная 5 раз в январе и 4 в феврале
+	if wantCost := int64(5990 + 3*900 + 9*100); total.TotalCostMinor != wantCost || total.SubscriptionMonths != 10+11+2 {
+		t.Errorf("CalculateTotalCost = %+v, want %d for 23 months", *total, wantCost)
+	}
+
+	breakdown, err := s.CalculateCostBreakdown(ctx, &entities.CostFilter{StartPeriod: "03-2026", EndPeriod: "04-2026"})
+	if err != nil {
+		t.Fatalf("CalculateCostBreakdown: %v", err)
+	}
+	march, april := breakdown.Months[0], breakdown.Months[1]
+	if march.TotalCostMinor != 5990 || len(march.Subscriptions) != 1 || april.TotalCostMinor != 900 || len(april.Subscriptions) != 1 {
+		t.Errorf("CalculateCostBreakdown = %+v", breakdown.Months)
+	}
+
+	patched, err := s.PatchSubscription(ctx, yearly, &entities.SubscriptionPatch{BillingPeriod: ptr(entities.BillingMonth)}, entities.AnyVersion,
+		func(*entities.Subscriptions) error { return nil })
+	if err != nil {
+		t.Fatalf("PatchSubscription(billing_period): %v", err)
+	}
+	if patched.BillingPeriod != entities.BillingMonth || patched.MonthlyAmountMinor != 5990 {
+		t.Errorf("patched billing = %s, monthly price %d", patched.BillingPeriod, patched.MonthlyAmountMinor)
+	}
+	patched, err = s.PatchSubscription(ctx, quarterly, &entities.SubscriptionPatch{SetBillingAnchor: true}, entities.AnyVersion,
+		func(*entities.Subscriptions) error { return nil })
+	if err != nil {
+		t.Fatalf("PatchSubscription(billing_anchor): %v", err)
+	}
+	if patched.BillingAnchor != nil {
+		t.Errorf("patched billing anchor = %v, want nil", *patched.BillingAnchor)
+	}
+
+	bad := fixtures()[0]
+	bad.BillingPeriod = "day"
+	if _, err := s.CreateSubscription(ctx, &bad); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CreateSubscription with invalid billing_period: got %v, want ErrValidation", err)
+	}
+}
+
+// testBasis сравнивает учет по списаниям и по начислению: при начислении списание делится поровну между
+// активными месяцами оплаченного периода (недельное - по дням), а части в сумме равны списанию.
+func testBasis(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	create(t, s, entities.Subscriptions{ServiceName: "PlayStation Plus", AmountMinor: 1200, UserID: userA,
+		StartDate: "03-2025", BillingPeriod: entities.BillingYear})
+	create(t, s, entities.Subscriptions{ServiceName: "Amediateka", AmountMinor: 100, UserID: userA,
+		StartDate: "01-2025", EndDate: ptr("05-2025"), BillingPeriod: entities.BillingQuarter})
+	create(t, s, entities.Subscriptions{ServiceName: "Gym", AmountMinor: 70, UserID: userB,
+		StartDate: "01-2025", BillingPeriod: entities.BillingWeek})
+
+	filter := func(start, end, user, basis string) *entities.CostFilter {
+		return &entities.CostFilter{StartPeriod: start, EndPeriod: end, UserID: ptr(user), Basis: basis}
+	}
+	monthly := func(f *entities.CostFilter) []int64 {
+		t.Helper()
+		breakdown, err := s.CalculateCostBreakdown(ctx, f)
+		if err != nil {
+			t.Fatalf("CalculateCostBreakdown(%+v): %v", *f, err)
+		}
+		costs := make([]int64, 0, len(breakdown.Months))
+		for _, m := range breakdown.Months {
+			costs = append(costs, m.TotalCostMinor)
+		}
+		return costs
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter *entities.CostFilter
+		want   int64
+	}{
+		{name: "cash by default", filter: filter("01-2025", "12-2025", userA, ""), want: 1200 + 2*100},
+		{name: "cash", filter: filter("01-2025", "12-2025", userA, entities.BasisCash), want: 1200 + 2*100},
+		// годовая - 10 месяцев по 100, квартальная за 04-2025 делится на два оставшихся месяца
+		{name: "accrual", filter: filter("01-2025", "12-2025", userA, entities.BasisAccrual), want: 10*100 + 2*100},
+		// годовое списание из 03-2025 начисляется и на месяцы следующего года
+		{name: "accrual before charge", filter: filter("01-2026", "02-2026", userA, entities.BasisAccrual), want: 200},
+		{name: "cash without charge", filter: filter("01-2026", "02-2026", userA, entities.BasisCash), want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			total, err := s.CalculateTotalCost(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("CalculateTotalCost: %v", err)
+			}
+			if total.TotalCostMinor != tc.want {
+				t.Errorf("TotalCost = %d, want %d", total.TotalCostMinor, tc.want)
+			}
+		})
+	}
+
+	if got, want := monthly(filter("01-2025", "05-2025", userA, entities.BasisAccrual)), []int64{33, 33, 134, 150, 150}; !reflect.DeepEqual(got, want) {
+		t.Errorf("accrual breakdown = %v, want %v", got, want)
+	}
+	if got, want := monthly(filter("01-2025", "05-2025", userA, entities.BasisCash)), []int64{100, 0, 1200, 100, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cash breakdown = %v, want %v", got, want)
+	}
+	// списание 29.01 оплачивает 3 дня января и 4 дня февраля, 26.02 - 3 дня февраля
+	if got, want := monthly(filter("01-2025", "02-2025", userB, entities.BasisAccrual)), []int64{4*70 + 30, 40 + 3*70 + 30}; !reflect.DeepEqual(got, want) {
+		t.Errorf("weekly accrual breakdown = %v, want %v", got, want)
+	}
+	if got, want := monthly(filter("01-2025", "02-2025", userB, entities.BasisCash)), []int64{5 * 70, 4 * 70}; !reflect.DeepEqual(got, want) {
+		t.Errorf("weekly cash breakdown = %v, want %v", got, want)
+	}
+
+	groups, err := s.CalculateGroupedCost(ctx, filter("01-2025", "12-2025", userA, entities.BasisAccrual), []string{entities.GroupByServiceName})
+	if err != nil {
+		t.Fatalf("CalculateGroupedCost: %v", err)
+	}
+	assertGroups(t, groups, []entities.GroupedCost{
+		{Key: map[string]string{"service_name": "Amediateka"}, TotalCostMinor: 200, SubscriptionMonths: 5, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"service_name": "PlayStation Plus"}, TotalCostMinor: 1000, SubscriptionMonths: 10, Currency: entities.BaseCurrency},
+	})
+
+	if _, err := s.CalculateTotalCost(ctx, filter("01-2025", "12-2025", userA, "daily")); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CalculateTotalCost with unknown basis: got %v, want ErrValidation", err)
+	}
+}
+
+// testCurrency проверяет курсы валют и пересчет стоимости: каждый месяц пересчитывается по курсу этого месяца,
+// а использованные курсы возвращаются вместе с суммой.
+func testCurrency(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	if err := s.SetExchangeRates(ctx, []entities.ExchangeRate{
+		{Currency: entities.CurrencyUSD, Month: "01-2025", Rate: "90"},
+		{Currency: entities.CurrencyUSD, Month: "02-2025", Rate: "90"},
+		{Currency: entities.CurrencyUSD, Month: "03-2025", Rate: "100"},
+		{Currency: entities.CurrencyUSD, Month: "04-2025", Rate: "100"},
+		{Currency: entities.CurrencyEUR, Month: "01-2025", Rate: "100.50000"},
+	}); err != nil {
+		t.Fatalf("SetExchangeRates: %v", err)
+	}
+	// повторная установка заменяет курс того же месяца
+	if err := s.SetExchangeRates(ctx, []entities.ExchangeRate{{Currency: entities.CurrencyUSD, Month: "03-2025", Rate: "100.0"}}); err != nil {
+		t.Fatalf("SetExchangeRates(replace): %v", err)
+	}
+
+	rates, err := s.ListExchangeRates(ctx, &entities.ExchangeRateFilter{})
+	if err != nil {
+		t.Fatalf("ListExchangeRates: %v", err)
+	}
+	usd01 := entities.ExchangeRate{Currency: entities.CurrencyUSD, Month: "01-2025", Rate: "90"}
+	usd02 := entities.ExchangeRate{Currency: entities.CurrencyUSD, Month: "02-2025", Rate: "90"}
+	usd03 := entities.ExchangeRate{Currency: entities.CurrencyUSD, Month: "03-2025", Rate: "100"}
+	usd04 := entities.ExchangeRate{Currency: entities.CurrencyUSD, Month: "04-2025", Rate: "100"}
+	usd := []entities.ExchangeRate{usd01, usd02, usd03, usd04}
+	eur01 := entities.ExchangeRate{Currency: entities.CurrencyEUR, Month: "01-2025", Rate: "100.5"}
+	if want := append([]entities.ExchangeRate{eur01}, usd...); !reflect.DeepEqual(rates, want) {
+		t.Fatalf("ListExchangeRates = %+v, want %+v", rates, want)
+	}
+	rates, err = s.ListExchangeRates(ctx, &entities.ExchangeRateFilter{Currencies: []string{entities.CurrencyUSD}})
+	if err != nil {
+		t.Fatalf("ListExchangeRates(USD): %v", err)
+	}
+	if want := usd; !reflect.DeepEqual(rates, want) {
+		t.Fatalf("ListExchangeRates(USD) = %+v, want %+v", rates, want)
+	}
+
+	id := create(t, s, entities.Subscriptions{ServiceName: "Spotify", AmountMinor: 1000, Currency: entities.CurrencyUSD, UserID: userA,
+		StartDate: "01-2025", EndDate: ptr("04-2025")})
+	create(t, s, entities.Subscriptions{ServiceName: "Netflix", AmountMinor: 50000, UserID: userA,
+		StartDate: "02-2025", EndDate: ptr("03-2025")})
+	create(t, s, entities.Subscriptions{ServiceName: "Deezer", AmountMinor: 1001, Currency: entities.CurrencyEUR, UserID: userB,
+		StartDate: "01-2025", EndDate: ptr("01-2025")})
+
+	stored, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if stored.Currency != entities.CurrencyUSD {
+		t.Errorf("Currency = %q, want USD", stored.Currency)
+	}
+
+	// цена сравнивается по целой части в своей валюте: 10,00 USD и 10,01 EUR
+	for _, tc := range []struct {
+		filter entities.ListFilter
+		want   string
+	}{
+		{filter: entities.ListFilter{Currencies: []string{entities.CurrencyUSD}, MinPrice: ptr(int64(10))}, want: "Spotify"},
+		{filter: entities.ListFilter{Currencies: []string{entities.CurrencyEUR}, MaxPrice: ptr(int64(10))}, want: "Deezer"},
+		{filter: entities.ListFilter{Currencies: []string{entities.CurrencyRUB}, MinPrice: ptr(int64(500))}, want: "Netflix"},
+	} {
+		tc.filter.Limit = 10
+		if listed := listPages(t, s, tc.filter, 1); len(listed) != 1 || listed[0].ServiceName != tc.want {
+			t.Errorf("ListSubscriptions(%v) = %v, want %s", tc.filter.Currencies, formatAll(listed), tc.want)
+		}
+	}
+
+	filter := func(user, currency string) *entities.CostFilter {
+		return &entities.CostFilter{StartPeriod: "01-2025", EndPeriod: "04-2025", UserID: ptr(user), Currency: currency}
+	}
+	for _, tc := range []struct {
+		name   string
+		filter *entities.CostFilter
+		want   entities.TotalCostResponse
+	}{
+		{
+			name:   "base currency by default",
+			filter: filter(userA, ""),
+			want: entities.TotalCostResponse{TotalCost: 4800, TotalCostMinor: 2*1000*90 + 2*1000*100 + 2*50000, SubscriptionMonths: 6, Currency: entities.BaseCurrency,
+				Rates: usd},
+		},
+		{
+			// 50000 / 90 = 555,56 и 50000 / 100 = 500; цены в USD пересчитывать не нужно
+			name:   "to USD",
+			filter: filter(userA, entities.CurrencyUSD),
+			want: entities.TotalCostResponse{TotalCost: 50, TotalCostMinor: 4*1000 + 556 + 500, SubscriptionMonths: 6, Currency: entities.CurrencyUSD,
+				Rates: []entities.ExchangeRate{usd02, usd03}},
+		},
+		{
+			// 1001 * 100,5 / 90 = 1117,78
+			name:   "cross rate",
+			filter: filter(userB, entities.CurrencyUSD),
+			want: entities.TotalCostResponse{TotalCost: 11, TotalCostMinor: 1118, SubscriptionMonths: 1, Currency: entities.CurrencyUSD,
+				Rates: []entities.ExchangeRate{eur01, usd01}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			total, err := s.CalculateTotalCost(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("CalculateTotalCost: %v", err)
+			}
+			if !reflect.DeepEqual(*total, tc.want) {
+				t.Errorf("CalculateTotalCost = %+v, want %+v", *total, tc.want)
+			}
+		})
+	}
+
+	breakdown, err := s.CalculateCostBreakdown(ctx, filter(userA, ""))
+	if err != nil {
+		t.Fatalf("CalculateCostBreakdown: %v", err)
+	}
+	costs := make([]int64, 0, len(breakdown.Months))
+	for _, m := range breakdown.Months {
+		costs = append(costs, m.TotalCostMinor)
+	}
+	if want := []int64{90000, 140000, 150000, 100000}; !reflect.DeepEqual(costs, want) {
+		t.Errorf("breakdown = %v, want %v", costs, want)
+	}
+	if breakdown.Currency != entities.BaseCurrency || !reflect.DeepEqual(breakdown.Rates, usd) {
+		t.Errorf("breakdown currency = %s, rates = %+v", breakdown.Currency, breakdown.Rates)
+	}
+
+	groups, err := s.CalculateGroupedCost(ctx, filter(userA, ""), []string{entities.GroupByServiceName})
+	if err != nil {
+		t.Fatalf("CalculateGroupedCost: %v", err)
+	}
+	assertGroups(t, groups, []entities.GroupedCost{
+		{Key: map[string]string{"service_name": "Netflix"}, TotalCostMinor: 100000, SubscriptionMonths: 2, Currency: entities.BaseCurrency},
+		{Key: map[string]string{"service_name": "Spotify"}, TotalCostMinor: 380000, SubscriptionMonths: 4, Currency: entities.BaseCurrency,
+			Rates: usd},
+	})
+
+	if _, err := s.CalculateTotalCost(ctx, filter(userA, "GBP")); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CalculateTotalCost with unknown currency: got %v, want ErrValidation", err)
+	}
+
+	if err := s.DeleteExchangeRate(ctx, entities.CurrencyUSD, "01-2025"); err != nil {
+		t.Fatalf("DeleteExchangeRate: %v", err)
+	}
+	if err := s.DeleteExchangeRate(ctx, entities.CurrencyUSD, "01-2025"); !errors.Is(err, entities.ErrNotFound) {
+		t.Errorf("DeleteExchangeRate twice: got %v, want ErrNotFound", err)
+	}
+	// курс другого месяца не подставляется: без курса за 01-2025 январь не пересчитать
+	if _, err := s.CalculateTotalCost(ctx, filter(userA, "")); !errors.Is(err, entities.ErrValidation) {
+		t.Errorf("CalculateTotalCost without rate: got %v, want ErrValidation", err)
+	}
+}
+
+// listPages проходит все страницы списка по курсорам и возвращает записи подряд.
+// Проверяет размер страниц и общее количество, если оно запрошено.
+func listPages(t *testing.T, s service.Storage, filter entities.ListFilter, total int) []entities.Subscriptions {
+	t.Helper()
+
+	var got []entities.Subscriptions
+	for pages := 0; ; pages++ {
+		if pages > total {
+			t.Fatalf("pagination did not terminate")
+		}
+
+		page, err := s.ListSubscriptions(context.Background(), &filter)
+		if err != nil {
+			t.Fatalf("ListSubscriptions(page %d): %v", pages, err)
+		}
+		if len(page.Items) > filter.Limit {
+			t.Fatalf("page %d has %d items, want at most %d", pages, len(page.Items), filter.Limit)
+		}
+		if filter.WithTotal && (page.Total == nil || *page.Total != int64(total)) {
+			t.Fatalf("page %d Total = %v, want %d", pages, page.Total, total)
+		}
+
+		got = append(got, page.Items...)
+		if page.Next == nil {
+			return got
+		}
+		filter.After = page.Next
+	}
+}
+
+// listEvents проходит все страницы журнала и возвращает события подряд
+func listEvents(t *testing.T, s service.Storage, filter entities.EventFilter) []entities.SubscriptionEvent {
+	t.Helper()
+
+	var got []entities.SubscriptionEvent
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("event pagination did not terminate")
+		}
+
+		page, err := s.ListEvents(context.Background(), &filter)
+		if err != nil {
+			t.Fatalf("ListEvents(page %d): %v", pages, err)
+		}
+		got = append(got, page.Items...)
+		if page.Next == 0 {
+			return got
+		}
+		filter.After = page.Next
+	}
+}
+
+func seed(t *testing.T, s service.Storage) []int64 {
+	t.Helper()
+
+	var ids []int64
+	for _, sub := range fixtures() {
+		ids = append(ids, create(t, s, sub))
+	}
+	return ids
+}
+
+func create(t *testing.T, s service.Storage, sub entities.Subscriptions) int64 {
+	t.Helper()
+
+	id, err := s.CreateSubscription(context.Background(), &sub)
+	if err != nil {
+		t.Fatalf("CreateSubscription(%s): %v", sub.ServiceName, err)
+	}
+	return id
+}
+
+func assertStored(t *testing.T, s service.Storage, id int64, want *entities.Subscriptions) {
+	t.Helper()
+
+	got, err := s.GetSubscription(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetSubscription(%d): %v", id, err)
+	}
+	assertSubscription(t, got, want)
+}
+
+func assertSubscription(t *testing.T, got, want *entities.Subscriptions) {
+	t.Helper()
+
+	if !reflect.DeepEqual(withoutMetadata(*got), withoutMetadata(*want)) {
+		t.Fatalf("subscription = %s, want %s", format(*got), format(*want))
+	}
+}
+
+func assertSubscriptions(t *testing.T, got, want []entities.Subscriptions) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d subscriptions %v, want %d %v", len(got), formatAll(got), len(want), formatAll(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(withoutMetadata(got[i]), withoutMetadata(want[i])) {
+			t.Fatalf("subscription %d = %s, want %s", i, format(got[i]), format(want[i]))
+		}
+	}
+}
+
+// assertGroups сравнивает группы стоимости; TotalCost ожидаемых групп выводится из TotalCostMinor
+func assertGroups(t *testing.T, got, want []entities.GroupedCost) {
+	t.Helper()
+
+	want = append([]entities.GroupedCost{}, want...)
+	for i := range want {
+		want[i].TotalCost = entities.MajorUnits(want[i].TotalCostMinor)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("groups = %+v, want %+v", got, want)
+	}
+}
+
+// testIdempotency проверяет жизненный цикл ключа идемпотентности: резервирование,
+// сохранение ответа, освобождение и истечение срока.
+func testIdempotency(t *testing.T, s service.IdempotencyStore) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	rec := &entities.IdempotencyRecord{Key: "key-1", Fingerprint: "fp-1", ExpiresAt: expiresAt}
+
+	if existing, err := s.ReserveIdempotencyKey(ctx, rec); err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey(new): got %+v, %v; want nil, nil", existing, err)
+	}
+	existing, err := s.ReserveIdempotencyKey(ctx, &entities.IdempotencyRecord{Key: "key-1", Fingerprint: "fp-2", ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("ReserveIdempotencyKey(pending): %v", err)
+	}
+	if existing == nil || existing.Fingerprint != "fp-1" || existing.Completed() {
+		t.Fatalf("ReserveIdempotencyKey(pending) = %+v, want pending record with fp-1", existing)
+	}
+
+	rec.Status = 201
+	rec.ContentType = "application/json"
+	rec.Body = []byte(`{"id":1}`)
+	if err := s.CompleteIdempotencyKey(ctx, rec); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	// Завершенный ключ не освобождается
+	if err := s.ReleaseIdempotencyKey(ctx, "key-1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey(completed): %v", err)
+	}
+	existing, err = s.ReserveIdempotencyKey(ctx, rec)
+	if err != nil {
+		t.Fatalf("ReserveIdempotencyKey(completed): %v", err)
+	}
+	if existing == nil || existing.Status != 201 || existing.ContentType != "application/json" || string(existing.Body) != `{"id":1}` {
+		t.Fatalf("ReserveIdempotencyKey(completed) = %+v, want stored response", existing)
+	}
+
+	released := &entities.IdempotencyRecord{Key: "key-2", Fingerprint: "fp", ExpiresAt: expiresAt}
+	if _, err := s.ReserveIdempotencyKey(ctx, released); err != nil {
+		t.Fatalf("ReserveIdempotencyKey(key-2): %v", err)
+	}
+	if err := s.ReleaseIdempotencyKey(ctx, "key-2"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey: %v", err)
+	}
+	if existing, err := s.ReserveIdempotencyKey(ctx, released); err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey(released): got %+v, %v; want nil, nil", existing, err)
+	}
+
+	expired := &entities.IdempotencyRecord{Key: "key-3", Fingerprint: "old", ExpiresAt: time.Now().Add(-time.Minute)}
+	if _, err := s.ReserveIdempotencyKey(ctx, expired); err != nil {
+		t.Fatalf("ReserveIdempotencyKey(expired): %v", err)
+	}
+	purged, err := s.PurgeIdempotencyKeys(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("PurgeIdempotencyKeys: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("PurgeIdempotencyKeys = %d, want 1", purged)
+	}
+
+	// Запись с истекшим сроком заменяется новой
+	if _, err := s.ReserveIdempotencyKey(ctx, expired); err != nil {
+		t.Fatalf("ReserveIdempotencyKey(expired): %v", err)
+	}
+	replaced := &entities.IdempotencyRecord{Key: "key-3", Fingerprint: "new", ExpiresAt: expiresAt}
+	if existing, err := s.ReserveIdempotencyKey(ctx, replaced); err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey(over expired): got %+v, %v; want nil, nil", existing, err)
+	}
+}
+
+// withoutMetadata обнуляет поля, которые назначает хранилище, чтобы сравнивать только данные подписки.
+// Пустой период оплаты хранилище сохраняет как месячный.
+func withoutMetadata(sub entities.Subscriptions) entities.Subscriptions {
+	sub.BillingPeriod = sub.Billing()
+	sub.Currency = sub.CurrencyCode()
+	sub.Price = entities.MajorUnits(sub.AmountMinor)
+	sub.MonthlyPrice = 0
+	sub.MonthlyAmountMinor = 0
+	sub.ID = 0
+	sub.Version = 0
+	sub.CreatedAt = time.Time{}
+	sub.UpdatedAt = time.Time{}
+	sub.DeletedAt = nil
+	return sub
+}
+
+func format(sub entities.Subscriptions) string {
+	end := "<nil>"
+	if sub.EndDate != nil {
+		end = *sub.EndDate
+	}
+	return fmt.Sprintf("%s/%d/%s/%s..%s", sub.ServiceName, sub.AmountMinor, sub.UserID, sub.StartDate, end)
+}
+
+func formatAll(subs []entities.Subscriptions) []string {
+	out := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		out = append(out, format(sub))
+	}
+	return out
+}
+
+func pick(subs []entities.Subscriptions, indexes ...int) []entities.Subscriptions {
+	out := make([]entities.Subscriptions, 0, len(indexes))
+	for _, i := range indexes {
+		out = append(out, subs[i])
+	}
+	return out
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"tz_effective/internal/entities"
+)
+
+// trashPurgeInterval период очистки корзины от подписок с истекшим сроком хранения
+const trashPurgeInterval = time.Hour
+
+// ListTrash возвращает страницу подписок из корзины
+func (s *Service) ListTrash(ctx context.Context, filter *entities.ListFilter) (*entities.SubscriptionPage, error) {
+	filter.Deleted = true
+	if err := scopePriceFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.storage.ListSubscriptions(ctx, filter)
+}
+
+// RestoreSubscription возвращает подписку из корзины
+func (s *Service) RestoreSubscription(ctx context.Context, id int64, version int64) (*entities.Subscriptions, error) {
+	slog.Info("Restoring subscription", "id", id)
+	return s.storage.RestoreSubscription(ctx, id, version)
+}
+
+// PurgeTrash окончательно удаляет подписки, пролежавшие в корзине дольше срока хранения
+func (s *Service) PurgeTrash(ctx context.Context, now time.Time) (int64, error) {
+	return s.storage.PurgeDeletedSubscriptions(ctx, now.Add(-s.cfg.Storage.TrashRetention))
+}
+
+// RunTrashPurge периодически очищает корзину, пока ctx не отменен.
+// Нулевой срок хранения отключает очистку.
+func (s *Service) RunTrashPurge(ctx context.Context) {
+	if s.cfg.Storage.TrashRetention <= 0 {
+		slog.Info("Trash purge disabled")
+		return
+	}
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeTrash(ctx, time.Now())
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					slog.Error("Failed to purge deleted subscriptions", "error", err)
+				}
+				continue
+			}
+			if purged > 0 {
+				slog.Info("Deleted subscriptions purged", "count", purged)
+			}
+		}
+	}
+}
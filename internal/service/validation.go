@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"tz_effective/internal/entities"
+)
+
+// validateSubscription проверяет подписку и приводит цену к минимальным единицам
+func validateSubscription(sub *entities.Subscriptions) error {
+	verr := &entities.ValidationError{}
+
+	if strings.TrimSpace(sub.ServiceName) == "" {
+		verr.Add("service_name", "must not be empty")
+	}
+
+	sub.AmountMinor = resolveAmount(verr, sub.Price, sub.AmountMinor)
+	sub.Price = entities.MajorUnits(sub.AmountMinor)
+
+	start, startErr := entities.ParseMonth(sub.StartDate)
+	if startErr != nil {
+		verr.Add("start_date", "expected format MM-YYYY")
+	}
+
+	if sub.EndDate != nil {
+		end, err := entities.ParseMonth(*sub.EndDate)
+		switch {
+		case err != nil:
+			verr.Add("end_date", "expected format MM-YYYY")
+		case startErr == nil && end < start:
+			verr.Add("end_date", "must not be before start_date")
+		}
+	}
+
+	if sub.Currency != "" && !entities.ValidCurrency(sub.Currency) {
+		verr.Add("currency", "must be one of "+strings.Join(entities.Currencies, ", "))
+	}
+
+	if sub.BillingPeriod != "" && !entities.ValidBillingPeriod(sub.BillingPeriod) {
+		verr.Add("billing_period", "must be one of month, quarter, year, week")
+	}
+
+	if sub.BillingAnchor != nil {
+		if _, err := entities.ParseMonth(*sub.BillingAnchor); err != nil {
+			verr.Add("billing_anchor", "expected format MM-YYYY")
+		}
+	}
+
+	return verr.Err()
+}
+
+// validateBatchOperation проверяет операцию пакета: вид операции, ID и подписку для create и update.
+// Ошибки полей подписки возвращаются с префиксом subscription.
+func validateBatchOperation(op *entities.BatchOperation) error {
+	verr := &entities.ValidationError{}
+
+	switch op.Op {
+	case entities.BatchCreate:
+		if op.ID != 0 {
+			verr.Add("id", "must not be set for create")
+		}
+		if op.Version != 0 {
+			verr.Add("version", "must not be set for create")
+		}
+	case entities.BatchUpdate, entities.BatchDelete:
+		if op.ID <= 0 {
+			verr.Add("id", "must be a positive integer")
+		}
+		if op.Version < 0 {
+			verr.Add("version", "must not be negative")
+		}
+	default:
+		verr.Add("op", "must be one of create, update, delete")
+		return verr
+	}
+
+	switch {
+	case op.Op == entities.BatchDelete:
+		if op.Subscription != nil {
+			verr.Add("subscription", "must not be set for delete")
+		}
+	case op.Subscription == nil:
+		verr.Add("subscription", "is required for "+op.Op)
+	default:
+		var subErr *entities.ValidationError
+		if errors.As(validateSubscription(op.Subscription), &subErr) {
+			for _, f := range subErr.Fields {
+				verr.Add("subscription."+f.Field, f.Message)
+			}
+		}
+	}
+
+	return verr.Err()
+}
+
+// validatePriceChange проверяет формат изменения цены и приводит цену к минимальным единицам
+func validatePriceChange(change *entities.PriceChange) error {
+	verr := &entities.ValidationError{}
+
+	if _, err := entities.ParseMonth(change.EffectiveFrom); err != nil {
+		verr.Add("effective_from", "expected format MM-YYYY")
+	}
+
+	change.AmountMinor = resolveAmount(verr, change.Price, change.AmountMinor)
+	change.Price = entities.MajorUnits(change.AmountMinor)
+
+	return verr.Err()
+}
+
+// validatePatchAmount приводит цену патча к минимальным единицам: после проверки
+// AmountMinor задан всегда, когда передано price или amount_minor
+func validatePatchAmount(patch *entities.SubscriptionPatch) error {
+	if patch.Price == nil && patch.AmountMinor == nil {
+		return nil
+	}
+
+	var price, amountMinor int64
+	if patch.Price != nil {
+		price = *patch.Price
+	}
+	if patch.AmountMinor != nil {
+		amountMinor = *patch.AmountMinor
+	}
+
+	verr := &entities.ValidationError{}
+	amount := resolveAmount(verr, price, amountMinor)
+	if err := verr.Err(); err != nil {
+		return err
+	}
+	patch.AmountMinor = &amount
+	return nil
+}
+
+// resolveAmount возвращает цену в минимальных единицах по цене в основных единицах price
+// или в минимальных amountMinor (см. entities.ResolveAmount) и добавляет в verr ошибки цены
+func resolveAmount(verr *entities.ValidationError, price, amountMinor int64) int64 {
+	if price < 0 {
+		verr.Add("price", "must not be negative")
+	} else if price > math.MaxInt64/entities.MinorUnits {
+		verr.Add("price", "is too large")
+	}
+	if amountMinor < 0 {
+		verr.Add("amount_minor", "must not be negative")
+	}
+
+	amount, ok := entities.ResolveAmount(price, amountMinor)
+	if !ok {
+		verr.Add("price", "must equal the whole part of amount_minor / 100 when both are set")
+	}
+	return amount
+}
+
+// scopePriceFilter ограничивает фильтр по цене одной валютой: суммы в разных валютах несравнимы.
+// Без фильтра по валюте цена сравнивается в BaseCurrency, фильтр по нескольким валютам отклоняется.
+func scopePriceFilter(filter *entities.ListFilter) error {
+	if filter.MinPrice == nil && filter.MaxPrice == nil {
+		return nil
+	}
+
+	switch len(filter.Currencies) {
+	case 0:
+		filter.Currencies = []string{entities.BaseCurrency}
+	case 1:
+	default:
+		return &entities.ValidationError{Fields: []entities.FieldError{
+			{Field: "currency", Message: "must be a single currency when min_price or max_price is set"},
+		}}
+	}
+	return nil
+}
+
+// checkPriceChange возвращает проверку, что изменение цены действует после начала и не позже окончания подписки
+func checkPriceChange(change *entities.PriceChange) func(*entities.Subscriptions) error {
+	return func(sub *entities.Subscriptions) error {
+		verr := &entities.ValidationError{}
+
+		from, err := entities.ParseMonth(change.EffectiveFrom)
+		if err != nil {
+			verr.Add("effective_from", "expected format MM-YYYY")
+			return verr
+		}
+
+		if start, err := entities.ParseMonth(sub.StartDate); err == nil && from <= start {
+			verr.Add("effective_from", "must be after start_date, change the subscription price instead")
+		}
+		if sub.EndDate != nil {
+			if end, err := entities.ParseMonth(*sub.EndDate); err == nil && from > end {
+				verr.Add("effective_from", "must not be after end_date")
+			}
+		}
+
+		return verr.Err()
+	}
+}
+
+// validateBreakdownPeriod ограничивает помесячную разбивку entities.MaxBreakdownMonths месяцами.
+// Формат и порядок месяцев проверяются при разборе фильтра.
+func validateBreakdownPeriod(filter *entities.CostFilter) error {
+	from, err := entities.ParseMonth(filter.StartPeriod)
+	if err != nil {
+		return nil
+	}
+	to, err := entities.ParseMonth(filter.EndPeriod)
+	if err != nil || int(to-from) < entities.MaxBreakdownMonths {
+		return nil
+	}
+	return &entities.ValidationError{Fields: []entities.FieldError{
+		{Field: "end_period", Message: fmt.Sprintf("period must not exceed %d months", entities.MaxBreakdownMonths)},
+	}}
+}
+
+// validateExchangeRate проверяет курс валюты: курсы задаются для поддерживаемых валют, кроме базовой,
+// за месяц MM-YYYY и должны быть положительными
+func validateExchangeRate(rate *entities.ExchangeRate) error {
+	verr := &entities.ValidationError{}
+
+	if rate.Currency == entities.BaseCurrency {
+		verr.Add("currency", "must not be the base currency "+entities.BaseCurrency)
+	} else if !entities.ValidCurrency(rate.Currency) {
+		verr.Add("currency", "must be one of "+strings.Join(entities.Currencies, ", "))
+	}
+
+	if _, err := entities.ParseMonth(rate.Month); err != nil {
+		verr.Add("month", "expected format MM-YYYY")
+	}
+
+	if _, err := entities.ParseRate(rate.Rate); err != nil {
+		verr.Add("rate", "must be a positive decimal number with at most 8 decimal places")
+	}
+
+	return verr.Err()
+}